@@ -6,11 +6,14 @@ import (
 
 	"github.com/mizdebsk/rhel-drivers/internal/api"
 	"github.com/mizdebsk/rhel-drivers/internal/cli"
+	"github.com/mizdebsk/rhel-drivers/internal/config"
+	"github.com/mizdebsk/rhel-drivers/internal/cryptopolicy"
+	"github.com/mizdebsk/rhel-drivers/internal/distrorepo"
 	"github.com/mizdebsk/rhel-drivers/internal/dnf"
 	"github.com/mizdebsk/rhel-drivers/internal/exec"
+	"github.com/mizdebsk/rhel-drivers/internal/log"
 	"github.com/mizdebsk/rhel-drivers/internal/provider/amd"
 	"github.com/mizdebsk/rhel-drivers/internal/provider/nvidia"
-	"github.com/mizdebsk/rhel-drivers/internal/rhsm"
 	"github.com/mizdebsk/rhel-drivers/internal/sysinfo"
 )
 
@@ -19,21 +22,40 @@ var version = "dev"
 
 func main() {
 	ctx := context.Background()
+	cfg, err := config.Load(config.DefaultPath)
+	if err != nil {
+		log.Errorf("%v", err)
+		os.Exit(1)
+	}
 	executor := exec.NewExecutor(ctx)
-	systemInfo := sysinfo.DetectSysInfo()
 
-	packageManager := dnf.NewPackageManager(executor)
-	repositoryManager := rhsm.NewRepositoryManager(executor, systemInfo)
-	providers := []api.Provider{nvidia.NewProvider(packageManager), amd.NewProvider(packageManager)}
-	deps := api.CoreDeps{
-		PackageManager:    packageManager,
-		RepositoryManager: repositoryManager,
-		Providers:         providers,
+	// System is detected only once, even if dependencies are created
+	// again for local repositories.
+	var detected *sysinfo.SysInfo
+	newDeps := func(installRoot string, localRepos []api.LocalRepository, target cli.Target) (api.CoreDeps, error) {
+		if detected == nil || detected.Root != installRoot {
+			si := sysinfo.DetectSysInfo(installRoot)
+			detected = &si
+		}
+		systemInfo := target.SystemInfo(*detected)
+		packageManager := dnf.NewPackageManager(executor, cfg, systemInfo, localRepos)
+		repositoryManager := distrorepo.NewRepositoryManager(executor, cfg, systemInfo)
+		providers := []api.Provider{nvidia.NewProvider(packageManager, repositoryManager, systemInfo), amd.NewProvider(packageManager, repositoryManager, systemInfo)}
+		return api.CoreDeps{
+			PackageManager:    packageManager,
+			RepositoryManager: repositoryManager,
+			Providers:         providers,
+			Executor:          executor,
+			SystemInfo:        systemInfo,
+			PolicyChecker:     cryptopolicy.NewChecker(systemInfo),
+			VersionPolicy:     cfg.VersionPolicy,
+		}, nil
 	}
 
-	root := cli.NewRootCmd(deps, version)
+	root := cli.NewRootCmd(newDeps, version)
 
 	if err := root.ExecuteContext(ctx); err != nil {
+		cli.PrintGuidance(err)
 		os.Exit(1)
 	}
 }
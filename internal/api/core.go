@@ -2,13 +2,156 @@ package api
 
 //go:generate mockgen -source=core.go -destination=../mocks/core_mock.go -package=mocks
 
+import (
+	"github.com/mizdebsk/rhel-drivers/internal/rpmver"
+	"github.com/mizdebsk/rhel-drivers/internal/sysinfo"
+)
+
+// Repository channels, named after RHEL content sets.  Repository
+// managers map them to repositories of the particular distribution.
+const (
+	ChannelBaseOS        = "BaseOS"
+	ChannelAppStream     = "AppStream"
+	ChannelCRB           = "CRB"
+	ChannelExtensions    = "Extensions"
+	ChannelSupplementary = "Supplementary"
+)
+
+// RepositoryStatus describes repository that provides a channel.
+type RepositoryStatus struct {
+	Channel string
+	// Repository ID, empty if no repository needs to be enabled for
+	// the channel on this system.
+	ID      string
+	Defined bool
+	Enabled bool
+	// Set when no repository provides the channel on this system, so
+	// there is nothing to enable.
+	Unavailable bool
+	// Set when repositories are not managed on this system, so it is
+	// not known which repository provides the channel.
+	Unmanaged bool
+	// Set when the repository is the one Red Hat provides for the
+	// channel, as managed by Subscription Manager, rather than a custom
+	// or third-party one.
+	RedHat bool
+}
+
+// Missing reports whether the repository is needed, but not defined or
+// not enabled.
+func (s RepositoryStatus) Missing() bool {
+	return s.ID != "" && !(s.Defined && s.Enabled)
+}
+
 type RepositoryManager interface {
-	EnsureRepositoriesEnabled() error
+	EnsureRepositoriesEnabled(channels []string) error
+	// ListRepositories returns status of repositories that provide
+	// given channels.
+	ListRepositories(channels []string) ([]RepositoryStatus, error)
+	// RollbackRepositories disables repositories enabled by
+	// EnsureRepositoriesEnabled in this run.
+	RollbackRepositories() error
+	// RestoreRepositories disables all repositories that were ever
+	// enabled by rhel-drivers and not restored since.
+	RestoreRepositories() error
+	// EnableCommand returns command that persistently enables given
+	// repositories on a system like this one, for provisioning
+	// snippets run there.
+	EnableCommand(ids []string) []string
+}
+
+// RepositoryIDs returns IDs of repositories that provide given channels
+// and are defined on the system, as mapped by rm.  Nil is returned if
+// any channel has no such repository, or is provided by repositories
+// enabled by default, as all enabled repositories need to be used then.
+func RepositoryIDs(rm RepositoryManager, channels []string) []string {
+	if rm == nil || len(channels) == 0 {
+		return nil
+	}
+	statuses, err := rm.ListRepositories(channels)
+	if err != nil {
+		return nil
+	}
+	var ids []string
+	for _, status := range statuses {
+		if status.ID == "" || !status.Defined {
+			return nil
+		}
+		ids = append(ids, status.ID)
+	}
+	return ids
+}
+
+// RedHatRepositoryIDs returns IDs of Red Hat repositories that provide
+// given channels, as mapped by rm.
+func RedHatRepositoryIDs(rm RepositoryManager, channels []string) []string {
+	if rm == nil || len(channels) == 0 {
+		return nil
+	}
+	statuses, err := rm.ListRepositories(channels)
+	if err != nil {
+		return nil
+	}
+	var ids []string
+	for _, status := range statuses {
+		if status.RedHat {
+			ids = append(ids, status.ID)
+		}
+	}
+	return ids
+}
+
+// PolicyFinding describes why a repository or its GPG key is not
+// acceptable under the active crypto policy.
+type PolicyFinding struct {
+	Repo    string
+	Problem string
+}
+
+type PolicyChecker interface {
+	// Policy returns name of the active crypto policy, eg. "DEFAULT" or
+	// "FIPS:OSPP".
+	Policy() string
+	// CheckRepositories returns problems of given repositories and the
+	// GPG keys they use, if any.
+	CheckRepositories(repos []string) ([]PolicyFinding, error)
 }
 
 type DriverID struct {
 	ProviderID string
+	Epoch      string
 	Version    string
+	Release    string
+	Arch       string
+	// Repositories the driver is available from, separated by commas.
+	Repo string
+}
+
+// FullVersion returns version-release, or just version if release is
+// not known.
+func (d DriverID) FullVersion() string {
+	if d.Release == "" {
+		return d.Version
+	}
+	return d.Version + "-" + d.Release
+}
+
+// String returns driver ID in the same form as accepted on the command
+// line, eg. "nvidia:580.95.05-1.el10".
+func (d DriverID) String() string {
+	return d.ProviderID + ":" + d.FullVersion()
+}
+
+// Matches reports whether driver o satisfies driver d, as requested by
+// the user.  Epoch and release are compared only if d specifies them.
+func (d DriverID) Matches(o DriverID) bool {
+	if d.ProviderID != o.ProviderID || d.Version != o.Version {
+		return false
+	}
+	if d.Epoch != "" && rpmver.CompareEVR(d.Epoch, "", "", o.Epoch, "", "") != 0 {
+		return false
+	}
+	return d.Release == "" || d.Release == o.Release
 }
 
 type CoreDeps struct {
@@ -16,6 +159,13 @@ type CoreDeps struct {
 	RepositoryManager RepositoryManager
 	Providers         []Provider
 	Executor          Executor
+	SystemInfo        sysinfo.SysInfo
+	// Checks repositories of packages to be installed before they are
+	// installed, if set.
+	PolicyChecker PolicyChecker
+	// Site policy restricting driver versions that may be selected,
+	// by provider ID.
+	VersionPolicy map[string]rpmver.Constraint
 }
 
 type DriverStatus struct {
@@ -1,12 +1,34 @@
 package api
 
+import (
+	"github.com/mizdebsk/rhel-drivers/internal/rpmver"
+	"github.com/mizdebsk/rhel-drivers/internal/sysinfo"
+)
+
 //go:generate mockgen -source=dnf.go -destination=../mocks/dnf_mock.go -package=mocks
 
 type PackageManager interface {
-	ListAvailablePackages() ([]PackageInfo, error)
+	ListAvailablePackages(query PackageQuery) ([]PackageInfo, error)
 	ListInstalledPackages() ([]PackageInfo, error)
 	Install(packages []string, batchMode, dryRun bool) error
 	Remove(packages []string, batchMode, dryRun bool) error
+	Download(packages []string, destDir string) error
+	CreateRepository(dir string) error
+}
+
+// LocalRepository is a repository in local directory, used instead of
+// system repositories for offline installation.
+type LocalRepository struct {
+	ID   string
+	Path string
+}
+
+// PackageQuery restricts which available packages are queried.  Names
+// may contain globs, eg. "nvidia-driver*".  Empty Repos means all
+// enabled repositories.
+type PackageQuery struct {
+	Names []string
+	Repos []string
 }
 
 type PackageInfo struct {
@@ -19,10 +41,22 @@ type PackageInfo struct {
 	Repo       string
 }
 
+func (p PackageInfo) EVR() rpmver.EVR {
+	return rpmver.EVR{Epoch: p.Epoch, Version: p.Version, Release: p.Release}
+}
+
 func (p PackageInfo) NEVRA() string {
-	epochStr := ""
-	if p.Epoch != "" && p.Epoch != "0" {
-		epochStr = p.Epoch + ":"
+	return rpmver.NEVRA{Name: p.Name, EVR: p.EVR(), Arch: p.Arch}.String()
+}
+
+// NewestInstalledKernel returns the newest kernel installed by given
+// packages, or unknown kernel if there is none.
+func NewestInstalledKernel(pkgs []PackageInfo) sysinfo.Kernel {
+	var kernels []sysinfo.Kernel
+	for _, pkg := range pkgs {
+		if k, ok := sysinfo.KernelFromPackage(pkg.Name, pkg.Version, pkg.Release, pkg.Arch); ok {
+			kernels = append(kernels, k)
+		}
 	}
-	return p.Name + "-" + epochStr + p.Version + "-" + p.Release + "." + p.Arch
+	return sysinfo.NewestKernel(kernels)
 }
@@ -5,9 +5,12 @@ package api
 type Provider interface {
 	GetID() string
 	GetName() string
-	Install(drivers []DriverID) ([]string, error)
+	Install(drivers []DriverID, multilib bool) ([]string, error)
 	Remove(drivers []DriverID) ([]string, error)
 	ListAvailable() ([]DriverID, error)
 	ListInstalled() ([]DriverID, error)
 	DetectHardware() (bool, error)
+	// Repository channels drivers and their dependencies come from on
+	// given OS major release and architecture.
+	GetRequiredChannels(osVersion int, arch string) []string
 }
@@ -0,0 +1,27 @@
+package cache
+
+import (
+	"sync"
+)
+
+type Map[K comparable, T any] struct {
+	mu      sync.Mutex
+	entries map[K]*Cache[T]
+}
+
+func (m *Map[K, T]) Get(
+	key K,
+	compute func() (T, error),
+) (T, error) {
+	m.mu.Lock()
+	if m.entries == nil {
+		m.entries = make(map[K]*Cache[T])
+	}
+	entry, ok := m.entries[key]
+	if !ok {
+		entry = &Cache[T]{}
+		m.entries[key] = entry
+	}
+	m.mu.Unlock()
+	return entry.Get(compute)
+}
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMapGet(t *testing.T) {
+	t.Parallel()
+
+	errCompute := errors.New("compute failure")
+	m := &Map[string, int]{}
+
+	calls := 0
+	compute := func(v int) func() (int, error) {
+		return func() (int, error) {
+			calls++
+			return v, nil
+		}
+	}
+
+	tests := []struct {
+		name          string
+		key           string
+		compute       func() (int, error)
+		expectedVal   int
+		expectedErr   error
+		expectedCalls int
+	}{
+		{
+			name:          "computes value for new key",
+			key:           "a",
+			compute:       compute(1),
+			expectedVal:   1,
+			expectedCalls: 1,
+		},
+		{
+			name:          "returns cached value for known key",
+			key:           "a",
+			compute:       compute(2),
+			expectedVal:   1,
+			expectedCalls: 1,
+		},
+		{
+			name:          "computes value for another key",
+			key:           "b",
+			compute:       compute(3),
+			expectedVal:   3,
+			expectedCalls: 2,
+		},
+		{
+			name: "does not cache errors",
+			key:  "c",
+			compute: func() (int, error) {
+				return 0, errCompute
+			},
+			expectedVal:   0,
+			expectedErr:   errCompute,
+			expectedCalls: 2,
+		},
+		{
+			name:          "computes value after previous error",
+			key:           "c",
+			compute:       compute(4),
+			expectedVal:   4,
+			expectedCalls: 3,
+		},
+	}
+
+	for _, tc := range tests {
+		val, err := m.Get(tc.key, tc.compute)
+		if val != tc.expectedVal || !errors.Is(err, tc.expectedErr) {
+			t.Errorf("%s: got val=%v, err=%v; want val=%v, err=%v",
+				tc.name, val, err, tc.expectedVal, tc.expectedErr)
+		}
+		if calls != tc.expectedCalls {
+			t.Errorf("%s: compute called %d times; want %d", tc.name, calls, tc.expectedCalls)
+		}
+	}
+}
@@ -1,24 +1,99 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 
 	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/containerfile"
 	"github.com/mizdebsk/rhel-drivers/internal/core"
+	"github.com/mizdebsk/rhel-drivers/internal/export"
+	"github.com/mizdebsk/rhel-drivers/internal/localrepo"
 	"github.com/mizdebsk/rhel-drivers/internal/log"
+	"github.com/mizdebsk/rhel-drivers/internal/sysinfo"
 )
 
 var (
-	flagVerbose bool
-	flagQuiet   bool
-	flagDebug   bool
-	flagVersion bool
+	flagVerbose     bool
+	flagQuiet       bool
+	flagDebug       bool
+	flagVersion     bool
+	flagInstallRoot string
+	flagTargetHW    []string
 )
 
-func NewRootCmd(deps api.CoreDeps, version string) *cobra.Command {
+// TargetKind tells where drivers handled by a command are going to be
+// used.
+type TargetKind int
+
+const (
+	// TargetThisSystem is the running system or install root.
+	TargetThisSystem TargetKind = iota
+	// TargetOtherSystem is another system packages are only prepared
+	// for here, so restrictions of this one are not enforced.
+	TargetOtherSystem
+	// TargetBootcImage is a bootable container image built from
+	// generated instructions, which runs its own kernel.
+	TargetBootcImage
+)
+
+// Target describes the system drivers handled by a command are going to
+// be used on.
+type Target struct {
+	Kind TargetKind
+	// Kernel drivers are installed for, if not the one of this system.
+	Kernel sysinfo.Kernel
+}
+
+// SystemInfo returns information about this system adjusted for target.
+func (t Target) SystemInfo(si sysinfo.SysInfo) sysinfo.SysInfo {
+	if t.Kind != TargetThisSystem {
+		// Whether kernel modules can be loaded here says nothing
+		// about the system drivers are going to be installed on.
+		si.ModuleSigning = sysinfo.ModuleSigning{}
+	}
+	if t.Kind == TargetBootcImage {
+		si.BootcBuild = true
+	}
+	if t.Kernel.Release != "" {
+		si.Kernel = t.Kernel
+		si.DefaultKernel = sysinfo.Kernel{}
+	}
+	return si
+}
+
+// DepsFactory creates dependencies of core functions for the system
+// installed in given root directory, or the running system if empty.
+// If any local repositories are given, packages come only from them.
+// Providers must be given system information adjusted for target.
+type DepsFactory func(installRoot string, localRepos []api.LocalRepository, target Target) (api.CoreDeps, error)
+
+// localDepsFactory creates dependencies using only given local
+// repositories, with global options applied.
+type localDepsFactory func(localRepos []api.LocalRepository, target Target) (api.CoreDeps, error)
+
+func NewRootCmd(newDeps DepsFactory, version string) *cobra.Command {
+	// Filled in once global options are parsed, before any subcommand runs.
+	deps := &api.CoreDeps{}
+	var installRoot string
+	newLocalDeps := func(localRepos []api.LocalRepository, target Target) (api.CoreDeps, error) {
+		d, err := newDeps(installRoot, localRepos, target)
+		if err != nil {
+			return d, err
+		}
+		if len(flagTargetHW) > 0 {
+			return core.WithTargetHardware(d, flagTargetHW)
+		}
+		return d, nil
+	}
+
 	cmd := &cobra.Command{
 		Use:   "rhel-drivers",
 		Short: "Install and manage RHEL hardware drivers",
@@ -29,6 +104,19 @@ func NewRootCmd(deps api.CoreDeps, version string) *cobra.Command {
 			}
 			return cmd.Help()
 		},
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			if installRoot, err = resolveInstallRoot(flagInstallRoot); err != nil {
+				return err
+			}
+			d, err := newLocalDeps(nil, Target{Kind: TargetThisSystem})
+			if err != nil {
+				return err
+			}
+			core.CheckVersionPolicy(d)
+			*deps = d
+			return nil
+		},
 	}
 
 	cmd.SetHelpCommand(&cobra.Command{})
@@ -40,6 +128,8 @@ func NewRootCmd(deps api.CoreDeps, version string) *cobra.Command {
 	cmd.PersistentFlags().BoolVar(&flagQuiet, "quiet", false, "Suppress non-error output")
 	cmd.PersistentFlags().BoolVar(&flagDebug, "debug", false, "Activate debug mode")
 	cmd.PersistentFlags().BoolVar(&flagVersion, "version", false, "Show version and exit")
+	cmd.PersistentFlags().StringVar(&flagInstallRoot, "installroot", "", "Operate on system installed in given directory (for image and chroot builds)")
+	cmd.PersistentFlags().StringSliceVar(&flagTargetHW, "target-hardware", nil, "Assume hardware of given providers (nvidia, amdgpu) instead of detecting it")
 
 	cobra.OnInitialize(func() {
 		log.Quiet = flagQuiet
@@ -48,14 +138,49 @@ func NewRootCmd(deps api.CoreDeps, version string) *cobra.Command {
 	})
 
 	cmd.AddCommand(
-		newInstallCmd(deps),
+		newInstallCmd(deps, newLocalDeps),
 		newRemoveCmd(deps),
 		newListCmd(deps),
+		newBundleCmd(newLocalDeps),
+		newExportCmd(newLocalDeps),
+		newReposCmd(deps),
 	)
 
 	return cmd
 }
 
+func resolveInstallRoot(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid install root %s: %w", path, err)
+	}
+	if abs == "/" {
+		return "", nil
+	}
+	stat, err := os.Stat(abs)
+	if err != nil {
+		return "", fmt.Errorf("cannot access install root: %w", err)
+	}
+	if !stat.IsDir() {
+		return "", fmt.Errorf("install root %s is not a directory", abs)
+	}
+	return abs, nil
+}
+
+// PrintGuidance prints advice on resolving err, if it carries any.
+func PrintGuidance(err error) {
+	var guided interface{ Guidance() []string }
+	if !errors.As(err, &guided) {
+		return
+	}
+	for _, line := range guided.Guidance() {
+		fmt.Fprintln(os.Stderr, line)
+	}
+}
+
 func printVersion(version string) {
 	v := strings.TrimSpace(version)
 	if v == "" {
@@ -64,20 +189,65 @@ func printVersion(version string) {
 	fmt.Println("rhel-drivers version", v)
 }
 
-func newInstallCmd(deps api.CoreDeps) *cobra.Command {
+func newInstallCmd(deps *api.CoreDeps, newLocalDeps localDepsFactory) *cobra.Command {
 	var (
-		autoDetect bool
-		batchMode  bool
-		dryRun     bool
-		force      bool
+		autoDetect      bool
+		batchMode       bool
+		dryRun          bool
+		force           bool
+		multilib        bool
+		fromRepos       []string
+		fromBundle      string
+		toContainerfile bool
 	)
 
 	cmd := &cobra.Command{
-		Use:     "install [OPTIONS] [DRIVER...]",
-		Short:   "Install hardware drivers",
+		Use:   "install [OPTIONS] [DRIVER...]",
+		Short: "Install hardware drivers",
+		Long: "Install hardware drivers.  DRIVER is either vendor:version[-release], or\n" +
+			"vendor:constraint to install the latest matching version, eg. \"nvidia:~> 580.95\"\n" +
+			"or \"nvidia:>= 570, < 590\".",
 		Aliases: []string{"in"},
 		Args:    cobra.ArbitraryArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if fromBundle != "" {
+				fromRepos = append(fromRepos, fromBundle)
+			}
+			var src *localrepo.Source
+			var localRepos []api.LocalRepository
+			if len(fromRepos) > 0 {
+				var err error
+				if src, err = localrepo.Open(deps.Executor, fromRepos); err != nil {
+					return err
+				}
+				defer src.Close()
+				localRepos = src.Repos
+			}
+			// Providers query packages through the package manager and
+			// see the system they were created with, so create everything
+			// anew.  Instructions are run when building bootable image,
+			// so they can be generated in a container too, and drivers
+			// are installed for the kernel of the image.
+			target := Target{Kind: TargetThisSystem}
+			if toContainerfile {
+				target.Kind = TargetBootcImage
+			}
+			coreDeps, err := newLocalDeps(localRepos, target)
+			if err != nil {
+				return err
+			}
+			if src != nil {
+				// Nothing needs to be enabled in local repositories.
+				coreDeps.RepositoryManager = src
+			}
+			if toContainerfile {
+				// Hardware of this system says nothing about where the
+				// image is going to run.
+				if autoDetect && !cmd.Flag("target-hardware").Changed {
+					return fmt.Errorf("--containerfile with --auto-detect requires --target-hardware")
+				}
+				coreDeps.PackageManager, coreDeps.RepositoryManager = containerfile.New(coreDeps.PackageManager, coreDeps.RepositoryManager, cmd.OutOrStdout())
+			}
 			if autoDetect {
 				if len(args) > 0 {
 					return fmt.Errorf("both --auto-detect and specific drivers given")
@@ -85,12 +255,12 @@ func newInstallCmd(deps api.CoreDeps) *cobra.Command {
 				if force {
 					return fmt.Errorf("both --auto-detect and --force were specified")
 				}
-				return core.InstallAutoDetect(deps, batchMode, dryRun)
+				return core.InstallAutoDetect(coreDeps, batchMode, dryRun, multilib)
 			} else {
 				if len(args) == 0 {
 					return fmt.Errorf("not specified what to install (use --auto-detect or provide drivers)")
 				}
-				return core.InstallSpecific(deps, args, batchMode, dryRun, force)
+				return core.InstallSpecific(coreDeps, args, batchMode, dryRun, force, multilib)
 			}
 		},
 	}
@@ -99,11 +269,178 @@ func newInstallCmd(deps api.CoreDeps) *cobra.Command {
 	cmd.Flags().BoolVar(&batchMode, "batch", false, "Batch mode (non-interactive)")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would happen, don't change anything")
 	cmd.Flags().BoolVar(&force, "force", false, "Force install (ignore checks)")
+	cmd.Flags().BoolVar(&multilib, "multilib", false, "Also install 32-bit compatibility libraries")
+	cmd.Flags().StringArrayVar(&fromRepos, "from-repo", nil, "Install only from local repository directory or ISO image (may be repeated)")
+	cmd.Flags().StringVar(&fromBundle, "from-bundle", "", "Install only from bundle created by the bundle command")
+	cmd.Flags().BoolVar(&toContainerfile, "containerfile", false, "Print Containerfile instructions instead of installing (for image mode)")
 
 	return cmd
 }
 
-func newRemoveCmd(deps api.CoreDeps) *cobra.Command {
+func newBundleCmd(newLocalDeps localDepsFactory) *cobra.Command {
+	var (
+		destDir  string
+		multilib bool
+		kernel   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "bundle [OPTIONS] --dest DIR DRIVER...",
+		Short: "Download drivers with dependencies for offline installation",
+		Long: "Download packages of given drivers together with all their dependencies into\n" +
+			"a local repository, which can be copied to a host of the same architecture\n" +
+			"and OS version and installed there with \"install --from-bundle DIR\".\n\n" +
+			"Prebuilt kernel modules are bundled for a single kernel only, the one of this\n" +
+			"system unless --kernel is given, so the other host has to run that kernel.",
+		Args: cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("not specified what to bundle (provide drivers)")
+			}
+			if destDir == "" {
+				return fmt.Errorf("bundle directory not specified (use --dest)")
+			}
+			deps, err := newLocalDeps(nil, Target{Kind: TargetOtherSystem, Kernel: sysinfo.ParseKernel(kernel)})
+			if err != nil {
+				return err
+			}
+			if target := deps.SystemInfo.TargetKernel(); kernel == "" && target.Release != "" {
+				log.Warnf("kernel modules are bundled for kernel %s of this system, use --kernel to select kernel of the other host", target)
+			}
+			return core.Bundle(deps, args, destDir, multilib)
+		},
+	}
+
+	cmd.Flags().StringVar(&destDir, "dest", "", "Directory to store the bundle in")
+	cmd.Flags().BoolVar(&multilib, "multilib", false, "Also bundle 32-bit compatibility libraries")
+	cmd.Flags().StringVar(&kernel, "kernel", "", "Bundle kernel modules for given kernel release (as reported by \"uname -r\")")
+
+	return cmd
+}
+
+func newExportCmd(newLocalDeps localDepsFactory) *cobra.Command {
+	var (
+		autoDetect bool
+		format     string
+		multilib   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export [OPTIONS] --format FORMAT [DRIVER...]",
+		Short: "Print provisioning snippet installing drivers",
+		Long: "Print a snippet that installs given drivers, for provisioning hosts without\n" +
+			"running rhel-drivers on each of them.  Required repositories are enabled\n" +
+			"permanently, so that drivers are updated with the rest of the system.\n" +
+			"Supported formats are: " + strings.Join(export.Formats, ", ") + ".",
+		Args: cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			deps, err := newLocalDeps(nil, Target{Kind: TargetOtherSystem})
+			if err != nil {
+				return err
+			}
+			if autoDetect {
+				if len(args) > 0 {
+					return fmt.Errorf("both --auto-detect and specific drivers given")
+				}
+				return core.ExportAutoDetect(deps, format, multilib, cmd.OutOrStdout())
+			}
+			if len(args) == 0 {
+				return fmt.Errorf("not specified what to export (use --auto-detect or provide drivers)")
+			}
+			return core.ExportSpecific(deps, args, format, multilib, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().BoolVar(&autoDetect, "auto-detect", false, "Export drivers for hardware detected on this host")
+	cmd.Flags().StringVar(&format, "format", export.FormatKickstart, "Snippet format ("+strings.Join(export.Formats, ", ")+")")
+	cmd.Flags().BoolVar(&multilib, "multilib", false, "Also install 32-bit compatibility libraries")
+
+	return cmd
+}
+
+func newReposCmd(deps *api.CoreDeps) *cobra.Command {
+	var (
+		list    bool
+		enable  bool
+		check   bool
+		restore bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "repos [--list|--enable|--check|--restore]",
+		Short: "Manage repositories enabled for drivers",
+		Long: "Show repositories drivers are installed from, for each channel the repository\n" +
+			"it maps to and whether it is defined and enabled (the default), enable them,\n" +
+			"check them, or disable repositories enabled by rhel-drivers.  Exits with\n" +
+			"non-zero status if any repository is missing.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch {
+			case restore:
+				return core.RestoreRepositories(*deps)
+			case enable:
+				return core.EnableRepositories(*deps)
+			}
+			statuses, err := core.ListRepositories(*deps)
+			if err != nil {
+				return err
+			}
+			if !check {
+				printRepositories(statuses)
+			}
+			if err := core.CheckRepositories(statuses); err != nil {
+				return err
+			}
+			if check && !slices.ContainsFunc(statuses, func(s api.RepositoryStatus) bool { return s.Unmanaged }) {
+				log.Infof("all required repositories are enabled")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&list, "list", false, "List required repositories and their status")
+	cmd.Flags().BoolVar(&enable, "enable", false, "Enable required repositories")
+	cmd.Flags().BoolVar(&check, "check", false, "Check that required repositories are enabled")
+	cmd.Flags().BoolVar(&restore, "restore", false, "Disable repositories enabled by rhel-drivers")
+	cmd.MarkFlagsMutuallyExclusive("list", "enable", "check", "restore")
+
+	return cmd
+}
+
+func printRepositories(statuses []api.RepositoryStatus) {
+	yesNo := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "CHANNEL\tREPOSITORY\tDEFINED\tENABLED")
+	for _, status := range statuses {
+		channel := status.Channel
+		if channel == "" {
+			channel = "-"
+		}
+		if status.Unavailable {
+			fmt.Fprintf(w, "%s\t(not available)\t-\t-\n", channel)
+			continue
+		}
+		if status.Unmanaged {
+			fmt.Fprintf(w, "%s\t(not managed)\t-\t-\n", channel)
+			continue
+		}
+		if status.ID == "" {
+			fmt.Fprintf(w, "%s\t(enabled by default)\t-\t-\n", channel)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", channel, status.ID, yesNo(status.Defined), yesNo(status.Enabled))
+	}
+	if err := w.Flush(); err != nil {
+		log.Warnf("failed to write output: %v", err)
+	}
+}
+
+func newRemoveCmd(deps *api.CoreDeps) *cobra.Command {
 	var (
 		all       bool
 		batchMode bool
@@ -120,12 +457,12 @@ func newRemoveCmd(deps api.CoreDeps) *cobra.Command {
 				if len(args) > 0 {
 					return fmt.Errorf("both --all and specific drivers given")
 				}
-				return core.RemoveAll(deps, batchMode, dryRun)
+				return core.RemoveAll(*deps, batchMode, dryRun)
 			} else {
 				if len(args) == 0 {
 					return fmt.Errorf("not specified what to remove (use --all or provide drivers)")
 				}
-				return core.RemoveSpecific(deps, args, batchMode, dryRun)
+				return core.RemoveSpecific(*deps, args, batchMode, dryRun)
 			}
 		},
 	}
@@ -137,7 +474,7 @@ func newRemoveCmd(deps api.CoreDeps) *cobra.Command {
 	return cmd
 }
 
-func newListCmd(deps api.CoreDeps) *cobra.Command {
+func newListCmd(deps *api.CoreDeps) *cobra.Command {
 	var (
 		flagAvailable bool
 		flagInstalled bool
@@ -150,7 +487,7 @@ func newListCmd(deps api.CoreDeps) *cobra.Command {
 		Args:    cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if flagAvailable || (!flagAvailable && !flagInstalled) {
-				res, err := core.List(deps, true, true, true)
+				res, err := core.List(*deps, true, true, true)
 				if err != nil {
 					return err
 				}
@@ -165,7 +502,11 @@ func newListCmd(deps api.CoreDeps) *cobra.Command {
 						if dev.Compatible {
 							markAuto = ">"
 						}
-						fmt.Printf("%s%s %s:%s\n", markInstalled, markAuto, dev.ID.ProviderID, dev.ID.Version)
+						if dev.ID.Repo != "" {
+							fmt.Printf("%s%s %s (%s)\n", markInstalled, markAuto, dev.ID, dev.ID.Repo)
+						} else {
+							fmt.Printf("%s%s %s\n", markInstalled, markAuto, dev.ID)
+						}
 					}
 				} else {
 					fmt.Println("Available drivers:\n  (none)")
@@ -173,14 +514,14 @@ func newListCmd(deps api.CoreDeps) *cobra.Command {
 			}
 
 			if flagInstalled {
-				res, err := core.List(deps, true, false, false)
+				res, err := core.List(*deps, true, false, false)
 				if err != nil {
 					return err
 				}
 				fmt.Print("Installed drivers:")
 				for _, dev := range res {
 					if dev.Installed {
-						fmt.Printf("\n%s:%s", dev.ID.ProviderID, dev.ID.Version)
+						fmt.Printf("\n%s", dev.ID)
 					}
 				}
 				fmt.Println()
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/mocks"
+	"github.com/mizdebsk/rhel-drivers/internal/provider/amd"
+	"github.com/mizdebsk/rhel-drivers/internal/provider/nvidia"
+	"github.com/mizdebsk/rhel-drivers/internal/sysinfo"
+)
+
+func TestInstallContainerfileForImageKernel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	pm := mocks.NewMockPackageManager(ctrl)
+	pm.EXPECT().ListAvailablePackages(gomock.Any()).Return([]api.PackageInfo{
+		{Name: "nvidia-driver", Epoch: "3", Version: "580.95.05", Release: "1.el10", Arch: "x86_64", Repo: "extensions"},
+		{Name: "kmod-nvidia-580.95.05-6.12.0-55", Epoch: "3", Version: "580.95.05", Release: "1.el10", Arch: "x86_64", Repo: "extensions"},
+		{Name: "kmod-nvidia-580.95.05-6.12.0-61", Epoch: "3", Version: "580.95.05", Release: "1.el10", Arch: "x86_64", Repo: "extensions"},
+	}, nil).AnyTimes()
+	// Kernel of the image, older than the one running on this host.
+	pm.EXPECT().ListInstalledPackages().Return([]api.PackageInfo{
+		{Name: "kernel-core", Version: "6.12.0", Release: "55.el10", Arch: "x86_64"},
+	}, nil).AnyTimes()
+	rm := mocks.NewMockRepositoryManager(ctrl)
+	rm.EXPECT().ListRepositories(gomock.Any()).Return(nil, nil).AnyTimes()
+
+	host := sysinfo.SysInfo{
+		IsRhel:    true,
+		OsVersion: 10,
+		Arch:      "x86_64",
+		Kernel:    sysinfo.ParseKernel("6.12.0-61.el10.x86_64"),
+	}
+	newDeps := func(installRoot string, localRepos []api.LocalRepository, target Target) (api.CoreDeps, error) {
+		si := target.SystemInfo(host)
+		return api.CoreDeps{
+			PackageManager:    pm,
+			RepositoryManager: rm,
+			Providers:         []api.Provider{nvidia.NewProvider(pm, rm, si), amd.NewProvider(pm, rm, si)},
+			SystemInfo:        si,
+		}, nil
+	}
+
+	cmd := NewRootCmd(newDeps, "test")
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"install", "--containerfile", "nvidia:580.95.05"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "kmod-nvidia-580.95.05-6.12.0-55-3:580.95.05-1.el10.x86_64") {
+		t.Errorf("expected kernel module for the kernel of the image, got:\n%s", out.String())
+	}
+	if strings.Contains(out.String(), "6.12.0-61") {
+		t.Errorf("unexpected kernel module for the kernel of this host in:\n%s", out.String())
+	}
+}
+
+func TestBundleForOtherKernel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	pm := mocks.NewMockPackageManager(ctrl)
+	pm.EXPECT().ListAvailablePackages(gomock.Any()).Return([]api.PackageInfo{
+		{Name: "nvidia-driver", Epoch: "3", Version: "580.95.05", Release: "1.el10", Arch: "x86_64", Repo: "extensions"},
+		{Name: "kmod-nvidia-580.95.05-6.12.0-55", Epoch: "3", Version: "580.95.05", Release: "1.el10", Arch: "x86_64", Repo: "extensions"},
+		{Name: "kmod-nvidia-580.95.05-6.12.0-61", Epoch: "3", Version: "580.95.05", Release: "1.el10", Arch: "x86_64", Repo: "extensions"},
+	}, nil).AnyTimes()
+	pm.EXPECT().ListInstalledPackages().Return(nil, nil).AnyTimes()
+	dest := t.TempDir()
+	var downloaded []string
+	pm.EXPECT().Download(gomock.Any(), dest).DoAndReturn(func(pkgs []string, dir string) error {
+		downloaded = pkgs
+		return nil
+	})
+	pm.EXPECT().CreateRepository(dest).Return(nil)
+	rm := mocks.NewMockRepositoryManager(ctrl)
+	rm.EXPECT().ListRepositories(gomock.Any()).Return(nil, nil).AnyTimes()
+	rm.EXPECT().EnsureRepositoriesEnabled(gomock.Any()).Return(nil)
+	rm.EXPECT().RollbackRepositories().Return(nil)
+
+	host := sysinfo.SysInfo{
+		IsRhel:    true,
+		OsVersion: 10,
+		Arch:      "x86_64",
+		Kernel:    sysinfo.ParseKernel("6.12.0-61.el10.x86_64"),
+	}
+	newDeps := func(installRoot string, localRepos []api.LocalRepository, target Target) (api.CoreDeps, error) {
+		si := target.SystemInfo(host)
+		return api.CoreDeps{
+			PackageManager:    pm,
+			RepositoryManager: rm,
+			Providers:         []api.Provider{nvidia.NewProvider(pm, rm, si), amd.NewProvider(pm, rm, si)},
+			SystemInfo:        si,
+		}, nil
+	}
+
+	cmd := NewRootCmd(newDeps, "test")
+	cmd.SetArgs([]string{"bundle", "--dest", dest, "--kernel", "6.12.0-55.el10.x86_64", "nvidia:580.95.05"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Contains(downloaded, "kmod-nvidia-580.95.05-6.12.0-55-3:580.95.05-1.el10.x86_64") {
+		t.Errorf("expected kernel module for given kernel, got %v", downloaded)
+	}
+}
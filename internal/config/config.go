@@ -0,0 +1,153 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+	"github.com/mizdebsk/rhel-drivers/internal/rpmver"
+)
+
+const DefaultPath = "/etc/rhel-drivers.conf"
+
+const (
+	InstalledBackendRpm    = "rpm"
+	InstalledBackendSqlite = "sqlite"
+
+	defaultRpmDBPath = "/var/lib/rpm/rpmdb.sqlite"
+
+	ImageModeRefuse    = "refuse"
+	ImageModeRpmOstree = "rpm-ostree"
+
+	RhsmBackendCLI  = "cli"
+	RhsmBackendDBus = "dbus"
+
+	versionPolicyPrefix = "version_policy."
+	repoMapPrefix       = "repo_map."
+)
+
+type Config struct {
+	// How installed packages are queried: "rpm" runs rpm -qa,
+	// "sqlite" reads the RPM database directly.
+	InstalledBackend string
+	RpmDBPath        string
+	// What to do when asked to install or remove packages on image
+	// mode system: "refuse" or layer them with "rpm-ostree".
+	ImageMode string
+	// How RHEL repositories are enabled: "cli" runs subscription-manager,
+	// "dbus" talks to RHSM service over D-Bus.
+	RhsmBackend string
+	// Constraints on driver versions that may be installed, by provider
+	// ID, eg. "version_policy.nvidia = < 590".
+	VersionPolicy map[string]rpmver.Constraint
+	// Repository IDs or glob patterns to use for RHEL channels, by
+	// channel, eg. "repo_map.BaseOS = MyOrg_*_BaseOS_RPMs", for Satellite
+	// and other setups where standard repository IDs do not apply.
+	RepoMap map[string][]string
+}
+
+func Default() Config {
+	return Config{
+		InstalledBackend: InstalledBackendRpm,
+		RpmDBPath:        defaultRpmDBPath,
+		ImageMode:        ImageModeRefuse,
+		RhsmBackend:      RhsmBackendCLI,
+	}
+}
+
+// Load reads configuration from given file.  A missing file is not an
+// error, defaults are used in that case.
+func Load(path string) (Config, error) {
+	cfg := Default()
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Debugf("config file %s does not exist, using defaults", path)
+			return cfg, nil
+		}
+		return cfg, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Warnf("failed to close file %s: %v", path, err)
+		}
+	}()
+
+	lineNum := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			return cfg, fmt.Errorf("%s:%d: expected key = value", path, lineNum)
+		}
+		key = strings.TrimSpace(key)
+		val = strings.TrimSpace(val)
+		if err := cfg.set(key, val); err != nil {
+			return cfg, fmt.Errorf("%s:%d: %w", path, lineNum, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return cfg, fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func (cfg *Config) set(key, val string) error {
+	switch key {
+	case "installed_backend":
+		if val != InstalledBackendRpm && val != InstalledBackendSqlite {
+			return fmt.Errorf("invalid installed_backend %q (expected %q or %q)", val, InstalledBackendRpm, InstalledBackendSqlite)
+		}
+		cfg.InstalledBackend = val
+	case "rpmdb_path":
+		cfg.RpmDBPath = val
+	case "image_mode":
+		if val != ImageModeRefuse && val != ImageModeRpmOstree {
+			return fmt.Errorf("invalid image_mode %q (expected %q or %q)", val, ImageModeRefuse, ImageModeRpmOstree)
+		}
+		cfg.ImageMode = val
+	case "rhsm_backend":
+		if val != RhsmBackendCLI && val != RhsmBackendDBus {
+			return fmt.Errorf("invalid rhsm_backend %q (expected %q or %q)", val, RhsmBackendCLI, RhsmBackendDBus)
+		}
+		cfg.RhsmBackend = val
+	default:
+		if providerID, ok := strings.CutPrefix(key, versionPolicyPrefix); ok && providerID != "" {
+			constraint, err := rpmver.ParseConstraint(val)
+			if err != nil {
+				return err
+			}
+			if cfg.VersionPolicy == nil {
+				cfg.VersionPolicy = make(map[string]rpmver.Constraint)
+			}
+			cfg.VersionPolicy[providerID] = constraint
+			return nil
+		}
+		if channel, ok := strings.CutPrefix(key, repoMapPrefix); ok && channel != "" {
+			var ids []string
+			for _, id := range strings.Split(val, ",") {
+				if id = strings.TrimSpace(id); id != "" {
+					ids = append(ids, id)
+				}
+			}
+			if len(ids) == 0 {
+				return fmt.Errorf("no repositories given for %s", key)
+			}
+			if cfg.RepoMap == nil {
+				cfg.RepoMap = make(map[string][]string)
+			}
+			cfg.RepoMap[channel] = ids
+			return nil
+		}
+		log.Warnf("unknown config option %q ignored", key)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mizdebsk/rhel-drivers/internal/rpmver"
+)
+
+func mustParseConstraint(t *testing.T, s string) rpmver.Constraint {
+	c, err := rpmver.ParseConstraint(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		expected  Config
+		expectErr bool
+	}{
+		{
+			name:     "MissingFile",
+			path:     "testdata/does-not-exist.conf",
+			expected: Default(),
+		},
+		{
+			name: "SqliteBackend",
+			path: "testdata/sqlite.conf",
+			expected: Config{
+				InstalledBackend: InstalledBackendSqlite,
+				RpmDBPath:        "/srv/root/var/lib/rpm/rpmdb.sqlite",
+				ImageMode:        ImageModeRefuse,
+				RhsmBackend:      RhsmBackendCLI,
+			},
+		},
+		{
+			name: "ImageModeRpmOstree",
+			path: "testdata/image_mode.conf",
+			expected: Config{
+				InstalledBackend: InstalledBackendRpm,
+				RpmDBPath:        "/var/lib/rpm/rpmdb.sqlite",
+				ImageMode:        ImageModeRpmOstree,
+				RhsmBackend:      RhsmBackendCLI,
+			},
+		},
+		{
+			name: "RhsmBackendDBus",
+			path: "testdata/rhsm_dbus.conf",
+			expected: Config{
+				InstalledBackend: InstalledBackendRpm,
+				RpmDBPath:        "/var/lib/rpm/rpmdb.sqlite",
+				ImageMode:        ImageModeRefuse,
+				RhsmBackend:      RhsmBackendDBus,
+			},
+		},
+		{
+			name:      "InvalidRhsmBackend",
+			path:      "testdata/invalid_rhsm_backend.conf",
+			expectErr: true,
+		},
+		{
+			name:      "InvalidImageMode",
+			path:      "testdata/invalid_image_mode.conf",
+			expectErr: true,
+		},
+		{
+			name: "VersionPolicy",
+			path: "testdata/version_policy.conf",
+			expected: Config{
+				InstalledBackend: InstalledBackendRpm,
+				RpmDBPath:        "/var/lib/rpm/rpmdb.sqlite",
+				ImageMode:        ImageModeRefuse,
+				RhsmBackend:      RhsmBackendCLI,
+				VersionPolicy: map[string]rpmver.Constraint{
+					"nvidia": mustParseConstraint(t, ">= 570, < 590"),
+					"amdgpu": mustParseConstraint(t, "~> 6.4"),
+				},
+			},
+		},
+		{
+			name: "RepoMap",
+			path: "testdata/repo_map.conf",
+			expected: Config{
+				InstalledBackend: InstalledBackendRpm,
+				RpmDBPath:        "/var/lib/rpm/rpmdb.sqlite",
+				ImageMode:        ImageModeRefuse,
+				RhsmBackend:      RhsmBackendCLI,
+				RepoMap: map[string][]string{
+					"BaseOS":     {"Example_Org_Red_Hat_Enterprise_Linux_10_for_x86_64_-_BaseOS_RPMs"},
+					"Extensions": {"Example_Org_*_Extensions_*", "rhel-10-for-x86_64-extensions-rpms"},
+				},
+			},
+		},
+		{
+			name:      "EmptyRepoMap",
+			path:      "testdata/empty_repo_map.conf",
+			expectErr: true,
+		},
+		{
+			name:      "InvalidVersionPolicy",
+			path:      "testdata/invalid_version_policy.conf",
+			expectErr: true,
+		},
+		{
+			name:     "UnknownKey",
+			path:     "testdata/unknown_key.conf",
+			expected: Default(),
+		},
+		{
+			name:      "InvalidBackend",
+			path:      "testdata/invalid_backend.conf",
+			expectErr: true,
+		},
+		{
+			name:      "MissingEquals",
+			path:      "testdata/missing_equals.conf",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load(tt.path)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Load(%q) error = %v, expectErr %v", tt.path, err, tt.expectErr)
+			}
+			if !tt.expectErr && !reflect.DeepEqual(cfg, tt.expected) {
+				t.Errorf("Load(%q) = %+v, want %+v", tt.path, cfg, tt.expected)
+			}
+		})
+	}
+}
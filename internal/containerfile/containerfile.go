@@ -0,0 +1,96 @@
+package containerfile
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+)
+
+// pkgMgr answers queries using the real package manager, but instead
+// of running transactions it writes Containerfile instructions that
+// perform them when building a bootc image.
+type pkgMgr struct {
+	api.PackageManager
+	out io.Writer
+	// Repositories to enable when installing packages in the image.
+	repos []string
+}
+
+// repoMgr lists repositories using the real repository manager, but
+// instead of enabling them on this system it has them enabled in the
+// Containerfile instructions.
+type repoMgr struct {
+	api.RepositoryManager
+	pm *pkgMgr
+}
+
+var _ api.PackageManager = (*pkgMgr)(nil)
+var _ api.RepositoryManager = (*repoMgr)(nil)
+
+// New returns package and repository managers that write Containerfile
+// instructions to out instead of changing this system.
+func New(pm api.PackageManager, rm api.RepositoryManager, out io.Writer) (api.PackageManager, api.RepositoryManager) {
+	p := &pkgMgr{
+		PackageManager: pm,
+		out:            out,
+	}
+	return p, &repoMgr{
+		RepositoryManager: rm,
+		pm:                p,
+	}
+}
+
+func (rm *repoMgr) EnsureRepositoriesEnabled(channels []string) error {
+	statuses, err := rm.ListRepositories(channels)
+	if err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		if status.ID == "" || slices.Contains(rm.pm.repos, status.ID) {
+			continue
+		}
+		if !status.Defined {
+			log.Warnf("repository %s (%s) is not defined on this system, available drivers were not checked in it", status.ID, status.Channel)
+		}
+		log.Logf("enabling repository %s (%s) in Containerfile", status.ID, status.Channel)
+		rm.pm.repos = append(rm.pm.repos, status.ID)
+	}
+	return nil
+}
+
+func (rm *repoMgr) RollbackRepositories() error {
+	// Nothing was changed on this system.
+	return nil
+}
+
+func (pm *pkgMgr) Install(packages []string, batchMode, dryRun bool) error {
+	return pm.writeRun("install", pm.repos, packages)
+}
+
+func (pm *pkgMgr) Remove(packages []string, batchMode, dryRun bool) error {
+	return pm.writeRun("remove", nil, packages)
+}
+
+func (pm *pkgMgr) writeRun(operation string, repos, packages []string) error {
+	if len(packages) == 0 {
+		return nil
+	}
+	var sb strings.Builder
+	sb.WriteString("RUN dnf -y")
+	for _, repo := range repos {
+		sb.WriteString(" --enablerepo=" + repo)
+	}
+	sb.WriteString(" " + operation + " \\\n")
+	for _, pkg := range packages {
+		sb.WriteString("        " + pkg + " \\\n")
+	}
+	sb.WriteString("    && dnf clean all\n")
+	if _, err := io.WriteString(pm.out, sb.String()); err != nil {
+		return fmt.Errorf("failed to write Containerfile instructions: %w", err)
+	}
+	return nil
+}
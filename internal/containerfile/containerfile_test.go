@@ -0,0 +1,104 @@
+package containerfile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/mocks"
+)
+
+func TestContainerfile(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(pm api.PackageManager) error
+		expected string
+	}{
+		{
+			name: "Install",
+			testFunc: func(pm api.PackageManager) error {
+				return pm.Install([]string{"nvidia-driver-3:580.95.05-1.el10.x86_64", "cuda-toolkit"}, false, false)
+			},
+			expected: "RUN dnf -y install \\\n" +
+				"        nvidia-driver-3:580.95.05-1.el10.x86_64 \\\n" +
+				"        cuda-toolkit \\\n" +
+				"    && dnf clean all\n",
+		},
+		{
+			name: "Remove",
+			testFunc: func(pm api.PackageManager) error {
+				return pm.Remove([]string{"kmod-amdgpu"}, true, true)
+			},
+			expected: "RUN dnf -y remove \\\n" +
+				"        kmod-amdgpu \\\n" +
+				"    && dnf clean all\n",
+		},
+		{
+			name: "Nothing",
+			testFunc: func(pm api.PackageManager) error {
+				return pm.Install(nil, false, false)
+			},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			var out strings.Builder
+			// Transactions must never reach the real package manager.
+			pm, _ := New(mocks.NewMockPackageManager(ctrl), mocks.NewMockRepositoryManager(ctrl), &out)
+			if err := tt.testFunc(pm); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != tt.expected {
+				t.Errorf("got output:\n%s\nwant:\n%s", out.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestContainerfileDelegatesQueries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockPM := mocks.NewMockPackageManager(ctrl)
+	installed := []api.PackageInfo{{Name: "bash"}}
+	mockPM.EXPECT().ListInstalledPackages().Return(installed, nil)
+
+	pm, _ := New(mockPM, mocks.NewMockRepositoryManager(ctrl), &strings.Builder{})
+	got, err := pm.ListInstalledPackages()
+	if err != nil || len(got) != 1 || got[0].Name != "bash" {
+		t.Errorf("ListInstalledPackages() = %v, %v; want %v", got, err, installed)
+	}
+}
+
+func TestContainerfileEnablesRepositories(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	// Repositories must never be enabled on this system.
+	mockRM := mocks.NewMockRepositoryManager(ctrl)
+	channels := []string{"BaseOS", "AppStream", "Extensions"}
+	mockRM.EXPECT().ListRepositories(channels).Return([]api.RepositoryStatus{
+		{Channel: "BaseOS", ID: "rhel-10-for-x86_64-baseos-rpms", Defined: true, Enabled: true},
+		{Channel: "AppStream"},
+		{Channel: "Extensions", ID: "rhel-10-for-x86_64-extensions-rpms"},
+	}, nil)
+
+	var out strings.Builder
+	pm, rm := New(mocks.NewMockPackageManager(ctrl), mockRM, &out)
+	if err := rm.EnsureRepositoriesEnabled(channels); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := rm.RollbackRepositories(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := pm.Install([]string{"nvidia-driver"}, true, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "RUN dnf -y --enablerepo=rhel-10-for-x86_64-baseos-rpms --enablerepo=rhel-10-for-x86_64-extensions-rpms install \\\n" +
+		"        nvidia-driver \\\n" +
+		"    && dnf clean all\n"
+	if out.String() != expected {
+		t.Errorf("got output:\n%s\nwant:\n%s", out.String(), expected)
+	}
+}
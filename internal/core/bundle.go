@@ -0,0 +1,45 @@
+package core
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+)
+
+// Bundle downloads packages needed to install given drivers, together
+// with all their dependencies, into a directory and turns it into
+// a repository usable for offline installation on another host.
+func Bundle(deps api.CoreDeps, drivers []string, destDir string, multilib bool) error {
+	if len(drivers) == 0 {
+		return fmt.Errorf("not specified what to bundle")
+	}
+	if destDir == "" {
+		return fmt.Errorf("bundle directory not specified")
+	}
+
+	toBundle, err := findAvailableDrivers(deps, drivers)
+	if err != nil {
+		return err
+	}
+
+	channels := requiredChannels(deps.SystemInfo, driverProviders(deps, toBundle))
+	return withRepositories(deps, channels, func() error {
+		allPkgs, err := collectInstallPackages(deps, toBundle, multilib)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(destDir, 0o755); err != nil {
+			return fmt.Errorf("failed to create bundle directory: %w", err)
+		}
+		if err := deps.PackageManager.Download(allPkgs, destDir); err != nil {
+			return err
+		}
+		if err := deps.PackageManager.CreateRepository(destDir); err != nil {
+			return err
+		}
+		log.Infof("bundle created in %s", destDir)
+		return nil
+	})
+}
@@ -0,0 +1,134 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/mocks"
+)
+
+func TestBundle(t *testing.T) {
+	tests := []struct {
+		name      string
+		drivers   []string
+		destDir   string
+		setup     func(*mocks.MockProvider, *mocks.MockPackageManager, *mocks.MockRepositoryManager)
+		expectErr bool
+	}{
+		{
+			name:      "EmptyDriversList",
+			drivers:   []string{},
+			destDir:   "bundle",
+			expectErr: true,
+			setup:     func(p *mocks.MockProvider, pm *mocks.MockPackageManager, rm *mocks.MockRepositoryManager) {},
+		},
+		{
+			name:      "NoDestination",
+			drivers:   []string{"nvidia:570.86.16"},
+			expectErr: true,
+			setup:     func(p *mocks.MockProvider, pm *mocks.MockPackageManager, rm *mocks.MockRepositoryManager) {},
+		},
+		{
+			name:      "DriverVersionNotAvailable",
+			drivers:   []string{"nvidia:999.99.99"},
+			destDir:   "bundle",
+			expectErr: true,
+			setup: func(p *mocks.MockProvider, pm *mocks.MockPackageManager, rm *mocks.MockRepositoryManager) {
+				p.EXPECT().GetID().Return("nvidia").AnyTimes()
+				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
+				p.EXPECT().ListAvailable().Return([]api.DriverID{
+					{ProviderID: "nvidia", Version: "570.86.16"},
+				}, nil)
+			},
+		},
+		{
+			name:    "SuccessfulBundle",
+			drivers: []string{"nvidia:570.86.16"},
+			destDir: "bundle",
+			setup: func(p *mocks.MockProvider, pm *mocks.MockPackageManager, rm *mocks.MockRepositoryManager) {
+				p.EXPECT().GetID().Return("nvidia").AnyTimes()
+				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
+				p.EXPECT().ListAvailable().Return([]api.DriverID{
+					{ProviderID: "nvidia", Version: "570.86.16"},
+				}, nil)
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().EnsureRepositoriesEnabled(testChannels).Return(nil)
+				rm.EXPECT().RollbackRepositories().Return(nil)
+				p.EXPECT().Install([]api.DriverID{{ProviderID: "nvidia", Version: "570.86.16"}}, false).Return([]string{"nvidia-driver"}, nil)
+				pm.EXPECT().Download([]string{"nvidia-driver"}, gomock.Any()).Return(nil)
+				pm.EXPECT().CreateRepository(gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name:      "DownloadFails",
+			drivers:   []string{"nvidia:570.86.16"},
+			destDir:   "bundle",
+			expectErr: true,
+			setup: func(p *mocks.MockProvider, pm *mocks.MockPackageManager, rm *mocks.MockRepositoryManager) {
+				p.EXPECT().GetID().Return("nvidia").AnyTimes()
+				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
+				p.EXPECT().ListAvailable().Return([]api.DriverID{
+					{ProviderID: "nvidia", Version: "570.86.16"},
+				}, nil)
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().EnsureRepositoriesEnabled(testChannels).Return(nil)
+				rm.EXPECT().RollbackRepositories().Return(nil)
+				p.EXPECT().Install([]api.DriverID{{ProviderID: "nvidia", Version: "570.86.16"}}, false).Return([]string{"nvidia-driver"}, nil)
+				pm.EXPECT().Download([]string{"nvidia-driver"}, gomock.Any()).Return(fmt.Errorf("download failed"))
+			},
+		},
+		{
+			name:      "RepositoryEnableFails",
+			drivers:   []string{"nvidia:570.86.16"},
+			destDir:   "bundle",
+			expectErr: true,
+			setup: func(p *mocks.MockProvider, pm *mocks.MockPackageManager, rm *mocks.MockRepositoryManager) {
+				p.EXPECT().GetID().Return("nvidia").AnyTimes()
+				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
+				p.EXPECT().ListAvailable().Return([]api.DriverID{
+					{ProviderID: "nvidia", Version: "570.86.16"},
+				}, nil)
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().EnsureRepositoriesEnabled(testChannels).Return(fmt.Errorf("repo error"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockProvider := mocks.NewMockProvider(ctrl)
+			mockPM := mocks.NewMockPackageManager(ctrl)
+			mockRM := mocks.NewMockRepositoryManager(ctrl)
+
+			tt.setup(mockProvider, mockPM, mockRM)
+
+			deps := api.CoreDeps{
+				PackageManager:    mockPM,
+				RepositoryManager: mockRM,
+				Providers:         []api.Provider{mockProvider},
+			}
+
+			destDir := tt.destDir
+			if destDir != "" {
+				destDir = filepath.Join(t.TempDir(), destDir)
+			}
+			err := Bundle(deps, tt.drivers, destDir, false)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("Bundle() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if !tt.expectErr {
+				if stat, err := os.Stat(destDir); err != nil || !stat.IsDir() {
+					t.Errorf("bundle directory %s was not created: %v", destDir, err)
+				}
+			}
+		})
+	}
+}
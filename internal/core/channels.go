@@ -0,0 +1,109 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+	"github.com/mizdebsk/rhel-drivers/internal/sysinfo"
+)
+
+// requiredChannels returns union of repository channels required by
+// given providers on the system, in the order they are first declared.
+func requiredChannels(si sysinfo.SysInfo, providers []api.Provider) []string {
+	var channels []string
+	for _, provider := range providers {
+		for _, channel := range provider.GetRequiredChannels(si.OsVersion, si.Arch) {
+			if !slices.Contains(channels, channel) {
+				channels = append(channels, channel)
+			}
+		}
+	}
+	return channels
+}
+
+// driverProviders returns providers of given drivers.
+func driverProviders(deps api.CoreDeps, drivers []api.DriverID) []api.Provider {
+	var providers []api.Provider
+	for _, provider := range deps.Providers {
+		provID := provider.GetID()
+		if slices.ContainsFunc(drivers, func(d api.DriverID) bool { return d.ProviderID == provID }) {
+			providers = append(providers, provider)
+		}
+	}
+	return providers
+}
+
+// ensureRepositoriesEnabled enables repositories needed to install
+// given drivers.
+func ensureRepositoriesEnabled(deps api.CoreDeps, drivers []api.DriverID) error {
+	channels := requiredChannels(deps.SystemInfo, driverProviders(deps, drivers))
+	return deps.RepositoryManager.EnsureRepositoriesEnabled(channels)
+}
+
+// reportRepositories logs repositories that ensureRepositoriesEnabled
+// would enable for given drivers, without changing anything.  Failure
+// to list them is only reported, as nothing depends on it.
+func reportRepositories(deps api.CoreDeps, drivers []api.DriverID) {
+	channels := requiredChannels(deps.SystemInfo, driverProviders(deps, drivers))
+	statuses, err := deps.RepositoryManager.ListRepositories(channels)
+	if err != nil {
+		log.Warnf("failed to list repositories: %v", err)
+		return
+	}
+	for _, status := range statuses {
+		switch {
+		case status.Unmanaged:
+			log.Logf("repository providing %s is not known", status.Channel)
+		case !status.Missing():
+		case !status.Defined:
+			log.Warnf("repository %s (%s) is not defined", status.ID, status.Channel)
+		default:
+			log.Infof("repository %s (%s) would be enabled", status.ID, status.Channel)
+		}
+	}
+}
+
+// repositoryIDs returns IDs of repositories that need to be enabled to
+// install given drivers, without enabling them on this system.
+func repositoryIDs(deps api.CoreDeps, drivers []api.DriverID) ([]string, error) {
+	channels := requiredChannels(deps.SystemInfo, driverProviders(deps, drivers))
+	statuses, err := deps.RepositoryManager.ListRepositories(channels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories: %w", err)
+	}
+	var ids []string
+	for _, status := range statuses {
+		if status.Unmanaged {
+			log.Warnf("repository providing %s is not known, you may need to enable it on target systems yourself", status.Channel)
+		}
+		if status.ID == "" {
+			continue
+		}
+		if !status.Defined {
+			log.Warnf("repository %s (%s) is not defined on this system", status.ID, status.Channel)
+		}
+		ids = append(ids, status.ID)
+	}
+	return ids, nil
+}
+
+// withRepositories enables repositories providing given channels only
+// for the time f reads from them and disables them again afterwards,
+// also when interrupted, as nothing is going to be installed here.
+func withRepositories(deps api.CoreDeps, channels []string, f func() error) error {
+	if err := deps.RepositoryManager.EnsureRepositoriesEnabled(channels); err != nil {
+		return fmt.Errorf("failed to verify/enable repositories: %w", err)
+	}
+	defer rollbackRepositories(deps)
+	return runInterruptible(f)
+}
+
+// rollbackRepositories disables repositories enabled in this run, which
+// are not needed once nothing is going to be installed.
+func rollbackRepositories(deps api.CoreDeps) {
+	if err := deps.RepositoryManager.RollbackRepositories(); err != nil {
+		log.Warnf("failed to restore repository configuration: %v", err)
+	}
+}
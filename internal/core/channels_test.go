@@ -0,0 +1,73 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/mocks"
+	"github.com/mizdebsk/rhel-drivers/internal/sysinfo"
+)
+
+var testChannels = []string{api.ChannelBaseOS, api.ChannelAppStream, api.ChannelExtensions}
+
+func TestEnsureRepositoriesEnabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		drivers  []api.DriverID
+		expected []string
+	}{
+		{
+			name:     "NVIDIA",
+			drivers:  []api.DriverID{{ProviderID: "nvidia", Version: "580.95.05"}},
+			expected: []string{api.ChannelBaseOS, api.ChannelAppStream, api.ChannelExtensions, api.ChannelSupplementary},
+		},
+		{
+			name:     "AMD",
+			drivers:  []api.DriverID{{ProviderID: "amdgpu", Version: "6.4.1"}},
+			expected: []string{api.ChannelBaseOS, api.ChannelExtensions},
+		},
+		{
+			name: "Union",
+			drivers: []api.DriverID{
+				{ProviderID: "amdgpu", Version: "6.4.1"},
+				{ProviderID: "nvidia", Version: "580.95.05"},
+			},
+			expected: []string{api.ChannelBaseOS, api.ChannelAppStream, api.ChannelExtensions, api.ChannelSupplementary},
+		},
+		{
+			name:    "NoDrivers",
+			drivers: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			nvidia := mocks.NewMockProvider(ctrl)
+			nvidia.EXPECT().GetID().Return("nvidia").AnyTimes()
+			nvidia.EXPECT().GetRequiredChannels(10, "x86_64").Return([]string{api.ChannelBaseOS, api.ChannelAppStream, api.ChannelExtensions, api.ChannelSupplementary}).AnyTimes()
+			amd := mocks.NewMockProvider(ctrl)
+			amd.EXPECT().GetID().Return("amdgpu").AnyTimes()
+			amd.EXPECT().GetRequiredChannels(10, "x86_64").Return([]string{api.ChannelBaseOS, api.ChannelExtensions}).AnyTimes()
+			rm := mocks.NewMockRepositoryManager(ctrl)
+			rm.EXPECT().EnsureRepositoriesEnabled(gomock.Any()).DoAndReturn(func(channels []string) error {
+				if !reflect.DeepEqual(channels, tt.expected) {
+					t.Errorf("EnsureRepositoriesEnabled(%v), expected %v", channels, tt.expected)
+				}
+				return nil
+			})
+
+			deps := api.CoreDeps{
+				RepositoryManager: rm,
+				Providers:         []api.Provider{nvidia, amd},
+				SystemInfo:        sysinfo.SysInfo{OsVersion: 10, Arch: "x86_64"},
+			}
+			if err := ensureRepositoriesEnabled(deps, tt.drivers); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
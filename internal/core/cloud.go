@@ -0,0 +1,35 @@
+package core
+
+import (
+	"strings"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+)
+
+// checkInstanceAccelerators warns when the number of accelerators found
+// differs from what the cloud instance type comes with, which suggests
+// devices that failed or are not visible to the system.  It reports
+// whether it warned.
+func checkInstanceAccelerators(deps api.CoreDeps) bool {
+	si := deps.SystemInfo
+	vendor, expected, ok := si.ExpectedAccelerators()
+	if !ok {
+		switch {
+		case si.Cloud == "":
+		case si.InstanceType == "":
+			log.Infof("instance type of %s cloud is not known, not checking number of accelerators", si.Cloud)
+		default:
+			log.Infof("accelerators of instance type %s are not known, not checking their number", si.InstanceType)
+		}
+		return false
+	}
+	found := si.Accelerators[vendor]
+	if found != expected {
+		log.Warnf("instance type %s should have %d %s accelerators, but %d were found",
+			si.InstanceType, expected, strings.ToUpper(vendor), found)
+		return true
+	}
+	log.Logf("found all %d %s accelerators of instance type %s", found, strings.ToUpper(vendor), si.InstanceType)
+	return false
+}
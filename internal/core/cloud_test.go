@@ -0,0 +1,51 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/sysinfo"
+)
+
+func TestCheckInstanceAccelerators(t *testing.T) {
+	tests := []struct {
+		name string
+		si   sysinfo.SysInfo
+		want bool
+	}{
+		{
+			name: "AllFound",
+			si:   sysinfo.SysInfo{Cloud: "aws", InstanceType: "p4d.24xlarge", Accelerators: map[string]int{"nvidia": 8}},
+		},
+		{
+			name: "Missing",
+			si:   sysinfo.SysInfo{Cloud: "aws", InstanceType: "p4d.24xlarge", Accelerators: map[string]int{"nvidia": 7}},
+			want: true,
+		},
+		{
+			name: "NoneFound",
+			si:   sysinfo.SysInfo{Cloud: "aws", InstanceType: "g4ad.8xlarge"},
+			want: true,
+		},
+		{
+			name: "UnknownFamily",
+			si:   sysinfo.SysInfo{Cloud: "aws", InstanceType: "m5.large"},
+		},
+		{
+			name: "UnknownInstanceType",
+			si:   sysinfo.SysInfo{Cloud: "azure"},
+		},
+		{
+			name: "NotCloud",
+			si:   sysinfo.SysInfo{Accelerators: map[string]int{"nvidia": 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkInstanceAccelerators(api.CoreDeps{SystemInfo: tt.si}); got != tt.want {
+				t.Errorf("checkInstanceAccelerators() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
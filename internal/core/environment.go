@@ -0,0 +1,50 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+)
+
+// ContainerError is returned when changing drivers inside a container,
+// where the kernel modules would never be loaded.
+type ContainerError struct {
+	Engine string
+}
+
+func (e *ContainerError) Error() string {
+	return fmt.Sprintf("running in %s container, where drivers cannot be installed or removed, as kernel modules are loaded by the host", e.Engine)
+}
+
+// Guidance describes how to resolve the problem.
+func (e *ContainerError) Guidance() []string {
+	return []string{
+		"Install drivers on the host instead, or into a bootable container image with",
+		"\"install --containerfile\".  To operate on a system mounted in the container,",
+		"use --installroot.",
+	}
+}
+
+// checkEnvironment refuses changing drivers in containers, other than
+// builds of bootable container images, unless forced.
+func checkEnvironment(deps api.CoreDeps, force bool) error {
+	si := deps.SystemInfo
+	if si.Container == "" || si.BootcBuild || si.Root != "" {
+		return nil
+	}
+	if force {
+		log.Warnf("changing drivers in %s container in force mode", si.Container)
+		return nil
+	}
+	return &ContainerError{Engine: si.Container}
+}
+
+// noHardwareError describes why no compatible hardware was found, with
+// a hint for virtual machines, which see only devices passed to them.
+func noHardwareError(deps api.CoreDeps) error {
+	if hv := deps.SystemInfo.Hypervisor; hv != "" {
+		return fmt.Errorf("no compatible hardware found in %s virtual machine; GPUs need to be passed through to the virtual machine, as vGPUs need drivers not provided by RHEL", hv)
+	}
+	return fmt.Errorf("no compatible hardware found")
+}
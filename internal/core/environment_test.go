@@ -0,0 +1,92 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/mocks"
+	"github.com/mizdebsk/rhel-drivers/internal/sysinfo"
+)
+
+func TestCheckEnvironment(t *testing.T) {
+	tests := []struct {
+		name      string
+		sysInfo   sysinfo.SysInfo
+		force     bool
+		expectErr bool
+	}{
+		{
+			name: "Host",
+		},
+		{
+			name:    "VirtualMachine",
+			sysInfo: sysinfo.SysInfo{Hypervisor: "kvm"},
+		},
+		{
+			name:      "Container",
+			sysInfo:   sysinfo.SysInfo{Container: "podman"},
+			expectErr: true,
+		},
+		{
+			name:    "ContainerForced",
+			sysInfo: sysinfo.SysInfo{Container: "podman"},
+			force:   true,
+		},
+		{
+			name:    "BootcBuild",
+			sysInfo: sysinfo.SysInfo{Container: "podman", BootcBuild: true},
+		},
+		{
+			name:    "InstallRoot",
+			sysInfo: sysinfo.SysInfo{Container: "docker", Root: "/mnt/sysimage"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkEnvironment(api.CoreDeps{SystemInfo: tt.sysInfo}, tt.force)
+			var containerErr *ContainerError
+			if errors.As(err, &containerErr) != tt.expectErr {
+				t.Fatalf("checkEnvironment() error = %v, expectErr %v", err, tt.expectErr)
+			}
+		})
+	}
+}
+
+func TestInstallInContainer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	deps := api.CoreDeps{
+		PackageManager:    mocks.NewMockPackageManager(ctrl),
+		RepositoryManager: mocks.NewMockRepositoryManager(ctrl),
+		Providers:         []api.Provider{mocks.NewMockProvider(ctrl)},
+		SystemInfo:        sysinfo.SysInfo{Container: "podman"},
+	}
+	var containerErr *ContainerError
+	if err := InstallAutoDetect(deps, true, false, false); !errors.As(err, &containerErr) {
+		t.Fatalf("InstallAutoDetect() error = %v, expected container error", err)
+	}
+	if err := InstallSpecific(deps, []string{"nvidia:580.95.05"}, true, false, false, false); !errors.As(err, &containerErr) {
+		t.Fatalf("InstallSpecific() error = %v, expected container error", err)
+	}
+	if err := doRemove(deps, []api.DriverID{{ProviderID: "nvidia", Version: "580.95.05"}}, true, false); !errors.As(err, &containerErr) {
+		t.Fatalf("doRemove() error = %v, expected container error", err)
+	}
+}
+
+func TestNoHardwareInVirtualMachine(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	p := mocks.NewMockProvider(ctrl)
+	p.EXPECT().DetectHardware().Return(false, nil)
+	deps := api.CoreDeps{
+		Providers:  []api.Provider{p},
+		SystemInfo: sysinfo.SysInfo{Hypervisor: "kvm"},
+	}
+	_, err := autoDetectDrivers(deps)
+	if err == nil || !strings.Contains(err.Error(), "kvm virtual machine") {
+		t.Fatalf("autoDetectDrivers() error = %v, expected hint for virtual machine", err)
+	}
+}
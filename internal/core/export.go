@@ -0,0 +1,56 @@
+package core
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/export"
+)
+
+// ExportSpecific writes a provisioning snippet in given format that
+// installs the same packages as InstallSpecific would.  Hardware is not
+// checked, as snippets are meant for other hosts.
+func ExportSpecific(deps api.CoreDeps, drivers []string, format string, multilib bool, out io.Writer) error {
+	if len(drivers) == 0 {
+		return fmt.Errorf("not specified what to export")
+	}
+	if err := export.CheckFormat(format); err != nil {
+		return err
+	}
+	toExport, err := findAvailableDrivers(deps, drivers)
+	if err != nil {
+		return err
+	}
+	return doExport(deps, toExport, format, multilib, out)
+}
+
+// ExportAutoDetect writes a provisioning snippet in given format that
+// installs drivers for hardware detected on this host.
+func ExportAutoDetect(deps api.CoreDeps, format string, multilib bool, out io.Writer) error {
+	if err := export.CheckFormat(format); err != nil {
+		return err
+	}
+	toExport, err := autoDetectDrivers(deps)
+	if err != nil {
+		return err
+	}
+	return doExport(deps, toExport, format, multilib, out)
+}
+
+func doExport(deps api.CoreDeps, toExport []api.DriverID, format string, multilib bool, out io.Writer) error {
+	// Repositories are enabled by the snippet on target hosts, not here.
+	repos, err := repositoryIDs(deps, toExport)
+	if err != nil {
+		return err
+	}
+	var enable []string
+	if len(repos) > 0 {
+		enable = deps.RepositoryManager.EnableCommand(repos)
+	}
+	allPkgs, err := collectInstallPackages(deps, toExport, multilib)
+	if err != nil {
+		return err
+	}
+	return export.Write(out, format, enable, allPkgs)
+}
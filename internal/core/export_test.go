@@ -0,0 +1,169 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/export"
+	"github.com/mizdebsk/rhel-drivers/internal/mocks"
+)
+
+func TestExport(t *testing.T) {
+	tests := []struct {
+		name       string
+		drivers    []string
+		autoDetect bool
+		format     string
+		setup      func(*mocks.MockProvider, *mocks.MockRepositoryManager)
+		expected   string
+		expectErr  bool
+	}{
+		{
+			name:      "EmptyDriversList",
+			format:    export.FormatKickstart,
+			setup:     func(p *mocks.MockProvider, rm *mocks.MockRepositoryManager) {},
+			expectErr: true,
+		},
+		{
+			name:      "UnknownFormat",
+			drivers:   []string{"nvidia:570.86.16"},
+			format:    "puppet",
+			setup:     func(p *mocks.MockProvider, rm *mocks.MockRepositoryManager) {},
+			expectErr: true,
+		},
+		{
+			name:    "SpecificDriver",
+			drivers: []string{"nvidia:570.86.16"},
+			format:  export.FormatCloudInit,
+			setup: func(p *mocks.MockProvider, rm *mocks.MockRepositoryManager) {
+				p.EXPECT().GetID().Return("nvidia").AnyTimes()
+				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
+				p.EXPECT().ListAvailable().Return([]api.DriverID{
+					{ProviderID: "nvidia", Version: "570.86.16"},
+				}, nil)
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().ListRepositories(testChannels).Return([]api.RepositoryStatus{
+					{Channel: "BaseOS"},
+					{Channel: "AppStream"},
+				}, nil)
+				p.EXPECT().Install([]api.DriverID{{ProviderID: "nvidia", Version: "570.86.16"}}, false).
+					Return([]string{"nvidia-driver-570.86.16"}, nil)
+			},
+			expected: "#cloud-config\npackages:\n  - \"nvidia-driver-570.86.16\"\n",
+		},
+		{
+			name:       "AutoDetect",
+			autoDetect: true,
+			format:     export.FormatAnsible,
+			setup: func(p *mocks.MockProvider, rm *mocks.MockRepositoryManager) {
+				p.EXPECT().GetID().Return("nvidia").AnyTimes()
+				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
+				p.EXPECT().DetectHardware().Return(true, nil)
+				p.EXPECT().ListAvailable().Return([]api.DriverID{
+					{ProviderID: "nvidia", Version: "580.95.05"},
+					{ProviderID: "nvidia", Version: "570.86.16"},
+				}, nil)
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				// Only listed, never enabled on the exporting host.
+				rm.EXPECT().ListRepositories(testChannels).Return([]api.RepositoryStatus{
+					{Channel: "BaseOS", ID: "rhel-10-for-x86_64-baseos-rpms", Defined: true, Enabled: true},
+					{Channel: "Extensions", ID: "rhel-10-for-x86_64-extensions-rpms", Defined: true},
+				}, nil)
+				rm.EXPECT().EnableCommand([]string{"rhel-10-for-x86_64-baseos-rpms", "rhel-10-for-x86_64-extensions-rpms"}).
+					Return([]string{"subscription-manager", "repos", "--enable", "rhel-10-for-x86_64-baseos-rpms", "--enable", "rhel-10-for-x86_64-extensions-rpms"})
+				p.EXPECT().Install([]api.DriverID{{ProviderID: "nvidia", Version: "580.95.05"}}, false).
+					Return([]string{"nvidia-driver-580.95.05"}, nil)
+			},
+			expected: "- name: Enable repositories of hardware drivers\n" +
+				"  ansible.builtin.command:\n" +
+				"    argv:\n" +
+				"      - \"subscription-manager\"\n" +
+				"      - \"repos\"\n" +
+				"      - \"--enable\"\n" +
+				"      - \"rhel-10-for-x86_64-baseos-rpms\"\n" +
+				"      - \"--enable\"\n" +
+				"      - \"rhel-10-for-x86_64-extensions-rpms\"\n" +
+				"- name: Install hardware drivers\n" +
+				"  ansible.builtin.dnf:\n" +
+				"    name:\n" +
+				"      - \"nvidia-driver-580.95.05\"\n" +
+				"    state: present\n",
+		},
+		{
+			name:    "RepositoriesNotManaged",
+			drivers: []string{"nvidia:570.86.16"},
+			format:  export.FormatKickstart,
+			setup: func(p *mocks.MockProvider, rm *mocks.MockRepositoryManager) {
+				p.EXPECT().GetID().Return("nvidia").AnyTimes()
+				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
+				p.EXPECT().ListAvailable().Return([]api.DriverID{
+					{ProviderID: "nvidia", Version: "570.86.16"},
+				}, nil)
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().ListRepositories(testChannels).Return([]api.RepositoryStatus{
+					{Channel: "BaseOS", Unmanaged: true},
+					{Channel: "AppStream", Unmanaged: true},
+				}, nil)
+				p.EXPECT().Install([]api.DriverID{{ProviderID: "nvidia", Version: "570.86.16"}}, false).
+					Return([]string{"nvidia-driver-570.86.16"}, nil)
+			},
+			expected: "%packages\nnvidia-driver-570.86.16\n%end\n",
+		},
+		{
+			name:    "ListRepositoriesFails",
+			drivers: []string{"nvidia:570.86.16"},
+			format:  export.FormatKickstart,
+			setup: func(p *mocks.MockProvider, rm *mocks.MockRepositoryManager) {
+				p.EXPECT().GetID().Return("nvidia").AnyTimes()
+				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
+				p.EXPECT().ListAvailable().Return([]api.DriverID{
+					{ProviderID: "nvidia", Version: "570.86.16"},
+				}, nil)
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().ListRepositories(testChannels).Return(nil, fmt.Errorf("redhat.repo unreadable"))
+			},
+			expectErr: true,
+		},
+		{
+			name:       "AutoDetectNoHardware",
+			autoDetect: true,
+			format:     export.FormatKickstart,
+			setup: func(p *mocks.MockProvider, rm *mocks.MockRepositoryManager) {
+				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
+				p.EXPECT().DetectHardware().Return(false, nil)
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockProvider := mocks.NewMockProvider(ctrl)
+			mockRepoMgr := mocks.NewMockRepositoryManager(ctrl)
+			tt.setup(mockProvider, mockRepoMgr)
+			deps := api.CoreDeps{
+				RepositoryManager: mockRepoMgr,
+				Providers:         []api.Provider{mockProvider},
+			}
+
+			var out strings.Builder
+			var err error
+			if tt.autoDetect {
+				err = ExportAutoDetect(deps, tt.format, false, &out)
+			} else {
+				err = ExportSpecific(deps, tt.drivers, tt.format, false, &out)
+			}
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Expected error: %v, but got: %v", tt.expectErr, err)
+			}
+			if out.String() != tt.expected {
+				t.Errorf("got output:\n%s\nwant:\n%s", out.String(), tt.expected)
+			}
+		})
+	}
+}
@@ -7,50 +7,62 @@ import (
 	"github.com/mizdebsk/rhel-drivers/internal/log"
 )
 
-func InstallSpecific(deps api.CoreDeps, drivers []string, batchMode, dryRun, force bool) error {
+func InstallSpecific(deps api.CoreDeps, drivers []string, batchMode, dryRun, force, multilib bool) error {
 	if len(drivers) == 0 {
 		return fmt.Errorf("not specified what to install")
 	}
+	if err := checkEnvironment(deps, force); err != nil {
+		return err
+	}
 
 	var toInstall []api.DriverID
 
-outer:
 	for _, driverStr := range drivers {
-		driver, provider, err := resolveDriver(deps, driverStr)
+		driver, provider, err := resolveDriverSpec(deps, driverStr)
 		if err != nil {
 			return err
 		}
-		available, err := provider.ListAvailable()
+		avail, err := findAvailable(deps, provider, driver)
 		if err != nil {
-			return fmt.Errorf("failed to list available %s drivers: %w", provider.GetName(), err)
+			return err
 		}
-		for _, avail := range available {
-			if avail.Version == driver.Version {
-				if !force {
-					compat, err := provider.DetectHardware()
-					if err != nil {
-						log.Warnf("hardware detection failed for %s failed: %v", provider.GetName(), err)
-					} else if !compat {
-						return fmt.Errorf("no compatible %s hardware found", provider.GetName())
-					} else {
-						log.Infof("compatible hardware %s found", provider.GetName())
-					}
-				} else {
-					log.Infof("not checking for %s hardware compatibility in force mode", provider.GetName())
-				}
-				toInstall = append(toInstall, driver)
-				continue outer
+		if deps.SystemInfo.BootcBuild {
+			log.Infof("not checking for %s hardware compatibility when building image", provider.GetName())
+		} else if !force {
+			compat, err := provider.DetectHardware()
+			if err != nil {
+				log.Warnf("hardware detection failed for %s failed: %v", provider.GetName(), err)
+			} else if !compat {
+				return fmt.Errorf("no compatible %s hardware found", provider.GetName())
+			} else {
+				log.Infof("compatible hardware %s found", provider.GetName())
 			}
+		} else {
+			log.Infof("not checking for %s hardware compatibility in force mode", provider.GetName())
 		}
-		return fmt.Errorf("%s driver version %s is NOT available", provider.GetName(), driver.Version)
+		toInstall = append(toInstall, avail)
 	}
 
-	return doInstall(deps, toInstall, batchMode, dryRun)
+	return doInstall(deps, toInstall, batchMode, dryRun, multilib)
 }
 
-func InstallAutoDetect(deps api.CoreDeps, batchMode, dryRun bool) error {
+func InstallAutoDetect(deps api.CoreDeps, batchMode, dryRun, multilib bool) error {
+	if err := checkEnvironment(deps, false); err != nil {
+		return err
+	}
+	toInstall, err := autoDetectDrivers(deps)
+	if err != nil {
+		return err
+	}
+	return doInstall(deps, toInstall, batchMode, dryRun, multilib)
+}
+
+// autoDetectDrivers returns the latest available driver allowed by
+// version policy for each provider whose hardware is detected.
+func autoDetectDrivers(deps api.CoreDeps) ([]api.DriverID, error) {
 	var toInstall []api.DriverID
 
+	checkInstanceAccelerators(deps)
 	hardwareDetected := false
 	for _, provider := range deps.Providers {
 		detected, err := provider.DetectHardware()
@@ -63,27 +75,74 @@ func InstallAutoDetect(deps api.CoreDeps, batchMode, dryRun bool) error {
 			log.Logf("detected %s hardware", provider.GetName())
 			available, err := provider.ListAvailable()
 			if err != nil {
-				return fmt.Errorf("failed to list available %s drivers: %w", provider.GetName(), err)
+				return nil, fmt.Errorf("failed to list available %s drivers: %w", provider.GetName(), err)
 			}
-			if len(available) > 0 {
-				toInstall = append(toInstall, available[0])
+			for _, avail := range available {
+				policy := deps.VersionPolicy[avail.ProviderID]
+				if policy.Match(avail.Version) {
+					toInstall = append(toInstall, avail)
+					break
+				}
+				log.Logf("skipping %s, not allowed by version policy %q", avail, policy)
 			}
 		}
 	}
 	if !hardwareDetected {
-		return fmt.Errorf("no compatible hardware found")
+		return nil, noHardwareError(deps)
 	}
 	if len(toInstall) == 0 {
-		return fmt.Errorf("no drivers available for detected hardware")
+		return nil, fmt.Errorf("no drivers available for detected hardware")
 	}
+	return toInstall, nil
+}
 
-	return doInstall(deps, toInstall, batchMode, dryRun)
+// findAvailableDrivers resolves given driver specifications to
+// available drivers, without checking hardware compatibility.
+func findAvailableDrivers(deps api.CoreDeps, drivers []string) ([]api.DriverID, error) {
+	var found []api.DriverID
+	for _, driverStr := range drivers {
+		driver, provider, err := resolveDriverSpec(deps, driverStr)
+		if err != nil {
+			return nil, err
+		}
+		avail, err := findAvailable(deps, provider, driver)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, avail)
+	}
+	return found, nil
 }
 
-func doInstall(deps api.CoreDeps, toInstall []api.DriverID, batchMode, dryRun bool) error {
-	if err := deps.RepositoryManager.EnsureRepositoriesEnabled(); err != nil {
-		return fmt.Errorf("failed to verify/enable repositories: %w", err)
+// findAvailable returns the latest available driver matching given
+// specification and allowed by version policy.
+func findAvailable(deps api.CoreDeps, provider api.Provider, driver driverSpec) (api.DriverID, error) {
+	available, err := provider.ListAvailable()
+	if err != nil {
+		return driver.DriverID, fmt.Errorf("failed to list available %s drivers: %w", provider.GetName(), err)
 	}
+	policy := deps.VersionPolicy[driver.ProviderID]
+	found := false
+	for _, avail := range available {
+		if driver.matches(avail) {
+			found = true
+			if policy.Match(avail.Version) {
+				return avail, nil
+			}
+		}
+	}
+	if found {
+		return driver.DriverID, fmt.Errorf("%s driver %s is not allowed by version policy %q", provider.GetName(), driver, policy)
+	}
+	if driver.constraint != nil {
+		return driver.DriverID, fmt.Errorf("no %s driver matching %q is available", provider.GetName(), driver.constraint)
+	}
+	return driver.DriverID, fmt.Errorf("%s driver version %s is NOT available", provider.GetName(), driver.FullVersion())
+}
+
+// collectInstallPackages asks providers which packages need to be
+// installed for given drivers.
+func collectInstallPackages(deps api.CoreDeps, toInstall []api.DriverID, multilib bool) ([]string, error) {
 	var allPkgs []string
 	for _, provider := range deps.Providers {
 		provID := provider.GetID()
@@ -94,20 +153,51 @@ func doInstall(deps api.CoreDeps, toInstall []api.DriverID, batchMode, dryRun bo
 			}
 		}
 		if len(provToInstall) != 0 {
-			pkgs, err := provider.Install(provToInstall)
+			pkgs, err := provider.Install(provToInstall, multilib)
 			if err != nil {
-				return fmt.Errorf("failed to install %s drivers: %w", provider.GetName(), err)
+				return nil, fmt.Errorf("failed to install %s drivers: %w", provider.GetName(), err)
 			}
 			allPkgs = append(allPkgs, pkgs...)
 		}
 	}
 
 	if len(allPkgs) == 0 {
-		return fmt.Errorf("nothing to install")
+		return nil, fmt.Errorf("nothing to install")
+	}
+	return allPkgs, nil
+}
+
+func doInstall(deps api.CoreDeps, toInstall []api.DriverID, batchMode, dryRun, multilib bool) error {
+	if dryRun {
+		// Nothing is going to be installed, so leave repositories alone
+		// and only tell which ones would be enabled.
+		reportRepositories(deps, toInstall)
+		return installPackages(deps, toInstall, batchMode, dryRun, multilib)
+	}
+	if err := ensureRepositoriesEnabled(deps, toInstall); err != nil {
+		return fmt.Errorf("failed to verify/enable repositories: %w", err)
+	}
+	err := runInterruptible(func() error {
+		return installPackages(deps, toInstall, batchMode, dryRun, multilib)
+	})
+	if err != nil {
+		// Don't leave repositories enabled when nothing was installed,
+		// whether the transaction failed, was declined by the user or
+		// cancelled with Ctrl-C.
+		rollbackRepositories(deps)
+	}
+	return err
+}
+
+func installPackages(deps api.CoreDeps, toInstall []api.DriverID, batchMode, dryRun, multilib bool) error {
+	allPkgs, err := collectInstallPackages(deps, toInstall, multilib)
+	if err != nil {
+		return err
 	}
 	for _, pkg := range allPkgs {
 		log.Logf("package will be installed: %v", pkg)
 	}
+	checkCryptoPolicy(deps, allPkgs)
 	if err := deps.PackageManager.Install(allPkgs, batchMode, dryRun); err != nil {
 		return fmt.Errorf("failed to install pacakges: %w", err)
 	}
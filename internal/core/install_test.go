@@ -2,14 +2,26 @@ package core
 
 import (
 	"fmt"
+	"os"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 
 	"github.com/mizdebsk/rhel-drivers/internal/api"
 	"github.com/mizdebsk/rhel-drivers/internal/mocks"
+	"github.com/mizdebsk/rhel-drivers/internal/rpmver"
 )
 
+func mustParseConstraint(t *testing.T, s string) rpmver.Constraint {
+	c, err := rpmver.ParseConstraint(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
 func TestInstallSpecific(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -17,6 +29,8 @@ func TestInstallSpecific(t *testing.T) {
 		batchMode bool
 		dryRun    bool
 		force     bool
+		bootc     bool
+		policy    string
 		setup     func(*mocks.MockProvider, *mocks.MockPackageManager, *mocks.MockRepositoryManager)
 		expectErr bool
 	}{
@@ -86,8 +100,25 @@ func TestInstallSpecific(t *testing.T) {
 				p.EXPECT().ListAvailable().Return([]api.DriverID{
 					{ProviderID: "nvidia", Version: "570.86.16"},
 				}, nil)
-				rm.EXPECT().EnsureRepositoriesEnabled().Return(nil)
-				p.EXPECT().Install([]api.DriverID{{ProviderID: "nvidia", Version: "570.86.16"}}).Return([]string{"nvidia-driver"}, nil)
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().EnsureRepositoriesEnabled(testChannels).Return(nil)
+				p.EXPECT().Install([]api.DriverID{{ProviderID: "nvidia", Version: "570.86.16"}}, false).Return([]string{"nvidia-driver"}, nil)
+				pm.EXPECT().Install([]string{"nvidia-driver"}, false, false).Return(nil)
+			},
+		},
+		{
+			name:    "BootcBuildSkipsHardwareCheck",
+			drivers: []string{"nvidia:570.86.16"},
+			bootc:   true,
+			setup: func(p *mocks.MockProvider, pm *mocks.MockPackageManager, rm *mocks.MockRepositoryManager) {
+				p.EXPECT().GetID().Return("nvidia").AnyTimes()
+				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
+				p.EXPECT().ListAvailable().Return([]api.DriverID{
+					{ProviderID: "nvidia", Version: "570.86.16"},
+				}, nil)
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().EnsureRepositoriesEnabled(testChannels).Return(nil)
+				p.EXPECT().Install([]api.DriverID{{ProviderID: "nvidia", Version: "570.86.16"}}, false).Return([]string{"nvidia-driver"}, nil)
 				pm.EXPECT().Install([]string{"nvidia-driver"}, false, false).Return(nil)
 			},
 		},
@@ -102,11 +133,114 @@ func TestInstallSpecific(t *testing.T) {
 					{ProviderID: "nvidia", Version: "570.86.16"},
 				}, nil)
 				p.EXPECT().DetectHardware().Return(true, nil)
-				rm.EXPECT().EnsureRepositoriesEnabled().Return(nil)
-				p.EXPECT().Install([]api.DriverID{{ProviderID: "nvidia", Version: "570.86.16"}}).Return([]string{"nvidia-driver"}, nil)
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().EnsureRepositoriesEnabled(testChannels).Return(nil)
+				p.EXPECT().Install([]api.DriverID{{ProviderID: "nvidia", Version: "570.86.16"}}, false).Return([]string{"nvidia-driver"}, nil)
+				pm.EXPECT().Install([]string{"nvidia-driver"}, false, false).Return(nil)
+			},
+		},
+		{
+			name:      "SuccessfulInstallSpecificRelease",
+			drivers:   []string{"nvidia:580.95.05-1.el10"},
+			expectErr: false,
+			setup: func(p *mocks.MockProvider, pm *mocks.MockPackageManager, rm *mocks.MockRepositoryManager) {
+				p.EXPECT().GetID().Return("nvidia").AnyTimes()
+				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
+				p.EXPECT().ListAvailable().Return([]api.DriverID{
+					{ProviderID: "nvidia", Version: "580.95.05", Release: "2.el10", Repo: "updates"},
+					{ProviderID: "nvidia", Version: "580.95.05", Release: "1.el10", Repo: "base"},
+				}, nil)
+				p.EXPECT().DetectHardware().Return(true, nil)
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().EnsureRepositoriesEnabled(testChannels).Return(nil)
+				p.EXPECT().Install([]api.DriverID{{ProviderID: "nvidia", Version: "580.95.05", Release: "1.el10", Repo: "base"}}, false).Return([]string{"nvidia-driver"}, nil)
+				pm.EXPECT().Install([]string{"nvidia-driver"}, false, false).Return(nil)
+			},
+		},
+		{
+			name:      "ReleaseNotAvailable",
+			drivers:   []string{"nvidia:580.95.05-3.el10"},
+			expectErr: true,
+			setup: func(p *mocks.MockProvider, pm *mocks.MockPackageManager, rm *mocks.MockRepositoryManager) {
+				p.EXPECT().GetID().Return("nvidia").AnyTimes()
+				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
+				p.EXPECT().ListAvailable().Return([]api.DriverID{
+					{ProviderID: "nvidia", Version: "580.95.05", Release: "2.el10", Repo: "updates"},
+				}, nil)
+			},
+		},
+		{
+			name:    "ConstraintSelectsLatestMatching",
+			drivers: []string{"nvidia:< 590"},
+			setup: func(p *mocks.MockProvider, pm *mocks.MockPackageManager, rm *mocks.MockRepositoryManager) {
+				p.EXPECT().GetID().Return("nvidia").AnyTimes()
+				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
+				p.EXPECT().ListAvailable().Return([]api.DriverID{
+					{ProviderID: "nvidia", Version: "590.48.01"},
+					{ProviderID: "nvidia", Version: "580.95.05"},
+					{ProviderID: "nvidia", Version: "570.86.16"},
+				}, nil)
+				p.EXPECT().DetectHardware().Return(true, nil)
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().EnsureRepositoriesEnabled(testChannels).Return(nil)
+				p.EXPECT().Install([]api.DriverID{{ProviderID: "nvidia", Version: "580.95.05"}}, false).Return([]string{"nvidia-driver"}, nil)
 				pm.EXPECT().Install([]string{"nvidia-driver"}, false, false).Return(nil)
 			},
 		},
+		{
+			name:      "ConstraintNotSatisfied",
+			drivers:   []string{"nvidia:~> 575.51"},
+			expectErr: true,
+			setup: func(p *mocks.MockProvider, pm *mocks.MockPackageManager, rm *mocks.MockRepositoryManager) {
+				p.EXPECT().GetID().Return("nvidia").AnyTimes()
+				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
+				p.EXPECT().ListAvailable().Return([]api.DriverID{
+					{ProviderID: "nvidia", Version: "590.48.01"},
+					{ProviderID: "nvidia", Version: "580.95.05"},
+					{ProviderID: "nvidia", Version: "570.86.16"},
+				}, nil)
+			},
+		},
+		{
+			name:      "InvalidConstraint",
+			drivers:   []string{"nvidia:> 580.*"},
+			expectErr: true,
+			setup:     func(p *mocks.MockProvider, pm *mocks.MockPackageManager, rm *mocks.MockRepositoryManager) {},
+		},
+		{
+			name:    "PolicyAppliesToConstraint",
+			drivers: []string{"nvidia:>= 570"},
+			policy:  "= 570.*",
+			setup: func(p *mocks.MockProvider, pm *mocks.MockPackageManager, rm *mocks.MockRepositoryManager) {
+				p.EXPECT().GetID().Return("nvidia").AnyTimes()
+				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
+				p.EXPECT().ListAvailable().Return([]api.DriverID{
+					{ProviderID: "nvidia", Version: "590.48.01"},
+					{ProviderID: "nvidia", Version: "580.95.05"},
+					{ProviderID: "nvidia", Version: "570.86.16"},
+				}, nil)
+				p.EXPECT().DetectHardware().Return(true, nil)
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().EnsureRepositoriesEnabled(testChannels).Return(nil)
+				p.EXPECT().Install([]api.DriverID{{ProviderID: "nvidia", Version: "570.86.16"}}, false).Return([]string{"nvidia-driver"}, nil)
+				pm.EXPECT().Install([]string{"nvidia-driver"}, false, false).Return(nil)
+			},
+		},
+		{
+			name:      "PolicyForbidsVersion",
+			drivers:   []string{"nvidia:590.48.01"},
+			policy:    "< 590",
+			expectErr: true,
+			setup: func(p *mocks.MockProvider, pm *mocks.MockPackageManager, rm *mocks.MockRepositoryManager) {
+				p.EXPECT().GetID().Return("nvidia").AnyTimes()
+				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
+				p.EXPECT().ListAvailable().Return([]api.DriverID{
+					{ProviderID: "nvidia", Version: "590.48.01"},
+					{ProviderID: "nvidia", Version: "580.95.05"},
+					{ProviderID: "nvidia", Version: "570.86.16"},
+				}, nil)
+			},
+		},
 		{
 			name:      "RepositoryEnableFails",
 			drivers:   []string{"nvidia:570.86.16"},
@@ -118,7 +252,8 @@ func TestInstallSpecific(t *testing.T) {
 					{ProviderID: "nvidia", Version: "570.86.16"},
 				}, nil)
 				p.EXPECT().DetectHardware().Return(true, nil)
-				rm.EXPECT().EnsureRepositoriesEnabled().Return(fmt.Errorf("repo enable failed"))
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().EnsureRepositoriesEnabled(testChannels).Return(fmt.Errorf("repo enable failed"))
 			},
 		},
 		{
@@ -132,8 +267,10 @@ func TestInstallSpecific(t *testing.T) {
 					{ProviderID: "nvidia", Version: "570.86.16"},
 				}, nil)
 				p.EXPECT().DetectHardware().Return(true, nil)
-				rm.EXPECT().EnsureRepositoriesEnabled().Return(nil)
-				p.EXPECT().Install([]api.DriverID{{ProviderID: "nvidia", Version: "570.86.16"}}).Return(nil, fmt.Errorf("install failed"))
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().EnsureRepositoriesEnabled(testChannels).Return(nil)
+				p.EXPECT().Install([]api.DriverID{{ProviderID: "nvidia", Version: "570.86.16"}}, false).Return(nil, fmt.Errorf("install failed"))
+				rm.EXPECT().RollbackRepositories().Return(nil)
 			},
 		},
 		{
@@ -147,9 +284,89 @@ func TestInstallSpecific(t *testing.T) {
 					{ProviderID: "nvidia", Version: "570.86.16"},
 				}, nil)
 				p.EXPECT().DetectHardware().Return(true, nil)
-				rm.EXPECT().EnsureRepositoriesEnabled().Return(nil)
-				p.EXPECT().Install([]api.DriverID{{ProviderID: "nvidia", Version: "570.86.16"}}).Return([]string{"nvidia-driver"}, nil)
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().EnsureRepositoriesEnabled(testChannels).Return(nil)
+				p.EXPECT().Install([]api.DriverID{{ProviderID: "nvidia", Version: "570.86.16"}}, false).Return([]string{"nvidia-driver"}, nil)
 				pm.EXPECT().Install([]string{"nvidia-driver"}, false, false).Return(fmt.Errorf("dnf failed"))
+				rm.EXPECT().RollbackRepositories().Return(nil)
+			},
+		},
+		{
+			name:      "RollbackFails",
+			drivers:   []string{"nvidia:570.86.16"},
+			expectErr: true,
+			setup: func(p *mocks.MockProvider, pm *mocks.MockPackageManager, rm *mocks.MockRepositoryManager) {
+				p.EXPECT().GetID().Return("nvidia").AnyTimes()
+				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
+				p.EXPECT().ListAvailable().Return([]api.DriverID{
+					{ProviderID: "nvidia", Version: "570.86.16"},
+				}, nil)
+				p.EXPECT().DetectHardware().Return(true, nil)
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().EnsureRepositoriesEnabled(testChannels).Return(nil)
+				p.EXPECT().Install([]api.DriverID{{ProviderID: "nvidia", Version: "570.86.16"}}, false).Return([]string{"nvidia-driver"}, nil)
+				pm.EXPECT().Install([]string{"nvidia-driver"}, false, false).Return(fmt.Errorf("user declined"))
+				rm.EXPECT().RollbackRepositories().Return(fmt.Errorf("subscription-manager failed"))
+			},
+		},
+		{
+			name:    "DryRunLeavesRepositories",
+			drivers: []string{"nvidia:570.86.16"},
+			dryRun:  true,
+			setup: func(p *mocks.MockProvider, pm *mocks.MockPackageManager, rm *mocks.MockRepositoryManager) {
+				p.EXPECT().GetID().Return("nvidia").AnyTimes()
+				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
+				p.EXPECT().ListAvailable().Return([]api.DriverID{
+					{ProviderID: "nvidia", Version: "570.86.16"},
+				}, nil)
+				p.EXPECT().DetectHardware().Return(true, nil)
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().ListRepositories(testChannels).Return([]api.RepositoryStatus{
+					{Channel: api.ChannelBaseOS, ID: "baseos", Defined: true, Enabled: true},
+					{Channel: api.ChannelExtensions, ID: "extensions", Defined: true},
+				}, nil)
+				p.EXPECT().Install([]api.DriverID{{ProviderID: "nvidia", Version: "570.86.16"}}, false).Return([]string{"nvidia-driver"}, nil)
+				pm.EXPECT().Install([]string{"nvidia-driver"}, false, true).Return(nil)
+			},
+		},
+		{
+			name:    "DryRunListRepositoriesFails",
+			drivers: []string{"nvidia:570.86.16"},
+			dryRun:  true,
+			setup: func(p *mocks.MockProvider, pm *mocks.MockPackageManager, rm *mocks.MockRepositoryManager) {
+				p.EXPECT().GetID().Return("nvidia").AnyTimes()
+				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
+				p.EXPECT().ListAvailable().Return([]api.DriverID{
+					{ProviderID: "nvidia", Version: "570.86.16"},
+				}, nil)
+				p.EXPECT().DetectHardware().Return(true, nil)
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().ListRepositories(testChannels).Return(nil, fmt.Errorf("redhat.repo unreadable"))
+				p.EXPECT().Install([]api.DriverID{{ProviderID: "nvidia", Version: "570.86.16"}}, false).Return([]string{"nvidia-driver"}, nil)
+				pm.EXPECT().Install([]string{"nvidia-driver"}, false, true).Return(nil)
+			},
+		},
+		{
+			name:      "InterruptedRollsBack",
+			drivers:   []string{"nvidia:570.86.16"},
+			expectErr: true,
+			setup: func(p *mocks.MockProvider, pm *mocks.MockPackageManager, rm *mocks.MockRepositoryManager) {
+				p.EXPECT().GetID().Return("nvidia").AnyTimes()
+				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
+				p.EXPECT().ListAvailable().Return([]api.DriverID{
+					{ProviderID: "nvidia", Version: "570.86.16"},
+				}, nil)
+				p.EXPECT().DetectHardware().Return(true, nil)
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().EnsureRepositoriesEnabled(testChannels).Return(nil)
+				p.EXPECT().Install([]api.DriverID{{ProviderID: "nvidia", Version: "570.86.16"}}, false).Return([]string{"nvidia-driver"}, nil)
+				// Ctrl-C at dnf prompt reaches both dnf and rhel-drivers.
+				pm.EXPECT().Install([]string{"nvidia-driver"}, false, false).DoAndReturn(func([]string, bool, bool) error {
+					_ = syscall.Kill(os.Getpid(), syscall.SIGINT)
+					time.Sleep(100 * time.Millisecond)
+					return fmt.Errorf("dnf was interrupted")
+				})
+				rm.EXPECT().RollbackRepositories().Return(nil)
 			},
 		},
 	}
@@ -170,8 +387,12 @@ func TestInstallSpecific(t *testing.T) {
 				RepositoryManager: mockRM,
 				Providers:         []api.Provider{mockProvider},
 			}
+			deps.SystemInfo.BootcBuild = tt.bootc
+			if tt.policy != "" {
+				deps.VersionPolicy = map[string]rpmver.Constraint{"nvidia": mustParseConstraint(t, tt.policy)}
+			}
 
-			err := InstallSpecific(deps, tt.drivers, tt.batchMode, tt.dryRun, tt.force)
+			err := InstallSpecific(deps, tt.drivers, tt.batchMode, tt.dryRun, tt.force, false)
 			if (err != nil) != tt.expectErr {
 				t.Errorf("InstallSpecific() error = %v, expectErr %v", err, tt.expectErr)
 			}
@@ -182,6 +403,7 @@ func TestInstallSpecific(t *testing.T) {
 func TestInstallAutoDetect(t *testing.T) {
 	tests := []struct {
 		name      string
+		policy    string
 		setup     func(*mocks.MockProvider, *mocks.MockPackageManager, *mocks.MockRepositoryManager)
 		expectErr bool
 	}{
@@ -221,11 +443,44 @@ func TestInstallAutoDetect(t *testing.T) {
 				p.EXPECT().ListAvailable().Return([]api.DriverID{
 					{ProviderID: "nvidia", Version: "570.86.16"},
 				}, nil)
-				rm.EXPECT().EnsureRepositoriesEnabled().Return(nil)
-				p.EXPECT().Install([]api.DriverID{{ProviderID: "nvidia", Version: "570.86.16"}}).Return([]string{"nvidia-driver"}, nil)
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().EnsureRepositoriesEnabled(testChannels).Return(nil)
+				p.EXPECT().Install([]api.DriverID{{ProviderID: "nvidia", Version: "570.86.16"}}, false).Return([]string{"nvidia-driver"}, nil)
+				pm.EXPECT().Install([]string{"nvidia-driver"}, false, false).Return(nil)
+			},
+		},
+		{
+			name:   "PolicyLimitsAutoDetect",
+			policy: ">= 570, < 590",
+			setup: func(p *mocks.MockProvider, pm *mocks.MockPackageManager, rm *mocks.MockRepositoryManager) {
+				p.EXPECT().GetID().Return("nvidia").AnyTimes()
+				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
+				p.EXPECT().DetectHardware().Return(true, nil)
+				p.EXPECT().ListAvailable().Return([]api.DriverID{
+					{ProviderID: "nvidia", Version: "590.48.01"},
+					{ProviderID: "nvidia", Version: "580.95.05"},
+					{ProviderID: "nvidia", Version: "570.86.16"},
+				}, nil)
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().EnsureRepositoriesEnabled(testChannels).Return(nil)
+				p.EXPECT().Install([]api.DriverID{{ProviderID: "nvidia", Version: "580.95.05"}}, false).Return([]string{"nvidia-driver"}, nil)
 				pm.EXPECT().Install([]string{"nvidia-driver"}, false, false).Return(nil)
 			},
 		},
+		{
+			name:      "PolicyExcludesAllAvailable",
+			policy:    "< 500",
+			expectErr: true,
+			setup: func(p *mocks.MockProvider, pm *mocks.MockPackageManager, rm *mocks.MockRepositoryManager) {
+				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
+				p.EXPECT().DetectHardware().Return(true, nil)
+				p.EXPECT().ListAvailable().Return([]api.DriverID{
+					{ProviderID: "nvidia", Version: "590.48.01"},
+					{ProviderID: "nvidia", Version: "580.95.05"},
+					{ProviderID: "nvidia", Version: "570.86.16"},
+				}, nil)
+			},
+		},
 		{
 			name:      "RepositoryEnableFails",
 			expectErr: true,
@@ -236,7 +491,8 @@ func TestInstallAutoDetect(t *testing.T) {
 				p.EXPECT().ListAvailable().Return([]api.DriverID{
 					{ProviderID: "nvidia", Version: "570.86.16"},
 				}, nil)
-				rm.EXPECT().EnsureRepositoriesEnabled().Return(fmt.Errorf("repo error"))
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().EnsureRepositoriesEnabled(testChannels).Return(fmt.Errorf("repo error"))
 			},
 		},
 	}
@@ -257,8 +513,11 @@ func TestInstallAutoDetect(t *testing.T) {
 				RepositoryManager: mockRM,
 				Providers:         []api.Provider{mockProvider},
 			}
+			if tt.policy != "" {
+				deps.VersionPolicy = map[string]rpmver.Constraint{"nvidia": mustParseConstraint(t, tt.policy)}
+			}
 
-			err := InstallAutoDetect(deps, false, false)
+			err := InstallAutoDetect(deps, false, false, false)
 			if (err != nil) != tt.expectErr {
 				t.Errorf("InstallAutoDetect() error = %v, expectErr %v", err, tt.expectErr)
 			}
@@ -0,0 +1,26 @@
+package core
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+)
+
+// runInterruptible runs f with SIGINT and SIGTERM caught instead of
+// terminating the process, so that changes made before can be rolled
+// back when f fails.  Commands run by f, such as dnf waiting for
+// confirmation, still receive the signal from terminal and abort.
+func runInterruptible(f func() error) error {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+	err := f()
+	select {
+	case sig := <-sigs:
+		log.Warnf("interrupted by %v signal", sig)
+	default:
+	}
+	return err
+}
@@ -8,14 +8,24 @@ import (
 )
 
 func List(deps api.CoreDeps, listInst, listAvail, hwdetect bool) ([]api.DriverStatus, error) {
+	if !listAvail {
+		return listDrivers(deps, listInst, listAvail, hwdetect)
+	}
 	var result []api.DriverStatus
+	err := withRepositories(deps, requiredChannels(deps.SystemInfo, deps.Providers), func() error {
+		var err error
+		result, err = listDrivers(deps, listInst, listAvail, hwdetect)
+		return err
+	})
+	return result, err
+}
 
-	if listAvail {
-		if err := deps.RepositoryManager.EnsureRepositoriesEnabled(); err != nil {
-			return result, fmt.Errorf("failed to verify/enable repositories: %w", err)
-		}
-	}
+func listDrivers(deps api.CoreDeps, listInst, listAvail, hwdetect bool) ([]api.DriverStatus, error) {
+	var result []api.DriverStatus
 
+	if hwdetect {
+		checkInstanceAccelerators(deps)
+	}
 	for _, provider := range deps.Providers {
 		var compat bool
 		if hwdetect {
@@ -51,27 +61,27 @@ func List(deps api.CoreDeps, listInst, listAvail, hwdetect bool) ([]api.DriverSt
 				log.Logf("%s driver is currently NOT available", provider.GetName())
 			}
 		}
-		var all []string
+		var all []api.DriverID
 		installedSet := make(map[string]struct{})
 		availableSet := make(map[string]struct{})
 		for _, avail := range available {
-			ver := avail.Version
-			all = append(all, ver)
-			availableSet[ver] = struct{}{}
+			all = append(all, avail)
+			availableSet[evrKey(avail)] = struct{}{}
 		}
 		for _, inst := range installed {
-			ver := inst.Version
-			if _, ok := availableSet[ver]; !ok {
-				all = append(all, ver)
+			key := evrKey(inst)
+			if _, ok := availableSet[key]; !ok {
+				all = append(all, inst)
 			}
-			installedSet[ver] = struct{}{}
+			installedSet[key] = struct{}{}
 		}
-		for _, ver := range all {
-			_, inst := installedSet[ver]
-			_, avail := availableSet[ver]
+		for _, driver := range all {
+			_, inst := installedSet[evrKey(driver)]
+			_, avail := availableSet[evrKey(driver)]
+			driver.ProviderID = provider.GetID()
 			result = append(result,
 				api.DriverStatus{
-					ID:         api.DriverID{ProviderID: provider.GetID(), Version: ver},
+					ID:         driver,
 					Available:  avail,
 					Installed:  inst,
 					Compatible: compat,
@@ -81,3 +91,13 @@ func List(deps api.CoreDeps, listInst, listAvail, hwdetect bool) ([]api.DriverSt
 
 	return result, nil
 }
+
+// evrKey identifies driver build regardless of repository and
+// architecture it comes from.
+func evrKey(driver api.DriverID) string {
+	epoch := driver.Epoch
+	if epoch == "" {
+		epoch = "0"
+	}
+	return epoch + ":" + driver.Version + "-" + driver.Release
+}
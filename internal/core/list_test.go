@@ -50,7 +50,9 @@ func TestList(t *testing.T) {
 			setup: func(p *mocks.MockProvider, rm *mocks.MockRepositoryManager) {
 				p.EXPECT().GetID().Return("nvidia").AnyTimes()
 				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
-				rm.EXPECT().EnsureRepositoriesEnabled().Return(nil)
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().EnsureRepositoriesEnabled(testChannels).Return(nil)
+				rm.EXPECT().RollbackRepositories().Return(nil)
 				p.EXPECT().ListAvailable().Return([]api.DriverID{
 					{ProviderID: "nvidia", Version: "570.86.16"},
 				}, nil)
@@ -72,7 +74,9 @@ func TestList(t *testing.T) {
 			setup: func(p *mocks.MockProvider, rm *mocks.MockRepositoryManager) {
 				p.EXPECT().GetID().Return("nvidia").AnyTimes()
 				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
-				rm.EXPECT().EnsureRepositoriesEnabled().Return(nil)
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().EnsureRepositoriesEnabled(testChannels).Return(nil)
+				rm.EXPECT().RollbackRepositories().Return(nil)
 				p.EXPECT().ListInstalled().Return([]api.DriverID{
 					{ProviderID: "nvidia", Version: "570.86.16"},
 				}, nil)
@@ -95,6 +99,39 @@ func TestList(t *testing.T) {
 				return nil
 			},
 		},
+		{
+			name:      "ListDistinguishesReleases",
+			listInst:  true,
+			listAvail: true,
+			hwdetect:  false,
+			setup: func(p *mocks.MockProvider, rm *mocks.MockRepositoryManager) {
+				p.EXPECT().GetID().Return("nvidia").AnyTimes()
+				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().EnsureRepositoriesEnabled(testChannels).Return(nil)
+				rm.EXPECT().RollbackRepositories().Return(nil)
+				p.EXPECT().ListInstalled().Return([]api.DriverID{
+					{ProviderID: "nvidia", Version: "580.95.05", Release: "1.el10"},
+				}, nil)
+				p.EXPECT().ListAvailable().Return([]api.DriverID{
+					{ProviderID: "nvidia", Epoch: "0", Version: "580.95.05", Release: "2.el10", Repo: "updates"},
+					{ProviderID: "nvidia", Epoch: "0", Version: "580.95.05", Release: "1.el10", Repo: "base"},
+				}, nil)
+			},
+			expectErr: false,
+			expectLen: 2,
+			checkFunc: func(result []api.DriverStatus) error {
+				for _, r := range result {
+					if r.ID.Release == "1.el10" && (!r.Installed || !r.Available || r.ID.Repo != "base") {
+						return fmt.Errorf("580.95.05-1.el10 should be both installed and available from base")
+					}
+					if r.ID.Release == "2.el10" && (r.Installed || !r.Available) {
+						return fmt.Errorf("580.95.05-2.el10 should be available but not installed")
+					}
+				}
+				return nil
+			},
+		},
 		{
 			name:      "ListWithHardwareDetection",
 			listInst:  true,
@@ -104,7 +141,9 @@ func TestList(t *testing.T) {
 				p.EXPECT().GetID().Return("nvidia").AnyTimes()
 				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
 				p.EXPECT().DetectHardware().Return(true, nil)
-				rm.EXPECT().EnsureRepositoriesEnabled().Return(nil)
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().EnsureRepositoriesEnabled(testChannels).Return(nil)
+				rm.EXPECT().RollbackRepositories().Return(nil)
 				p.EXPECT().ListInstalled().Return([]api.DriverID{}, nil)
 				p.EXPECT().ListAvailable().Return([]api.DriverID{
 					{ProviderID: "nvidia", Version: "570.86.16"},
@@ -125,7 +164,8 @@ func TestList(t *testing.T) {
 			listAvail: true,
 			hwdetect:  false,
 			setup: func(p *mocks.MockProvider, rm *mocks.MockRepositoryManager) {
-				rm.EXPECT().EnsureRepositoriesEnabled().Return(fmt.Errorf("repo error"))
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().EnsureRepositoriesEnabled(testChannels).Return(fmt.Errorf("repo error"))
 			},
 			expectErr: true,
 			expectLen: 0,
@@ -151,7 +191,9 @@ func TestList(t *testing.T) {
 			setup: func(p *mocks.MockProvider, rm *mocks.MockRepositoryManager) {
 				p.EXPECT().GetID().Return("nvidia").AnyTimes()
 				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
-				rm.EXPECT().EnsureRepositoriesEnabled().Return(nil)
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().EnsureRepositoriesEnabled(testChannels).Return(nil)
+				rm.EXPECT().RollbackRepositories().Return(nil)
 				p.EXPECT().ListInstalled().Return([]api.DriverID{}, nil)
 				p.EXPECT().ListAvailable().Return(nil, fmt.Errorf("list failed"))
 			},
@@ -166,7 +208,9 @@ func TestList(t *testing.T) {
 			setup: func(p *mocks.MockProvider, rm *mocks.MockRepositoryManager) {
 				p.EXPECT().GetID().Return("nvidia").AnyTimes()
 				p.EXPECT().GetName().Return("NVIDIA").AnyTimes()
-				rm.EXPECT().EnsureRepositoriesEnabled().Return(nil)
+				p.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels).AnyTimes()
+				rm.EXPECT().EnsureRepositoriesEnabled(testChannels).Return(nil)
+				rm.EXPECT().RollbackRepositories().Return(nil)
 				p.EXPECT().ListInstalled().Return([]api.DriverID{}, nil)
 				p.EXPECT().ListAvailable().Return([]api.DriverID{}, nil)
 			},
@@ -0,0 +1,25 @@
+package core
+
+import (
+	"slices"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+)
+
+// CheckVersionPolicy warns about version policy of providers that do
+// not exist, which would otherwise be silently ignored, and returns
+// their IDs.
+func CheckVersionPolicy(deps api.CoreDeps) []string {
+	var unknown []string
+	for id := range deps.VersionPolicy {
+		if !slices.ContainsFunc(deps.Providers, func(p api.Provider) bool { return p.GetID() == id }) {
+			unknown = append(unknown, id)
+		}
+	}
+	slices.Sort(unknown)
+	for _, id := range unknown {
+		log.Warnf("ignoring version policy of unknown provider %q", id)
+	}
+	return unknown
+}
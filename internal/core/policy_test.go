@@ -0,0 +1,33 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/mocks"
+	"github.com/mizdebsk/rhel-drivers/internal/rpmver"
+)
+
+func TestCheckVersionPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	nvidia := mocks.NewMockProvider(ctrl)
+	nvidia.EXPECT().GetID().Return("nvidia").AnyTimes()
+	amd := mocks.NewMockProvider(ctrl)
+	amd.EXPECT().GetID().Return("amdgpu").AnyTimes()
+	deps := api.CoreDeps{
+		Providers: []api.Provider{nvidia, amd},
+		VersionPolicy: map[string]rpmver.Constraint{
+			"nvidia": mustParseConstraint(t, "< 590"),
+			"amdgpu": mustParseConstraint(t, "~> 6.4"),
+			"amd":    mustParseConstraint(t, "~> 6.4"),
+			"intel":  mustParseConstraint(t, ">= 1"),
+		},
+	}
+	want := []string{"amd", "intel"}
+	if got := CheckVersionPolicy(deps); !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckVersionPolicy() = %v, want %v", got, want)
+	}
+}
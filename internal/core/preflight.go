@@ -0,0 +1,46 @@
+package core
+
+import (
+	"slices"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+)
+
+// checkCryptoPolicy reports whether repositories providing packages to
+// be installed, and GPG keys they use, are acceptable under the active
+// crypto policy.  Problems are only reported, dnf and rpm decide what
+// they accept.
+func checkCryptoPolicy(deps api.CoreDeps, pkgs []string) {
+	checker := deps.PolicyChecker
+	if checker == nil {
+		return
+	}
+	available, err := deps.PackageManager.ListAvailablePackages(api.PackageQuery{Names: pkgs})
+	if err != nil {
+		log.Warnf("unable to check repositories against crypto policy: %v", err)
+		return
+	}
+	var repos []string
+	for _, pkg := range available {
+		if pkg.Repo != "" && !slices.Contains(repos, pkg.Repo) {
+			repos = append(repos, pkg.Repo)
+		}
+	}
+	slices.Sort(repos)
+	findings, err := checker.CheckRepositories(repos)
+	if err != nil {
+		log.Warnf("unable to check repositories against crypto policy: %v", err)
+		return
+	}
+	for _, finding := range findings {
+		log.Warnf("repository %s: %s", finding.Repo, finding.Problem)
+	}
+	// Dependencies are resolved only by the transaction itself, so
+	// repositories they come from are not known here.
+	if len(findings) != 0 {
+		log.Warnf("%d problems found in repositories of selected packages under crypto policy %s (repositories of dependencies not checked)", len(findings), checker.Policy())
+		return
+	}
+	log.Logf("repositories of selected packages are acceptable under crypto policy %s (repositories of dependencies not checked)", checker.Policy())
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/mocks"
+)
+
+func TestCheckCryptoPolicy(t *testing.T) {
+	pkgs := []string{"nvidia-driver-580.95.05", "kmod-nvidia-580.95.05-6.12.0-55.el10"}
+	tests := []struct {
+		name  string
+		setup func(*mocks.MockPackageManager, *mocks.MockPolicyChecker)
+	}{
+		{
+			name: "Acceptable",
+			setup: func(pm *mocks.MockPackageManager, pc *mocks.MockPolicyChecker) {
+				pm.EXPECT().ListAvailablePackages(api.PackageQuery{Names: pkgs}).Return([]api.PackageInfo{
+					{Name: "nvidia-driver", Version: "580.95.05", Repo: "cuda-rhel10-x86_64"},
+					{Name: "kmod-nvidia-580.95.05-6.12.0-55.el10", Version: "580.95.05", Repo: "rhel-10-for-x86_64-extensions-rpms"},
+					{Name: "nvidia-driver", Version: "580.95.05", Arch: "i686", Repo: "cuda-rhel10-x86_64"},
+				}, nil)
+				pc.EXPECT().CheckRepositories([]string{"cuda-rhel10-x86_64", "rhel-10-for-x86_64-extensions-rpms"}).Return(nil, nil)
+				pc.EXPECT().Policy().Return("DEFAULT")
+			},
+		},
+		{
+			name: "Findings",
+			setup: func(pm *mocks.MockPackageManager, pc *mocks.MockPolicyChecker) {
+				pm.EXPECT().ListAvailablePackages(gomock.Any()).Return([]api.PackageInfo{
+					{Name: "nvidia-driver", Version: "580.95.05", Repo: "unsigned"},
+				}, nil)
+				pc.EXPECT().CheckRepositories([]string{"unsigned"}).Return([]api.PolicyFinding{
+					{Repo: "unsigned", Problem: "package signatures are not checked (gpgcheck=0)"},
+				}, nil)
+				pc.EXPECT().Policy().Return("FIPS")
+			},
+		},
+		{
+			name: "QueryFails",
+			setup: func(pm *mocks.MockPackageManager, pc *mocks.MockPolicyChecker) {
+				pm.EXPECT().ListAvailablePackages(gomock.Any()).Return(nil, fmt.Errorf("dnf failed"))
+			},
+		},
+		{
+			name: "CheckFails",
+			setup: func(pm *mocks.MockPackageManager, pc *mocks.MockPolicyChecker) {
+				pm.EXPECT().ListAvailablePackages(gomock.Any()).Return(nil, nil)
+				pc.EXPECT().CheckRepositories(nil).Return(nil, fmt.Errorf("invalid repository configuration"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			pm := mocks.NewMockPackageManager(ctrl)
+			pc := mocks.NewMockPolicyChecker(ctrl)
+			tt.setup(pm, pc)
+
+			checkCryptoPolicy(api.CoreDeps{PackageManager: pm, PolicyChecker: pc}, pkgs)
+		})
+	}
+}
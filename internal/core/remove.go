@@ -24,12 +24,12 @@ outer:
 			return fmt.Errorf("failed to list installed %s drivers: %w", provider.GetName(), err)
 		}
 		for _, inst := range installed {
-			if inst.Version == driver.Version {
+			if driver.Matches(inst) {
 				toRemove = append(toRemove, inst)
 				continue outer
 			}
 		}
-		return fmt.Errorf("driver %s version %s is NOT installed", provider.GetName(), driver.Version)
+		return fmt.Errorf("driver %s version %s is NOT installed", provider.GetName(), driver.FullVersion())
 	}
 	return doRemove(deps, toRemove, batchMode, dryRun)
 }
@@ -51,6 +51,9 @@ func RemoveAll(deps api.CoreDeps, batchMode, dryRun bool) error {
 }
 
 func doRemove(deps api.CoreDeps, toRemove []api.DriverID, batchMode, dryRun bool) error {
+	if err := checkEnvironment(deps, false); err != nil {
+		return err
+	}
 	var allPkgs []string
 	for _, provider := range deps.Providers {
 		provID := provider.GetID()
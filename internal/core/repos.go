@@ -0,0 +1,62 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+)
+
+// RestoreRepositories disables repositories that rhel-drivers enabled,
+// returning repository configuration to its original state.
+func RestoreRepositories(deps api.CoreDeps) error {
+	if err := deps.RepositoryManager.RestoreRepositories(); err != nil {
+		return fmt.Errorf("failed to restore repositories: %w", err)
+	}
+	return nil
+}
+
+// ListRepositories returns status of repositories that drivers of all
+// providers need.
+func ListRepositories(deps api.CoreDeps) ([]api.RepositoryStatus, error) {
+	statuses, err := deps.RepositoryManager.ListRepositories(requiredChannels(deps.SystemInfo, deps.Providers))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories: %w", err)
+	}
+	return statuses, nil
+}
+
+// EnableRepositories enables repositories that drivers of all providers
+// need, without installing anything.
+func EnableRepositories(deps api.CoreDeps) error {
+	if err := deps.RepositoryManager.EnsureRepositoriesEnabled(requiredChannels(deps.SystemInfo, deps.Providers)); err != nil {
+		return fmt.Errorf("failed to verify/enable repositories: %w", err)
+	}
+	return nil
+}
+
+// CheckRepositories returns error if any of needed repositories is not
+// defined or not enabled.  Channels whose repositories are not managed
+// on this system can't be checked, which is only reported.
+func CheckRepositories(statuses []api.RepositoryStatus) error {
+	missing := 0
+	for _, status := range statuses {
+		if status.Unmanaged {
+			log.Warnf("repository providing %s is not known, its status was not checked", status.Channel)
+			continue
+		}
+		if !status.Missing() {
+			continue
+		}
+		missing++
+		if !status.Defined {
+			log.Warnf("repository %s (%s) is not defined", status.ID, status.Channel)
+		} else {
+			log.Warnf("repository %s (%s) is not enabled", status.ID, status.Channel)
+		}
+	}
+	if missing > 0 {
+		return fmt.Errorf("%d required repositories are missing or disabled (use \"repos --enable\")", missing)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/mocks"
+)
+
+func TestRestoreRepositories(t *testing.T) {
+	tests := []struct {
+		name       string
+		restoreErr error
+		expectErr  bool
+	}{
+		{name: "Success"},
+		{name: "Failure", restoreErr: fmt.Errorf("subscription-manager failed"), expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockRM := mocks.NewMockRepositoryManager(ctrl)
+			mockRM.EXPECT().RestoreRepositories().Return(tt.restoreErr)
+
+			err := RestoreRepositories(api.CoreDeps{RepositoryManager: mockRM})
+			if (err != nil) != tt.expectErr {
+				t.Errorf("RestoreRepositories() error = %v, expectErr %v", err, tt.expectErr)
+			}
+		})
+	}
+}
+
+func TestCheckRepositories(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []api.RepositoryStatus
+		expectErr bool
+	}{
+		{
+			name: "AllEnabled",
+			statuses: []api.RepositoryStatus{
+				{Channel: api.ChannelBaseOS, ID: "rhel-10-for-x86_64-baseos-rpms", Defined: true, Enabled: true},
+				{Channel: api.ChannelSupplementary},
+			},
+		},
+		{
+			name: "Disabled",
+			statuses: []api.RepositoryStatus{
+				{Channel: api.ChannelBaseOS, ID: "rhel-10-for-x86_64-baseos-rpms", Defined: true, Enabled: true},
+				{Channel: api.ChannelExtensions, ID: "rhel-10-for-x86_64-extensions-rpms", Defined: true},
+			},
+			expectErr: true,
+		},
+		{
+			name: "Unmanaged",
+			statuses: []api.RepositoryStatus{
+				{Channel: api.ChannelBaseOS, Unmanaged: true},
+				{Channel: api.ChannelExtensions, Unmanaged: true},
+			},
+		},
+		{
+			name: "Undefined",
+			statuses: []api.RepositoryStatus{
+				{Channel: api.ChannelExtensions, ID: "rhel-10-for-x86_64-extensions-rpms"},
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckRepositories(tt.statuses)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("CheckRepositories() error = %v, expectErr %v", err, tt.expectErr)
+			}
+		})
+	}
+}
+
+func TestListRepositories(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockProvider := mocks.NewMockProvider(ctrl)
+	mockProvider.EXPECT().GetRequiredChannels(gomock.Any(), gomock.Any()).Return(testChannels)
+	mockRM := mocks.NewMockRepositoryManager(ctrl)
+	expected := []api.RepositoryStatus{{Channel: api.ChannelBaseOS, ID: "baseos", Defined: true, Enabled: true}}
+	mockRM.EXPECT().ListRepositories(testChannels).Return(expected, nil)
+
+	statuses, err := ListRepositories(api.CoreDeps{RepositoryManager: mockRM, Providers: []api.Provider{mockProvider}})
+	if err != nil {
+		t.Fatalf("ListRepositories() error = %v", err)
+	}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("ListRepositories() = %v, expected %v", statuses, expected)
+	}
+}
@@ -5,16 +5,43 @@ import (
 	"strings"
 
 	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/rpmver"
 )
 
+// driverSpec is a driver requested by the user, either a particular
+// version or any version satisfying a constraint, eg. "nvidia:>= 570".
+type driverSpec struct {
+	api.DriverID
+	constraint rpmver.Constraint
+}
+
+func (s driverSpec) matches(o api.DriverID) bool {
+	if s.constraint != nil {
+		return s.ProviderID == o.ProviderID && s.constraint.Match(o.Version)
+	}
+	return s.DriverID.Matches(o)
+}
+
+func (s driverSpec) String() string {
+	if s.constraint != nil {
+		return s.ProviderID + ":" + s.constraint.String()
+	}
+	return s.DriverID.String()
+}
+
 func parseDriverID(input string) (api.DriverID, error) {
 	parts := strings.Split(input, ":")
 	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
-		return api.DriverID{}, fmt.Errorf("invalid driver ID format: %q (expected 'vendor:version')", input)
+		return api.DriverID{}, fmt.Errorf("invalid driver ID format: %q (expected 'vendor:version' or 'vendor:version-release')", input)
+	}
+	version, release, hasRelease := strings.Cut(parts[1], "-")
+	if version == "" || (hasRelease && release == "") {
+		return api.DriverID{}, fmt.Errorf("invalid driver ID format: %q (expected 'vendor:version' or 'vendor:version-release')", input)
 	}
 	return api.DriverID{
 		ProviderID: parts[0],
-		Version:    parts[1],
+		Version:    version,
+		Release:    release,
 	}, nil
 }
 
@@ -25,7 +52,7 @@ func lookupProvider(deps api.CoreDeps, driver api.DriverID) (api.Provider, error
 			return provider, nil
 		}
 	}
-	return nil, fmt.Errorf("unknown provider for driver: %s:%s", driver.ProviderID, driver.Version)
+	return nil, fmt.Errorf("unknown provider for driver: %s", driver)
 }
 
 func resolveDriver(deps api.CoreDeps, driverStr string) (api.DriverID, api.Provider, error) {
@@ -39,3 +66,31 @@ func resolveDriver(deps api.CoreDeps, driverStr string) (api.DriverID, api.Provi
 	}
 	return driver, provider, nil
 }
+
+func parseDriverSpec(input string) (driverSpec, error) {
+	providerID, version, ok := strings.Cut(input, ":")
+	if ok && providerID != "" && rpmver.IsConstraint(version) {
+		constraint, err := rpmver.ParseConstraint(version)
+		if err != nil {
+			return driverSpec{}, err
+		}
+		return driverSpec{
+			DriverID:   api.DriverID{ProviderID: providerID},
+			constraint: constraint,
+		}, nil
+	}
+	driver, err := parseDriverID(input)
+	return driverSpec{DriverID: driver}, err
+}
+
+func resolveDriverSpec(deps api.CoreDeps, driverStr string) (driverSpec, api.Provider, error) {
+	spec, err := parseDriverSpec(driverStr)
+	if err != nil {
+		return spec, nil, err
+	}
+	provider, err := lookupProvider(deps, spec.DriverID)
+	if err != nil {
+		return spec, nil, err
+	}
+	return spec, provider, nil
+}
@@ -24,6 +24,27 @@ func TestParseDriverID(t *testing.T) {
 				Version:    "450.80.02",
 			},
 		},
+		{
+			name:  "ValidInputWithRelease",
+			input: "nvidia:580.95.05-1.el10",
+			expected: api.DriverID{
+				ProviderID: "nvidia",
+				Version:    "580.95.05",
+				Release:    "1.el10",
+			},
+		},
+		{
+			name:        "EmptyRelease",
+			input:       "nvidia:580.95.05-",
+			expected:    api.DriverID{},
+			expectError: true,
+		},
+		{
+			name:        "EmptyVersionWithRelease",
+			input:       "nvidia:-1.el10",
+			expected:    api.DriverID{},
+			expectError: true,
+		},
 		{
 			name:        "MissingVersion",
 			input:       "nvidia:",
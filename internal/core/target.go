@@ -0,0 +1,46 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+)
+
+// targetProvider answers hardware detection from an explicit list
+// instead of probing the host, which may not be where drivers run.
+type targetProvider struct {
+	api.Provider
+	present bool
+}
+
+func (p *targetProvider) DetectHardware() (bool, error) {
+	return p.present, nil
+}
+
+// WithTargetHardware replaces hardware detection of all providers with
+// given list of provider IDs, whose hardware is assumed to be present
+// on the target system.  Hardware of other providers is assumed absent.
+func WithTargetHardware(deps api.CoreDeps, providerIDs []string) (api.CoreDeps, error) {
+	var known []string
+	for _, provider := range deps.Providers {
+		known = append(known, provider.GetID())
+	}
+	for _, id := range providerIDs {
+		if !slices.Contains(known, id) {
+			return deps, fmt.Errorf("unknown target hardware %q (known: %s)", id, strings.Join(known, ", "))
+		}
+	}
+	var providers []api.Provider
+	for _, provider := range deps.Providers {
+		present := slices.Contains(providerIDs, provider.GetID())
+		if present {
+			log.Logf("assuming %s hardware is present on target system", provider.GetName())
+		}
+		providers = append(providers, &targetProvider{Provider: provider, present: present})
+	}
+	deps.Providers = providers
+	return deps, nil
+}
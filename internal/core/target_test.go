@@ -0,0 +1,64 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/provider/amd"
+	"github.com/mizdebsk/rhel-drivers/internal/provider/nvidia"
+	"github.com/mizdebsk/rhel-drivers/internal/sysinfo"
+)
+
+func TestWithTargetHardware(t *testing.T) {
+	tests := []struct {
+		name      string
+		targets   []string
+		expected  map[string]bool
+		expectErr bool
+	}{
+		{
+			name:     "SingleTarget",
+			targets:  []string{"nvidia"},
+			expected: map[string]bool{"nvidia": true, "amdgpu": false},
+		},
+		{
+			name:     "AllTargets",
+			targets:  []string{"amdgpu", "nvidia"},
+			expected: map[string]bool{"nvidia": true, "amdgpu": true},
+		},
+		{
+			name:      "VendorInsteadOfProvider",
+			targets:   []string{"amd"},
+			expectErr: true,
+		},
+		{
+			name:      "UnknownTarget",
+			targets:   []string{"matrox"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Real providers, so that their actual IDs are used.
+			providers := []api.Provider{
+				nvidia.NewProvider(nil, nil, sysinfo.SysInfo{}),
+				amd.NewProvider(nil, nil, sysinfo.SysInfo{}),
+			}
+
+			deps, err := WithTargetHardware(api.CoreDeps{Providers: providers}, tt.targets)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Expected error: %v, but got: %v", tt.expectErr, err)
+			}
+			if err != nil {
+				return
+			}
+			for _, p := range deps.Providers {
+				detected, err := p.DetectHardware()
+				if err != nil || detected != tt.expected[p.GetID()] {
+					t.Errorf("%s: DetectHardware() = %v, %v; want %v", p.GetID(), detected, err, tt.expected[p.GetID()])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package cryptopolicy
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+	"github.com/mizdebsk/rhel-drivers/internal/sysinfo"
+	"github.com/mizdebsk/rhel-drivers/internal/yumrepo"
+)
+
+// Minimum RSA key sizes of base policies, as set by crypto-policies.
+// Unknown policies are treated like DEFAULT.
+var minRSABits = map[string]int{
+	"LEGACY":  1024,
+	"DEFAULT": 2048,
+	"FIPS":    2048,
+	"FUTURE":  3072,
+}
+
+// Curves approved for ECDSA in FIPS mode.
+var fipsCurves = map[string]bool{
+	"P-256": true,
+	"P-384": true,
+	"P-521": true,
+}
+
+type checker struct {
+	systemInfo sysinfo.SysInfo
+	policy     string
+	reposDir   string
+	varsDir    string
+}
+
+var _ api.PolicyChecker = (*checker)(nil)
+
+// NewChecker returns checker of repositories against system-wide crypto
+// policy, which is FIPS on systems in FIPS mode regardless of what
+// crypto-policies say, and DEFAULT on systems without crypto-policies.
+func NewChecker(systemInfo sysinfo.SysInfo) api.PolicyChecker {
+	policy := systemInfo.CryptoPolicy
+	switch {
+	case systemInfo.FIPS && basePolicy(policy) != "FIPS":
+		policy = "FIPS"
+	case policy == "":
+		policy = "DEFAULT"
+	}
+	return &checker{
+		systemInfo: systemInfo,
+		policy:     policy,
+		reposDir:   filepath.Join(systemInfo.Root, yumrepo.DefaultReposDir),
+		varsDir:    filepath.Join(systemInfo.Root, yumrepo.DefaultVarsDir),
+	}
+}
+
+// basePolicy returns policy without subpolicies, eg. "FIPS" for
+// "FIPS:OSPP".
+func basePolicy(policy string) string {
+	base, _, _ := strings.Cut(policy, ":")
+	return base
+}
+
+func (c *checker) Policy() string {
+	return c.policy
+}
+
+func (c *checker) fips() bool {
+	return basePolicy(c.policy) == "FIPS"
+}
+
+func (c *checker) CheckRepositories(ids []string) ([]api.PolicyFinding, error) {
+	vars := yumrepo.LoadVars(c.varsDir, map[string]string{
+		"releasever": strconv.Itoa(c.systemInfo.OsVersion),
+		"basearch":   c.systemInfo.Arch,
+	})
+	repos, err := yumrepo.LoadDir(c.reposDir, vars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository configuration: %w", err)
+	}
+	var findings []api.PolicyFinding
+	for _, id := range ids {
+		repo := repos.Repo(id)
+		if repo == nil {
+			log.Debugf("repository %s is not defined in %s, not checking it", id, c.reposDir)
+			continue
+		}
+		for _, problem := range c.checkRepo(repo) {
+			findings = append(findings, api.PolicyFinding{Repo: id, Problem: problem})
+		}
+	}
+	return findings, nil
+}
+
+func (c *checker) checkRepo(repo *yumrepo.Repo) []string {
+	var problems []string
+	if disabled(repo, "gpgcheck") {
+		problems = append(problems, "package signatures are not checked (gpgcheck=0)")
+	}
+	if disabled(repo, "sslverify") {
+		problems = append(problems, "server certificates are not verified (sslverify=0)")
+	}
+	gpgkeys, _ := repo.Get("gpgkey")
+	for _, url := range strings.FieldsFunc(gpgkeys, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	}) {
+		path, ok := strings.CutPrefix(url, "file://")
+		if !ok {
+			problems = append(problems, fmt.Sprintf("GPG key %s not checked, as it is not a local file", url))
+			continue
+		}
+		keys, err := readKeyFile(filepath.Join(c.systemInfo.Root, path))
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("unable to read GPG key: %v", err))
+			continue
+		}
+		for _, key := range keys {
+			if reason := c.checkKey(key); reason != "" {
+				problems = append(problems, fmt.Sprintf("GPG key %s of %q in %s %s", key, key.UserID, path, reason))
+			}
+		}
+	}
+	return problems
+}
+
+// disabled reports whether given boolean option is explicitly disabled
+// in repository definition.
+func disabled(repo *yumrepo.Repo, key string) bool {
+	val, ok := repo.Get(key)
+	if !ok {
+		return false
+	}
+	enabled, err := yumrepo.ParseBool(val)
+	if err != nil {
+		log.Warnf("%s: repository %s: %v", repo.File, repo.ID, err)
+		return false
+	}
+	return !enabled
+}
+
+// checkKey returns why signatures made by given key would be rejected
+// under the policy, or empty string if they are acceptable.
+func (c *checker) checkKey(key Key) string {
+	base := basePolicy(c.policy)
+	minBits, ok := minRSABits[base]
+	if !ok {
+		minBits = minRSABits["DEFAULT"]
+	}
+	switch key.Algorithm {
+	case "RSA":
+		if key.Bits < minBits {
+			return fmt.Sprintf("is shorter than %d bits required by %s policy", minBits, c.policy)
+		}
+	case "DSA":
+		if base != "LEGACY" {
+			return fmt.Sprintf("uses DSA, which is not allowed by %s policy", c.policy)
+		}
+		if key.Bits < minBits {
+			return fmt.Sprintf("is shorter than %d bits required by %s policy", minBits, c.policy)
+		}
+	case "ECDSA":
+		if c.fips() && !fipsCurves[key.Curve] {
+			return fmt.Sprintf("uses curve %s, which is not approved in FIPS mode", key.Curve)
+		}
+	case "EdDSA":
+		if c.fips() {
+			return "uses EdDSA, which is not allowed in FIPS mode"
+		}
+	}
+	return ""
+}
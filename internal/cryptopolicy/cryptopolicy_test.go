@@ -0,0 +1,121 @@
+package cryptopolicy
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/sysinfo"
+)
+
+func TestNewChecker(t *testing.T) {
+	tests := []struct {
+		name    string
+		sysInfo sysinfo.SysInfo
+		want    string
+	}{
+		{name: "Default", sysInfo: sysinfo.SysInfo{CryptoPolicy: "DEFAULT:SHA1"}, want: "DEFAULT:SHA1"},
+		{name: "NoCryptoPolicies", want: "DEFAULT"},
+		{name: "FIPSMode", sysInfo: sysinfo.SysInfo{FIPS: true, CryptoPolicy: "DEFAULT"}, want: "FIPS"},
+		{name: "FIPSSubpolicy", sysInfo: sysinfo.SysInfo{FIPS: true, CryptoPolicy: "FIPS:OSPP"}, want: "FIPS:OSPP"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewChecker(tt.sysInfo).Policy(); got != tt.want {
+				t.Errorf("Policy() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckRepositories(t *testing.T) {
+	allRepos := []string{
+		"example-rsa4096", "example-rsa1024", "example-dsa", "example-ed25519",
+		"example-nistp256", "example-remote-key", "example-missing-key",
+		"example-unsigned", "not-defined",
+	}
+	unsigned := []api.PolicyFinding{
+		{Repo: "example-unsigned", Problem: "package signatures are not checked (gpgcheck=0)"},
+		{Repo: "example-unsigned", Problem: "server certificates are not verified (sslverify=0)"},
+	}
+	remote := api.PolicyFinding{
+		Repo:    "example-remote-key",
+		Problem: "GPG key https://example.com/RPM-GPG-KEY-example not checked, as it is not a local file",
+	}
+	missing := api.PolicyFinding{
+		Repo:    "example-missing-key",
+		Problem: "unable to read GPG key: open testdata/etc/pki/rpm-gpg/RPM-GPG-KEY-example-missing: no such file or directory",
+	}
+	rsa1024 := api.PolicyFinding{
+		Repo:    "example-rsa1024",
+		Problem: `GPG key RSA 1024 of "Example RSA 1024 Signing Key <security@example.com>" in /etc/pki/rpm-gpg/RPM-GPG-KEY-example-rsa1024 is shorter than 2048 bits required by %s policy`,
+	}
+	tests := []struct {
+		name   string
+		policy string
+		fips   bool
+		repos  []string
+		want   []api.PolicyFinding
+	}{
+		{
+			name:   "Acceptable",
+			policy: "DEFAULT",
+			repos:  []string{"example-rsa4096", "example-nistp256", "example-ed25519"},
+		},
+		{
+			name:   "RemoteKey",
+			policy: "DEFAULT",
+			repos:  []string{"example-remote-key"},
+			want:   []api.PolicyFinding{remote},
+		},
+		{
+			name:   "Default",
+			policy: "DEFAULT",
+			repos:  allRepos,
+			want: append([]api.PolicyFinding{
+				{Repo: rsa1024.Repo, Problem: fmt.Sprintf(rsa1024.Problem, "DEFAULT")},
+				{Repo: "example-dsa", Problem: `GPG key DSA 1024 of "Example DSA 1024 Signing Key <security@example.com>" in /etc/pki/rpm-gpg/RPM-GPG-KEY-example-dsa1024 uses DSA, which is not allowed by DEFAULT policy`},
+				remote,
+				missing,
+			}, unsigned...),
+		},
+		{
+			name:   "Legacy",
+			policy: "LEGACY",
+			repos:  allRepos,
+			want:   append([]api.PolicyFinding{remote, missing}, unsigned...),
+		},
+		{
+			name:   "Future",
+			policy: "FUTURE",
+			repos:  []string{"example-rsa4096", "example-rsa1024"},
+			want: []api.PolicyFinding{
+				{Repo: rsa1024.Repo, Problem: `GPG key RSA 1024 of "Example RSA 1024 Signing Key <security@example.com>" in /etc/pki/rpm-gpg/RPM-GPG-KEY-example-rsa1024 is shorter than 3072 bits required by FUTURE policy`},
+			},
+		},
+		{
+			name:  "FIPSMode",
+			fips:  true,
+			repos: []string{"example-rsa4096", "example-rsa1024", "example-ed25519", "example-nistp256"},
+			want: []api.PolicyFinding{
+				{Repo: rsa1024.Repo, Problem: fmt.Sprintf(rsa1024.Problem, "FIPS")},
+				{Repo: "example-ed25519", Problem: `GPG key EdDSA Ed25519 of "Example Ed25519 Signing Key <security@example.com>" in /etc/pki/rpm-gpg/RPM-GPG-KEY-example-ed25519 uses EdDSA, which is not allowed in FIPS mode`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker(sysinfo.SysInfo{OsVersion: 10, Arch: "x86_64", CryptoPolicy: tt.policy, FIPS: tt.fips, Root: "testdata"})
+			got, err := c.CheckRepositories(tt.repos)
+			if err != nil {
+				t.Fatalf("CheckRepositories() failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckRepositories() = %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package cryptopolicy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Public key algorithms, as numbered in RFC 9580.
+const (
+	algoRSA         = 1
+	algoRSAEncrypt  = 2
+	algoRSASign     = 3
+	algoElgamal     = 16
+	algoDSA         = 17
+	algoECDH        = 18
+	algoECDSA       = 19
+	algoEdDSALegacy = 22
+	algoX25519      = 25
+	algoX448        = 26
+	algoEd25519     = 27
+	algoEd448       = 28
+)
+
+// OpenPGP packet tags.
+const (
+	tagPublicKey    = 6
+	tagUserID       = 13
+	tagPublicSubkey = 14
+)
+
+// Curves of ECDSA and EdDSA keys by their DER-encoded OID.
+var curveOIDs = map[string]string{
+	"2a8648ce3d030107":   "P-256",
+	"2b81040022":         "P-384",
+	"2b81040023":         "P-521",
+	"2b2403030208010107": "brainpoolP256r1",
+	"2b240303020801010b": "brainpoolP384r1",
+	"2b240303020801010d": "brainpoolP512r1",
+	"2b06010401da470f01": "Ed25519",
+	"2b0601040197550105": "Curve25519",
+	"2b8104000a":         "secp256k1",
+}
+
+// Key describes signing key or subkey found in an OpenPGP certificate.
+type Key struct {
+	Algorithm string
+	// Size of RSA modulus or DSA prime in bits, zero for elliptic
+	// curve keys.
+	Bits   int
+	Curve  string
+	Subkey bool
+	// First user ID of the certificate.
+	UserID string
+}
+
+func (k Key) String() string {
+	if k.Curve != "" {
+		return fmt.Sprintf("%s %s", k.Algorithm, k.Curve)
+	}
+	return fmt.Sprintf("%s %d", k.Algorithm, k.Bits)
+}
+
+// ReadKeys returns signing keys of all certificates in armored OpenPGP
+// key file, such as those referenced by gpgkey option of repositories.
+// Encryption-only subkeys are skipped, as they are not used by rpm.
+func ReadKeys(r io.Reader) ([]Key, error) {
+	blocks, err := dearmor(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("no armored public key block found")
+	}
+	var keys []Key
+	for _, block := range blocks {
+		blockKeys, err := parseCertificate(block)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, blockKeys...)
+	}
+	return keys, nil
+}
+
+// dearmor returns decoded content of armored public key blocks.
+func dearmor(r io.Reader) ([][]byte, error) {
+	var blocks [][]byte
+	var body strings.Builder
+	inBlock, inHeaders := false, false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "-----BEGIN PGP PUBLIC KEY BLOCK-----":
+			inBlock, inHeaders = true, true
+			body.Reset()
+		case !inBlock:
+		case line == "-----END PGP PUBLIC KEY BLOCK-----":
+			data, err := base64.StdEncoding.DecodeString(body.String())
+			if err != nil {
+				return nil, fmt.Errorf("invalid armored key: %w", err)
+			}
+			blocks = append(blocks, data)
+			inBlock = false
+		case inHeaders:
+			// Armor headers, if any, end with an empty line.
+			if line == "" || !strings.Contains(line, ": ") {
+				inHeaders = false
+				body.WriteString(line)
+			}
+		case strings.HasPrefix(line, "="):
+			// CRC24 checksum
+		default:
+			body.WriteString(line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if inBlock {
+		return nil, fmt.Errorf("unterminated armored key block")
+	}
+	return blocks, nil
+}
+
+func parseCertificate(data []byte) ([]Key, error) {
+	var keys []Key
+	userID := ""
+	for len(data) > 0 {
+		tag, body, rest, err := readPacket(data)
+		if err != nil {
+			return nil, err
+		}
+		data = rest
+		switch tag {
+		case tagPublicKey, tagPublicSubkey:
+			key, signing, err := parseKey(body)
+			if err != nil {
+				return nil, err
+			}
+			if signing {
+				key.Subkey = tag == tagPublicSubkey
+				keys = append(keys, key)
+			}
+		case tagUserID:
+			if userID == "" {
+				userID = string(body)
+			}
+		}
+	}
+	for i := range keys {
+		keys[i].UserID = userID
+	}
+	return keys, nil
+}
+
+// readPacket splits the first packet off given data and returns its tag
+// and body.  Both old and new packet formats are supported, except for
+// partial body lengths, which are not allowed in keys.
+func readPacket(data []byte) (tag int, body, rest []byte, err error) {
+	if data[0]&0x80 == 0 {
+		return 0, nil, nil, fmt.Errorf("invalid OpenPGP packet header 0x%02x", data[0])
+	}
+	var length, off int
+	if data[0]&0x40 == 0 {
+		tag = int(data[0]>>2) & 0x0f
+		switch data[0] & 0x03 {
+		case 0:
+			length, off = lenAt(data, 1, 1), 2
+		case 1:
+			length, off = lenAt(data, 1, 2), 3
+		case 2:
+			length, off = lenAt(data, 1, 4), 5
+		default:
+			return 0, nil, nil, fmt.Errorf("indeterminate OpenPGP packet length")
+		}
+	} else {
+		tag = int(data[0] & 0x3f)
+		if len(data) < 2 {
+			return 0, nil, nil, io.ErrUnexpectedEOF
+		}
+		switch {
+		case data[1] < 192:
+			length, off = int(data[1]), 2
+		case data[1] < 224:
+			if len(data) < 3 {
+				return 0, nil, nil, io.ErrUnexpectedEOF
+			}
+			length, off = (int(data[1])-192)<<8+int(data[2])+192, 3
+		case data[1] == 255:
+			length, off = lenAt(data, 2, 4), 6
+		default:
+			return 0, nil, nil, fmt.Errorf("partial OpenPGP packet length")
+		}
+	}
+	if length < 0 || off+length > len(data) {
+		return 0, nil, nil, io.ErrUnexpectedEOF
+	}
+	return tag, data[off : off+length], data[off+length:], nil
+}
+
+// lenAt returns big-endian length of given size at given offset, or -1
+// if data is too short.
+func lenAt(data []byte, off, size int) int {
+	if off+size > len(data) {
+		return -1
+	}
+	n := 0
+	for _, b := range data[off : off+size] {
+		n = n<<8 | int(b)
+	}
+	return n
+}
+
+// parseKey describes public key packet and reports whether the key can
+// be used for signing.
+func parseKey(body []byte) (Key, bool, error) {
+	if len(body) < 1 {
+		return Key{}, false, io.ErrUnexpectedEOF
+	}
+	var off int
+	switch body[0] {
+	case 2, 3:
+		// version, creation time, validity days, algorithm
+		off = 8
+	case 4:
+		// version, creation time, algorithm
+		off = 6
+	case 5, 6:
+		// version, creation time, algorithm, key material length
+		off = 10
+	default:
+		return Key{}, false, fmt.Errorf("unsupported OpenPGP key version %d", body[0])
+	}
+	if len(body) < off {
+		return Key{}, false, io.ErrUnexpectedEOF
+	}
+	algo := body[off-1]
+	if body[0] >= 5 {
+		algo = body[5]
+	}
+	material := body[off:]
+	switch algo {
+	case algoRSA, algoRSASign:
+		bits, err := mpiBits(material)
+		return Key{Algorithm: "RSA", Bits: bits}, true, err
+	case algoDSA:
+		bits, err := mpiBits(material)
+		return Key{Algorithm: "DSA", Bits: bits}, true, err
+	case algoECDSA:
+		curve, err := readCurve(material)
+		return Key{Algorithm: "ECDSA", Curve: curve}, true, err
+	case algoEdDSALegacy:
+		curve, err := readCurve(material)
+		return Key{Algorithm: "EdDSA", Curve: curve}, true, err
+	case algoEd25519:
+		return Key{Algorithm: "EdDSA", Curve: "Ed25519"}, true, nil
+	case algoEd448:
+		return Key{Algorithm: "EdDSA", Curve: "Ed448"}, true, nil
+	case algoRSAEncrypt, algoElgamal, algoECDH, algoX25519, algoX448:
+		return Key{}, false, nil
+	default:
+		return Key{}, false, fmt.Errorf("unsupported OpenPGP public key algorithm %d", algo)
+	}
+}
+
+// mpiBits returns bit count of the first multiprecision integer, which
+// is the modulus of RSA keys and the prime of DSA keys.
+func mpiBits(data []byte) (int, error) {
+	if len(data) < 2 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return int(binary.BigEndian.Uint16(data)), nil
+}
+
+func readCurve(data []byte) (string, error) {
+	if len(data) < 1 || len(data) < 1+int(data[0]) {
+		return "", io.ErrUnexpectedEOF
+	}
+	oid := hex.EncodeToString(data[1 : 1+int(data[0])])
+	if curve, ok := curveOIDs[oid]; ok {
+		return curve, nil
+	}
+	return "unknown curve " + oid, nil
+}
+
+// readKeyFile returns signing keys in given key file.
+func readKeyFile(path string) ([]Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := ReadKeys(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return keys, nil
+}
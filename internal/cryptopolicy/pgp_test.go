@@ -0,0 +1,73 @@
+package cryptopolicy
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadKeys(t *testing.T) {
+	tests := []struct {
+		file string
+		want []Key
+	}{
+		{
+			file: "RPM-GPG-KEY-example-rsa4096",
+			want: []Key{{Algorithm: "RSA", Bits: 4096, UserID: "Example RSA 4096 Signing Key <security@example.com>"}},
+		},
+		{
+			file: "RPM-GPG-KEY-example-rsa1024",
+			want: []Key{{Algorithm: "RSA", Bits: 1024, UserID: "Example RSA 1024 Signing Key <security@example.com>"}},
+		},
+		{
+			file: "RPM-GPG-KEY-example-dsa1024",
+			want: []Key{{Algorithm: "DSA", Bits: 1024, UserID: "Example DSA 1024 Signing Key <security@example.com>"}},
+		},
+		{
+			file: "RPM-GPG-KEY-example-ed25519",
+			want: []Key{{Algorithm: "EdDSA", Curve: "Ed25519", UserID: "Example Ed25519 Signing Key <security@example.com>"}},
+		},
+		{
+			file: "RPM-GPG-KEY-example-nistp256",
+			want: []Key{{Algorithm: "ECDSA", Curve: "P-256", UserID: "Example NIST P-256 Signing Key <security@example.com>"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			f, err := os.Open("testdata/etc/pki/rpm-gpg/" + tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			got, err := ReadKeys(f)
+			if err != nil {
+				t.Fatalf("ReadKeys() failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadKeys() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadKeysErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "NotArmored", input: "not a key\n"},
+		{name: "Unterminated", input: "-----BEGIN PGP PUBLIC KEY BLOCK-----\n\nmQINBGj\n"},
+		{name: "InvalidBase64", input: "-----BEGIN PGP PUBLIC KEY BLOCK-----\n\n!!!!\n-----END PGP PUBLIC KEY BLOCK-----\n"},
+		{name: "Truncated", input: "-----BEGIN PGP PUBLIC KEY BLOCK-----\n\nmQIN\n-----END PGP PUBLIC KEY BLOCK-----\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if keys, err := ReadKeys(strings.NewReader(tt.input)); err == nil {
+				t.Errorf("ReadKeys() = %+v, expected error", keys)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package distrorepo
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/config"
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+	"github.com/mizdebsk/rhel-drivers/internal/repostate"
+	"github.com/mizdebsk/rhel-drivers/internal/rhsm"
+	"github.com/mizdebsk/rhel-drivers/internal/sysinfo"
+	"github.com/mizdebsk/rhel-drivers/internal/yumrepo"
+)
+
+const defaultDNFBinary = "dnf"
+
+// strategy describes which repositories need to be enabled on
+// a distribution other than RHEL.
+type strategy struct {
+	distro string
+	// Repositories needed regardless of channels.
+	base []string
+	// Repositories corresponding to RHEL channels, unless enabled by
+	// default or not available on the distribution.
+	channels map[string]string
+	// Channels provided by repositories enabled by default.  Channels
+	// that are in neither list are not available.
+	defaults []string
+	// dnf5 has different config-manager syntax.
+	dnf5 bool
+}
+
+// repos returns IDs of repositories to enable for given channels.
+func (s strategy) repos(channels []string) []string {
+	repos := slices.Clone(s.base)
+	for _, channel := range channels {
+		repo, ok := s.channels[channel]
+		if !ok {
+			log.Debugf("channel %s has no repository to enable on %s", channel, s.distro)
+			continue
+		}
+		if !slices.Contains(repos, repo) {
+			repos = append(repos, repo)
+		}
+	}
+	return repos
+}
+
+func selectStrategy(si sysinfo.SysInfo) *strategy {
+	switch {
+	case si.OsID == "fedora" && si.VariantID == "eln":
+		return &strategy{
+			distro: "Fedora ELN",
+			base:   []string{"eln-crb"},
+			channels: map[string]string{
+				api.ChannelBaseOS:     "eln-baseos",
+				api.ChannelAppStream:  "eln-appstream",
+				api.ChannelCRB:        "eln-crb",
+				api.ChannelExtensions: "eln-extras",
+			},
+			dnf5: true,
+		}
+	case si.OsID == "fedora":
+		// Default Fedora repositories provide everything needed.
+		return &strategy{
+			distro: "Fedora",
+			defaults: []string{api.ChannelBaseOS, api.ChannelAppStream, api.ChannelCRB,
+				api.ChannelExtensions, api.ChannelSupplementary},
+			dnf5: true,
+		}
+	case si.OsID == "centos":
+		return elStrategy("CentOS Stream", si, "extras-common")
+	case si.OsID == "almalinux":
+		return elStrategy("AlmaLinux", si, "extras")
+	case si.OsID == "rocky":
+		return elStrategy("Rocky Linux", si, "extras")
+	case si.IsLike("rhel") || si.IsLike("centos"):
+		return elStrategy("Enterprise Linux derivative "+si.OsID, si, "")
+	}
+	return nil
+}
+
+// elStrategy returns strategy for Enterprise Linux derivative, which
+// has BaseOS and AppStream enabled by default and provides content of
+// RHEL Extensions in given repository, if any.
+func elStrategy(distro string, si sysinfo.SysInfo, extras string) *strategy {
+	s := &strategy{
+		distro:   distro,
+		base:     []string{crbRepo(si)},
+		channels: map[string]string{api.ChannelCRB: crbRepo(si)},
+		defaults: []string{api.ChannelBaseOS, api.ChannelAppStream},
+	}
+	if extras != "" {
+		s.channels[api.ChannelExtensions] = extras
+	}
+	return s
+}
+
+// crbRepo returns ID of CodeReady Linux Builder repository, which was
+// called PowerTools in EL 8 derivatives.
+func crbRepo(si sysinfo.SysInfo) string {
+	if si.OsVersion == 8 {
+		return "powertools"
+	}
+	return "crb"
+}
+
+type repoMgr struct {
+	systemInfo sysinfo.SysInfo
+	executor   api.Executor
+	strategy   strategy
+	dnfBin     string
+	reposDir   string
+	varsDir    string
+	journal    *repostate.Journal
+}
+
+var _ api.RepositoryManager = (*repoMgr)(nil)
+
+// NewRepositoryManager returns repository manager appropriate for the
+// distribution: Subscription Manager on RHEL, dnf config-manager on
+// CentOS Stream, RHEL rebuilds and Fedora.
+func NewRepositoryManager(executor api.Executor, cfg config.Config, systemInfo sysinfo.SysInfo) api.RepositoryManager {
+	s := selectStrategy(systemInfo)
+	if systemInfo.IsRhel || s == nil {
+		return rhsm.NewRepositoryManager(executor, cfg, systemInfo)
+	}
+	return &repoMgr{
+		systemInfo: systemInfo,
+		executor:   executor,
+		strategy:   *s,
+		dnfBin:     defaultDNFBinary,
+		reposDir:   filepath.Join(systemInfo.Root, yumrepo.DefaultReposDir),
+		varsDir:    filepath.Join(systemInfo.Root, yumrepo.DefaultVarsDir),
+		journal:    repostate.NewJournal(filepath.Join(systemInfo.Root, repostate.DefaultPath)),
+	}
+}
+
+func (rm *repoMgr) EnsureRepositoriesEnabled(channels []string) error {
+	log.Logf("detected %s %s", rm.strategy.distro, rm.systemInfo.Release())
+	required := rm.strategy.repos(channels)
+	if len(required) == 0 {
+		log.Logf("no additional repositories need to be enabled")
+		return nil
+	}
+
+	repos, err := rm.loadRepos()
+	if err != nil {
+		return err
+	}
+
+	var toEnable []string
+	for _, id := range required {
+		repo := repos.Repo(id)
+		switch {
+		case repo == nil:
+			log.Warnf("repository %s is not defined in %s", id, rm.reposDir)
+			log.Warnf("You may need to enable appropriate repositories yourself.")
+		case repo.Enabled():
+			log.Logf("repository %s is already enabled", id)
+		default:
+			log.Infof("enabling repository %s", id)
+			toEnable = append(toEnable, id)
+		}
+	}
+	if len(toEnable) == 0 {
+		log.Logf("all required repositories are already enabled")
+		return nil
+	}
+
+	log.Logf("running dnf config-manager to enable repositories")
+	if err := rm.setEnabled(toEnable, true); err != nil {
+		return fmt.Errorf("failed to enable repositories: %w", err)
+	}
+	log.Logf("repositories were enabled successfully")
+	if err := rm.journal.Record(toEnable); err != nil {
+		log.Warnf("%v", err)
+	}
+	return nil
+}
+
+// ListRepositories returns status of repositories enabled for given
+// channels.  Channels provided by repositories enabled by default are
+// listed without repository ID, and so are channels not available on
+// the distribution, marked as such.
+func (rm *repoMgr) ListRepositories(channels []string) ([]api.RepositoryStatus, error) {
+	repos, err := rm.loadRepos()
+	if err != nil {
+		return nil, err
+	}
+	var statuses []api.RepositoryStatus
+	add := func(channel, id string) {
+		for i := range statuses {
+			if id != "" && statuses[i].ID == id {
+				if statuses[i].Channel == "" {
+					statuses[i].Channel = channel
+				}
+				return
+			}
+		}
+		status := api.RepositoryStatus{Channel: channel, ID: id}
+		if repo := repos.Repo(id); repo != nil {
+			status.Defined = true
+			status.Enabled = repo.Enabled()
+		}
+		statuses = append(statuses, status)
+	}
+	for _, id := range rm.strategy.base {
+		add("", id)
+	}
+	for _, channel := range channels {
+		id, ok := rm.strategy.channels[channel]
+		if !ok && !slices.Contains(rm.strategy.defaults, channel) {
+			statuses = append(statuses, api.RepositoryStatus{Channel: channel, Unavailable: true})
+			continue
+		}
+		add(channel, id)
+	}
+	return statuses, nil
+}
+
+func (rm *repoMgr) loadRepos() (*yumrepo.Config, error) {
+	vars := yumrepo.LoadVars(rm.varsDir, map[string]string{
+		"releasever": strconv.Itoa(rm.systemInfo.OsVersion),
+		"basearch":   rm.systemInfo.Arch,
+	})
+	repos, err := yumrepo.LoadDir(rm.reposDir, vars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository configuration: %w", err)
+	}
+	return repos, nil
+}
+
+func (rm *repoMgr) RollbackRepositories() error {
+	return rm.journal.Rollback(rm.disableRepos)
+}
+
+func (rm *repoMgr) RestoreRepositories() error {
+	return rm.journal.Restore(rm.disableRepos)
+}
+
+func (rm *repoMgr) disableRepos(ids []string) error {
+	log.Logf("running dnf config-manager to disable repositories")
+	return rm.setEnabled(ids, false)
+}
+
+func (rm *repoMgr) EnableCommand(ids []string) []string {
+	return append([]string{rm.dnfBin}, rm.strategy.configManagerArgs(ids, true)...)
+}
+
+func (rm *repoMgr) setEnabled(ids []string, enabled bool) error {
+	var args []string
+	if rm.systemInfo.Root != "" {
+		args = append(args, "--installroot", rm.systemInfo.Root)
+	}
+	args = append(args, rm.strategy.configManagerArgs(ids, enabled)...)
+	return rm.executor.Run(rm.dnfBin, args)
+}
+
+// configManagerArgs returns dnf arguments that enable or disable given
+// repositories.
+func (s strategy) configManagerArgs(ids []string, enabled bool) []string {
+	if s.dnf5 {
+		value := "0"
+		if enabled {
+			value = "1"
+		}
+		args := []string{"config-manager", "setopt"}
+		for _, id := range ids {
+			args = append(args, id+".enabled="+value)
+		}
+		return args
+	}
+	if enabled {
+		return append([]string{"config-manager", "--set-enabled"}, ids...)
+	}
+	return append([]string{"config-manager", "--set-disabled"}, ids...)
+}
@@ -0,0 +1,243 @@
+package distrorepo
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/config"
+	"github.com/mizdebsk/rhel-drivers/internal/mocks"
+	"github.com/mizdebsk/rhel-drivers/internal/repostate"
+	"github.com/mizdebsk/rhel-drivers/internal/sysinfo"
+)
+
+var (
+	centosStream10 = sysinfo.SysInfo{OsID: "centos", OsIDLike: []string{"rhel", "fedora"}, OsVersion: 10, Arch: "x86_64"}
+	alma9          = sysinfo.SysInfo{OsID: "almalinux", OsIDLike: []string{"rhel", "centos", "fedora"}, OsVersion: 9, Arch: "x86_64"}
+	rocky8         = sysinfo.SysInfo{OsID: "rocky", OsIDLike: []string{"rhel", "centos", "fedora"}, OsVersion: 8, Arch: "x86_64"}
+	eln            = sysinfo.SysInfo{OsID: "fedora", VariantID: "eln", OsVersion: 44, Arch: "x86_64"}
+	fedora43       = sysinfo.SysInfo{OsID: "fedora", OsVersion: 43, Arch: "x86_64"}
+	oracle9        = sysinfo.SysInfo{OsID: "ol", OsIDLike: []string{"fedora"}, OsVersion: 9, Arch: "x86_64"}
+	navy9          = sysinfo.SysInfo{OsID: "navy", OsIDLike: []string{"rhel", "fedora"}, OsVersion: 9, Arch: "x86_64"}
+)
+
+var (
+	nvidiaChannels = []string{api.ChannelBaseOS, api.ChannelAppStream, api.ChannelExtensions, api.ChannelSupplementary}
+	amdChannels    = []string{api.ChannelBaseOS, api.ChannelAppStream}
+)
+
+func TestSelectStrategy(t *testing.T) {
+	tests := []struct {
+		name     string
+		sysInfo  sysinfo.SysInfo
+		channels []string
+		distro   string
+		expected []string
+	}{
+		{"CentOSStream", centosStream10, nvidiaChannels, "CentOS Stream", []string{"crb", "extras-common"}},
+		{"CentOSStreamNoExtensions", centosStream10, amdChannels, "CentOS Stream", []string{"crb"}},
+		{"AlmaLinux", alma9, nvidiaChannels, "AlmaLinux", []string{"crb", "extras"}},
+		{"Rocky8", rocky8, nvidiaChannels, "Rocky Linux", []string{"powertools", "extras"}},
+		{"Rocky8CRB", rocky8, []string{api.ChannelCRB}, "Rocky Linux", []string{"powertools"}},
+		{"ELN", eln, nvidiaChannels, "Fedora ELN", []string{"eln-crb", "eln-baseos", "eln-appstream", "eln-extras"}},
+		{"Fedora", fedora43, nvidiaChannels, "Fedora", nil},
+		{"OtherDerivative", navy9, nvidiaChannels, "Enterprise Linux derivative navy", []string{"crb"}},
+		{"Unknown", oracle9, nvidiaChannels, "", nil},
+		{"RHEL", sysinfo.SysInfo{OsID: "rhel", IsRhel: true}, nvidiaChannels, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectStrategy(tt.sysInfo)
+			if tt.sysInfo.IsRhel {
+				// RHEL is handled by Subscription Manager regardless.
+				if _, ok := NewRepositoryManager(nil, config.Default(), tt.sysInfo).(*repoMgr); ok {
+					t.Errorf("expected RHSM repository manager for RHEL")
+				}
+				return
+			}
+			if tt.distro == "" {
+				if got != nil {
+					t.Errorf("selectStrategy() = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil || got.distro != tt.distro {
+				t.Fatalf("selectStrategy() = %+v, want %s", got, tt.distro)
+			}
+			if repos := got.repos(tt.channels); !reflect.DeepEqual(repos, tt.expected) {
+				t.Errorf("repos(%v) = %v, want %v", tt.channels, repos, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEnsureRepositoriesEnabled(t *testing.T) {
+	tests := []struct {
+		name      string
+		sysInfo   sysinfo.SysInfo
+		reposDir  string
+		expectRun []string
+		runErr    error
+		expectErr bool
+	}{
+		{
+			name:      "CentOSStreamEnableCRB",
+			sysInfo:   centosStream10,
+			reposDir:  "testdata/centos",
+			expectRun: []string{"config-manager", "--set-enabled", "crb"},
+		},
+		{
+			name:      "CentOSStreamInstallRoot",
+			sysInfo:   sysinfo.SysInfo{OsID: "centos", OsVersion: 10, Arch: "x86_64", Root: "/mnt/sysimage"},
+			reposDir:  "testdata/centos",
+			expectRun: []string{"--installroot", "/mnt/sysimage", "config-manager", "--set-enabled", "crb"},
+		},
+		{
+			name:      "CentOSStreamFailure",
+			sysInfo:   centosStream10,
+			reposDir:  "testdata/centos",
+			expectRun: []string{"config-manager", "--set-enabled", "crb"},
+			runErr:    fmt.Errorf("no such command: config-manager"),
+			expectErr: true,
+		},
+		{
+			name:     "AlmaLinuxAlreadyEnabled",
+			sysInfo:  alma9,
+			reposDir: "testdata/alma",
+		},
+		{
+			// PowerTools repository is not defined, nothing to enable.
+			name:     "RockyMissingRepos",
+			sysInfo:  rocky8,
+			reposDir: "testdata/alma",
+		},
+		{
+			name:      "ELN",
+			sysInfo:   eln,
+			reposDir:  "testdata/eln",
+			expectRun: []string{"config-manager", "setopt", "eln-crb.enabled=1", "eln-extras.enabled=1"},
+		},
+		{
+			name:     "Fedora",
+			sysInfo:  fedora43,
+			reposDir: "testdata/does-not-exist",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockExec := mocks.NewMockExecutor(ctrl)
+			if tt.expectRun != nil {
+				mockExec.EXPECT().Run("mydnf", tt.expectRun).Return(tt.runErr)
+			}
+			rm := &repoMgr{
+				systemInfo: tt.sysInfo,
+				executor:   mockExec,
+				strategy:   *selectStrategy(tt.sysInfo),
+				dnfBin:     "mydnf",
+				reposDir:   tt.reposDir,
+				varsDir:    "testdata/vars",
+				journal:    repostate.NewJournal(filepath.Join(t.TempDir(), "enabled-repos")),
+			}
+			err := rm.EnsureRepositoriesEnabled(nvidiaChannels)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("Expected error: %v, but got: %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestRestoreRepositories(t *testing.T) {
+	tests := []struct {
+		name      string
+		sysInfo   sysinfo.SysInfo
+		expectRun []string
+	}{
+		{
+			name:      "CentOSStream",
+			sysInfo:   centosStream10,
+			expectRun: []string{"config-manager", "--set-disabled", "crb", "extras-common"},
+		},
+		{
+			name:      "ELN",
+			sysInfo:   eln,
+			expectRun: []string{"config-manager", "setopt", "crb.enabled=0", "extras-common.enabled=0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockExec := mocks.NewMockExecutor(ctrl)
+			mockExec.EXPECT().Run("mydnf", tt.expectRun).Return(nil)
+			path := filepath.Join(t.TempDir(), "enabled-repos")
+			if err := repostate.NewJournal(path).Record([]string{"crb", "extras-common"}); err != nil {
+				t.Fatal(err)
+			}
+			rm := &repoMgr{
+				systemInfo: tt.sysInfo,
+				executor:   mockExec,
+				strategy:   *selectStrategy(tt.sysInfo),
+				dnfBin:     "mydnf",
+				journal:    repostate.NewJournal(path),
+			}
+			// Nothing was enabled in this run.
+			if err := rm.RollbackRepositories(); err != nil {
+				t.Errorf("RollbackRepositories() error = %v", err)
+			}
+			if err := rm.RestoreRepositories(); err != nil {
+				t.Errorf("RestoreRepositories() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestListRepositories(t *testing.T) {
+	rm := &repoMgr{
+		systemInfo: centosStream10,
+		strategy:   *selectStrategy(centosStream10),
+		reposDir:   "testdata/centos",
+		varsDir:    "testdata/vars",
+	}
+	statuses, err := rm.ListRepositories([]string{api.ChannelBaseOS, api.ChannelCRB, api.ChannelExtensions, api.ChannelSupplementary})
+	if err != nil {
+		t.Fatalf("ListRepositories() error = %v", err)
+	}
+	expected := []api.RepositoryStatus{
+		{Channel: api.ChannelCRB, ID: "crb", Defined: true},
+		{Channel: api.ChannelBaseOS},
+		{Channel: api.ChannelExtensions, ID: "extras-common", Defined: true, Enabled: true},
+		{Channel: api.ChannelSupplementary, Unavailable: true},
+	}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("ListRepositories() = %+v, expected %+v", statuses, expected)
+	}
+}
+
+func TestEnableCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		sysInfo  sysinfo.SysInfo
+		expected []string
+	}{
+		{"CentOSStream", centosStream10, []string{"dnf", "config-manager", "--set-enabled", "crb", "extras-common"}},
+		{"ELN", eln, []string{"dnf", "config-manager", "setopt", "crb.enabled=1", "extras-common.enabled=1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Install root is where the snippet is generated, not where
+			// it runs.
+			tt.sysInfo.Root = "/mnt/sysimage"
+			rm := NewRepositoryManager(nil, config.Default(), tt.sysInfo)
+			if got := rm.EnableCommand([]string{"crb", "extras-common"}); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("EnableCommand() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -1,35 +1,99 @@
 package dnf
 
 import (
+	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"strings"
 
 	"github.com/mizdebsk/rhel-drivers/internal/api"
 	"github.com/mizdebsk/rhel-drivers/internal/cache"
+	"github.com/mizdebsk/rhel-drivers/internal/config"
 	"github.com/mizdebsk/rhel-drivers/internal/log"
+	"github.com/mizdebsk/rhel-drivers/internal/rpmdb"
+	"github.com/mizdebsk/rhel-drivers/internal/rpmver"
+	"github.com/mizdebsk/rhel-drivers/internal/sysinfo"
 )
 
-const defaultDNFBinary = "dnf"
+const (
+	defaultDNFBinary        = "dnf"
+	defaultCreaterepoBinary = "createrepo_c"
+	defaultRpmOstreeBinary  = "rpm-ostree"
+)
 
 type pkgMgr struct {
-	bin  string
-	exec api.Executor
+	bin           string
+	createrepoBin string
+	exec          api.Executor
+	// When set, packages are queried and installed in this alternate
+	// root directory instead of the running system.
+	installRoot string
+	// When set, installed packages are read from this RPM database
+	// instead of running rpm.
+	rpmdbPath string
+	// When set, only these repositories are used.
+	localRepos []api.LocalRepository
+	// On image mode systems transactions are either refused or done
+	// by layering packages with rpm-ostree.
+	imageMode    bool
+	ostreeLayer  bool
+	rpmOstreeBin string
 }
 
 var _ api.PackageManager = (*pkgMgr)(nil)
 
-func NewPackageManager(executor api.Executor) api.PackageManager {
-	return &pkgMgr{
-		bin:  defaultDNFBinary,
-		exec: executor,
+// NewPackageManager returns package manager using dnf.  If any local
+// repositories are given, only those are used instead of system ones.
+func NewPackageManager(executor api.Executor, cfg config.Config, systemInfo sysinfo.SysInfo, localRepos []api.LocalRepository) api.PackageManager {
+	pm := &pkgMgr{
+		bin:           defaultDNFBinary,
+		createrepoBin: defaultCreaterepoBinary,
+		rpmOstreeBin:  defaultRpmOstreeBinary,
+		exec:          executor,
+		installRoot:   systemInfo.Root,
+		localRepos:    localRepos,
+		imageMode:     systemInfo.ImageMode,
+		ostreeLayer:   cfg.ImageMode == config.ImageModeRpmOstree,
+	}
+	if cfg.InstalledBackend == config.InstalledBackendSqlite {
+		pm.rpmdbPath = filepath.Join(systemInfo.Root, cfg.RpmDBPath)
 	}
+	return pm
 }
 
-var availableCache = cache.Cache[[]api.PackageInfo]{}
+var availableCache = cache.Map[string, []api.PackageInfo]{}
 var installedCache = cache.Cache[[]api.PackageInfo]{}
 
-func (pm *pkgMgr) ListAvailablePackages() ([]api.PackageInfo, error) {
-	return availableCache.Get(func() ([]api.PackageInfo, error) {
+// rootArgs returns dnf options that select the alternate install root,
+// if any.
+func (pm *pkgMgr) rootArgs() []string {
+	if pm.installRoot == "" {
+		return nil
+	}
+	return []string{"--installroot", pm.installRoot}
+}
+
+// repoArgs returns dnf options that restrict it to local repositories,
+// if any were configured.
+func (pm *pkgMgr) repoArgs() []string {
+	var args []string
+	for _, repo := range pm.localRepos {
+		args = append(args, "--repofrompath", repo.ID+","+repo.Path)
+	}
+	for _, repo := range pm.localRepos {
+		args = append(args, "--repo", repo.ID)
+	}
+	return args
+}
+
+func (pm *pkgMgr) ListAvailablePackages(query api.PackageQuery) ([]api.PackageInfo, error) {
+	repos := query.Repos
+	if len(pm.localRepos) > 0 {
+		// Only local repositories are used, whatever they provide.
+		repos = nil
+	}
+	key := strings.Join(pm.rootArgs(), ",") + "|" + strings.Join(pm.repoArgs(), ",") + "|" + strings.Join(repos, ",") + "|" + strings.Join(query.Names, ",")
+	return availableCache.Get(key, func() ([]api.PackageInfo, error) {
 		tags := []string{"name", "epoch", "version", "release", "arch", "sourcerpm", "repoid"}
 		// QQQ and YYY are there to make filtering spurious lines easier.
 		format := "QQQ"
@@ -39,7 +103,15 @@ func (pm *pkgMgr) ListAvailablePackages() ([]api.PackageInfo, error) {
 		// Trailing NL is not required with DNF 4, but will be required with DNF 5.
 		// With DNF 4 it will result in empty lines, but they are ignored anyway.
 		format += "|YYY\n"
-		lines, err := pm.exec.RunCapture(pm.bin, []string{"-q", "repoquery", "--qf", format}...)
+		args := []string{"-q"}
+		args = append(args, pm.rootArgs()...)
+		args = append(args, "repoquery", "--qf", format)
+		args = append(args, pm.repoArgs()...)
+		for _, repo := range repos {
+			args = append(args, "--repo", repo)
+		}
+		args = append(args, query.Names...)
+		lines, err := pm.exec.RunCapture(pm.bin, args...)
 		if err != nil {
 			return nil, fmt.Errorf("failed to list available packages: %w", err)
 		}
@@ -49,13 +121,20 @@ func (pm *pkgMgr) ListAvailablePackages() ([]api.PackageInfo, error) {
 
 func (pm *pkgMgr) ListInstalledPackages() ([]api.PackageInfo, error) {
 	return installedCache.Get(func() ([]api.PackageInfo, error) {
+		if pm.rpmdbPath != "" {
+			return pm.readRpmDB()
+		}
 		tags := []string{"NAME", "EPOCH", "VERSION", "RELEASE", "ARCH", "SOURCERPM"}
 		format := "QQQ"
 		for _, field := range tags {
 			format += "|%|" + field + "?{%{" + field + "}}|"
 		}
 		format += "||YYY\n"
-		lines, err := pm.exec.RunCapture("rpm", []string{"-qa", "--qf", format}...)
+		args := []string{"-qa", "--qf", format}
+		if pm.installRoot != "" {
+			args = append(args, "--root", pm.installRoot)
+		}
+		lines, err := pm.exec.RunCapture("rpm", args...)
 		if err != nil {
 			return nil, fmt.Errorf("failed to list installed packages: %w", err)
 		}
@@ -63,6 +142,25 @@ func (pm *pkgMgr) ListInstalledPackages() ([]api.PackageInfo, error) {
 	})
 }
 
+func (pm *pkgMgr) readRpmDB() ([]api.PackageInfo, error) {
+	pkgs, err := rpmdb.ReadPackages(pm.rpmdbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list installed packages: %w", err)
+	}
+	var infos []api.PackageInfo
+	for _, pkg := range pkgs {
+		infos = append(infos, api.PackageInfo{
+			Name:       pkg.Name,
+			Epoch:      pkg.Epoch,
+			Version:    pkg.Version,
+			Release:    pkg.Release,
+			Arch:       pkg.Arch,
+			SourceName: parseNameFromNVRA(pkg.SourceRPM),
+		})
+	}
+	return infos, nil
+}
+
 func parseQueryOutput(lines []string) []api.PackageInfo {
 	var infos []api.PackageInfo
 	for _, line := range lines {
@@ -96,12 +194,17 @@ func (pm *pkgMgr) Remove(packages []string, batchMode, dryRun bool) error {
 }
 
 func (pm *pkgMgr) runTransaction(operation string, packages []string, batchMode, dryRun bool) error {
+	if pm.imageMode {
+		return pm.runOstreeTransaction(operation, packages, dryRun)
+	}
 	var args []string
 	if dryRun {
 		args = append(args, "--assumeno")
 	} else if batchMode {
 		args = append(args, "-y")
 	}
+	args = append(args, pm.rootArgs()...)
+	args = append(args, pm.repoArgs()...)
 	args = append(args, operation)
 	if len(packages) == 0 {
 		log.Warnf("no packages to %s", operation)
@@ -111,3 +214,110 @@ func (pm *pkgMgr) runTransaction(operation string, packages []string, batchMode,
 	args = append(args, packages...)
 	return pm.exec.Run(pm.bin, args)
 }
+
+func (pm *pkgMgr) runOstreeTransaction(operation string, packages []string, dryRun bool) error {
+	if !pm.ostreeLayer {
+		return fmt.Errorf("this system runs in image mode, where packages installed with dnf do not persist; " +
+			"use \"install --containerfile\" to generate instructions for building a new bootc image with drivers, " +
+			"or set \"image_mode = rpm-ostree\" in " + config.DefaultPath + " to layer packages with rpm-ostree")
+	}
+	if operation == "remove" {
+		layered, err := pm.layeredPackages(packages)
+		if err != nil {
+			return err
+		}
+		packages = layered
+	}
+	if len(packages) == 0 {
+		log.Warnf("no packages to %s", operation)
+		return nil
+	}
+	var args []string
+	switch operation {
+	case "install":
+		args = append(args, "install", "--idempotent")
+	case "remove":
+		args = append(args, "uninstall")
+	default:
+		return fmt.Errorf("unsupported operation %s in image mode", operation)
+	}
+	if dryRun {
+		args = append(args, "--dry-run")
+	}
+	log.Logf("%s packages with rpm-ostree: %v", operation, packages)
+	args = append(args, packages...)
+	if err := pm.exec.Run(pm.rpmOstreeBin, args); err != nil {
+		return err
+	}
+	if !dryRun {
+		log.Infof("changes will take effect after reboot")
+	}
+	return nil
+}
+
+// layeredPackages returns those packages requested to be layered in
+// the default deployment that match given packages, either by their
+// NEVRA or by name.  rpm-ostree refuses to uninstall anything else.
+func (pm *pkgMgr) layeredPackages(packages []string) ([]string, error) {
+	lines, err := pm.exec.RunCapture(pm.rpmOstreeBin, "status", "--json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rpm-ostree status: %w", err)
+	}
+	var status struct {
+		Deployments []struct {
+			RequestedPackages []string `json:"requested-packages"`
+		} `json:"deployments"`
+	}
+	if err := json.Unmarshal([]byte(strings.Join(lines, "\n")), &status); err != nil {
+		return nil, fmt.Errorf("failed to parse rpm-ostree status: %w", err)
+	}
+	if len(status.Deployments) == 0 {
+		return nil, nil
+	}
+	inst, err := pm.ListInstalledPackages()
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]bool{}
+	for _, pkg := range packages {
+		names[pkg] = true
+		for _, info := range inst {
+			if info.NEVRA() == pkg {
+				names[info.Name] = true
+			}
+		}
+	}
+	var layered []string
+	for _, req := range status.Deployments[0].RequestedPackages {
+		if nevra, err := rpmver.ParseNEVRA(req); names[req] || err == nil && names[nevra.Name] {
+			layered = append(layered, req)
+		}
+	}
+	return layered, nil
+}
+
+func (pm *pkgMgr) Download(packages []string, destDir string) error {
+	if len(packages) == 0 {
+		log.Warnf("no packages to download")
+		return nil
+	}
+	// All dependencies are downloaded, not just those missing on this
+	// system, so that packages can be installed on a different host.
+	args := pm.rootArgs()
+	args = append(args, "download", "--resolve", "--alldeps", "--destdir", destDir)
+	args = append(args, pm.repoArgs()...)
+	log.Logf("download packages: %v", packages)
+	args = append(args, packages...)
+	if err := pm.exec.Run(pm.bin, args); err != nil {
+		return fmt.Errorf("failed to download packages: %w", err)
+	}
+	return nil
+}
+
+func (pm *pkgMgr) CreateRepository(dir string) error {
+	log.Logf("creating repository metadata in %s", dir)
+	if err := pm.exec.Run(pm.createrepoBin, []string{dir}); err != nil {
+		return fmt.Errorf("failed to create repository metadata: %w", err)
+	}
+	return nil
+}
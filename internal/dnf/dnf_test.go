@@ -2,12 +2,15 @@ package dnf
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/golang/mock/gomock"
 
 	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/config"
 	"github.com/mizdebsk/rhel-drivers/internal/mocks"
+	"github.com/mizdebsk/rhel-drivers/internal/sysinfo"
 )
 
 func assertTwoPackagesAntBash(out []api.PackageInfo, t *testing.T) {
@@ -85,6 +88,78 @@ func TestDnf(t *testing.T) {
 				return pm.runTransaction("oper", []string{"foo", "bar"}, true, true)
 			},
 		},
+		{
+			name: "TransactionLocalRepos",
+			testFunc: func(t *testing.T) error {
+				pm.localRepos = []api.LocalRepository{
+					{ID: "local-a", Path: "/srv/a"},
+					{ID: "local-b", Path: "/srv/b"},
+				}
+				mockExec.EXPECT().
+					Run(dnfBin, []string{
+						"-y",
+						"--repofrompath", "local-a,/srv/a",
+						"--repofrompath", "local-b,/srv/b",
+						"--repo", "local-a",
+						"--repo", "local-b",
+						"oper", "foo", "bar",
+					}).
+					Return(nil)
+				return pm.runTransaction("oper", []string{"foo", "bar"}, true, false)
+			},
+		},
+		{
+			name: "TransactionInstallRoot",
+			testFunc: func(t *testing.T) error {
+				pm.installRoot = "/mnt/sysimage"
+				mockExec.EXPECT().
+					Run(dnfBin, []string{"-y", "--installroot", "/mnt/sysimage", "oper", "foo", "bar"}).
+					Return(nil)
+				return pm.runTransaction("oper", []string{"foo", "bar"}, true, false)
+			},
+		},
+		{
+			name: "ImageModeRefused",
+			testFunc: func(t *testing.T) error {
+				pm.imageMode = true
+				return pm.Install([]string{"foo", "bar"}, true, false)
+			},
+			expectErr: true,
+		},
+		{
+			name: "ImageModeInstall",
+			testFunc: func(t *testing.T) error {
+				pm.imageMode = true
+				pm.ostreeLayer = true
+				mockExec.EXPECT().
+					Run("myostree", []string{"install", "--idempotent", "foo", "bar"}).
+					Return(nil)
+				return pm.Install([]string{"foo", "bar"}, true, false)
+			},
+		},
+		{
+			name: "ImageModeInstallDryRun",
+			testFunc: func(t *testing.T) error {
+				pm.imageMode = true
+				pm.ostreeLayer = true
+				mockExec.EXPECT().
+					Run("myostree", []string{"install", "--idempotent", "--dry-run", "foo"}).
+					Return(nil)
+				return pm.Install([]string{"foo"}, false, true)
+			},
+		},
+		{
+			name: "ImageModeFailure",
+			testFunc: func(t *testing.T) error {
+				pm.imageMode = true
+				pm.ostreeLayer = true
+				mockExec.EXPECT().
+					Run("myostree", []string{"install", "--idempotent", "foo"}).
+					Return(fmt.Errorf("rpm-ostree failed"))
+				return pm.Install([]string{"foo"}, false, false)
+			},
+			expectErr: true,
+		},
 		{
 			name: "InstallSuccess",
 			testFunc: func(t *testing.T) error {
@@ -115,7 +190,7 @@ func TestDnf(t *testing.T) {
 						"QQQ|ant-junit|0|1.10.15|32.fc43|noarch|ant-1.10.15-32.fc43.src.rpm|updates-testing|YYY",
 						"oh well...",
 					}, fmt.Errorf("fatal error"))
-				out, err := pm.ListAvailablePackages()
+				out, err := pm.ListAvailablePackages(api.PackageQuery{})
 				if len(out) != 0 {
 					t.Errorf("Expected exactly 0 packages, got %d", len(out))
 				}
@@ -137,7 +212,7 @@ func TestDnf(t *testing.T) {
 						"QQQ|f|o|o|YYY",
 						"QQQ|bash|0|5.3.0|2.fc43|x86_64|bash-5.3.0-2.fc43.src.rpm|fedora|YYY",
 					}, nil)
-				out, err := pm.ListAvailablePackages()
+				out, err := pm.ListAvailablePackages(api.PackageQuery{})
 				assertTwoPackagesAntBash(out, t)
 				return err
 			},
@@ -145,11 +220,159 @@ func TestDnf(t *testing.T) {
 		{
 			name: "ListAvailableCached",
 			testFunc: func(t *testing.T) error {
-				out, err := pm.ListAvailablePackages()
+				out, err := pm.ListAvailablePackages(api.PackageQuery{})
+				assertTwoPackagesAntBash(out, t)
+				return err
+			},
+		},
+		{
+			name: "ListAvailableQuery",
+			testFunc: func(t *testing.T) error {
+				mockExec.EXPECT().
+					RunCapture(dnfBin, []string{
+						"-q", "repoquery", "--qf",
+						"QQQ|%{name}|%{epoch}|%{version}|%{release}|%{arch}|%{sourcerpm}|%{repoid}|YYY\n",
+						"ant*", "bash",
+					}).
+					Return([]string{
+						"QQQ|ant-junit|0|1.10.15|32.fc43|noarch|ant-1.10.15-32.fc43.src.rpm|updates-testing|YYY",
+						"QQQ|bash|0|5.3.0|2.fc43|x86_64|bash-5.3.0-2.fc43.src.rpm|fedora|YYY",
+					}, nil)
+				out, err := pm.ListAvailablePackages(api.PackageQuery{
+					Names: []string{"ant*", "bash"},
+				})
+				assertTwoPackagesAntBash(out, t)
+				return err
+			},
+		},
+		{
+			name: "ListAvailableQueryCached",
+			testFunc: func(t *testing.T) error {
+				out, err := pm.ListAvailablePackages(api.PackageQuery{
+					Names: []string{"ant*", "bash"},
+				})
+				assertTwoPackagesAntBash(out, t)
+				return err
+			},
+		},
+		{
+			name: "ListAvailableQueryRepos",
+			testFunc: func(t *testing.T) error {
+				mockExec.EXPECT().
+					RunCapture(dnfBin, []string{
+						"-q", "repoquery", "--qf",
+						"QQQ|%{name}|%{epoch}|%{version}|%{release}|%{arch}|%{sourcerpm}|%{repoid}|YYY\n",
+						"--repo", "rhel-10-for-x86_64-baseos-rpms", "--repo", "rhel-10-for-x86_64-extensions-rpms",
+						"ant*", "bash",
+					}).
+					Return([]string{
+						"QQQ|ant-junit|0|1.10.15|32.fc43|noarch|ant-1.10.15-32.fc43.src.rpm|rhel-10-for-x86_64-extensions-rpms|YYY",
+						"QQQ|bash|0|5.3.0|2.fc43|x86_64|bash-5.3.0-2.fc43.src.rpm|rhel-10-for-x86_64-baseos-rpms|YYY",
+					}, nil)
+				out, err := pm.ListAvailablePackages(api.PackageQuery{
+					Names: []string{"ant*", "bash"},
+					Repos: []string{"rhel-10-for-x86_64-baseos-rpms", "rhel-10-for-x86_64-extensions-rpms"},
+				})
+				assertTwoPackagesAntBash(out, t)
+				return err
+			},
+		},
+		{
+			name: "ListAvailableLocalRepos",
+			testFunc: func(t *testing.T) error {
+				pm.localRepos = []api.LocalRepository{{ID: "local", Path: "/srv/bundle"}}
+				mockExec.EXPECT().
+					RunCapture(dnfBin, []string{
+						"-q", "repoquery", "--qf",
+						"QQQ|%{name}|%{epoch}|%{version}|%{release}|%{arch}|%{sourcerpm}|%{repoid}|YYY\n",
+						"--repofrompath", "local,/srv/bundle", "--repo", "local",
+						"ant*", "bash",
+					}).
+					Return([]string{
+						"QQQ|ant-junit|0|1.10.15|32.fc43|noarch|ant-1.10.15-32.fc43.src.rpm|local|YYY",
+						"QQQ|bash|0|5.3.0|2.fc43|x86_64|bash-5.3.0-2.fc43.src.rpm|local|YYY",
+					}, nil)
+				// Only local repositories are used, whatever they provide.
+				out, err := pm.ListAvailablePackages(api.PackageQuery{
+					Names: []string{"ant*", "bash"},
+					Repos: []string{"rhel-10-for-x86_64-baseos-rpms", "rhel-10-for-x86_64-extensions-rpms"},
+				})
+				assertTwoPackagesAntBash(out, t)
+				return err
+			},
+		},
+		{
+			name: "ListAvailableInstallRoot",
+			testFunc: func(t *testing.T) error {
+				pm.installRoot = "/mnt/sysimage"
+				mockExec.EXPECT().
+					RunCapture(dnfBin, []string{
+						"-q", "--installroot", "/mnt/sysimage", "repoquery", "--qf",
+						"QQQ|%{name}|%{epoch}|%{version}|%{release}|%{arch}|%{sourcerpm}|%{repoid}|YYY\n",
+					}).
+					Return([]string{
+						"QQQ|ant-junit|0|1.10.15|32.fc43|noarch|ant-1.10.15-32.fc43.src.rpm|updates-testing|YYY",
+						"QQQ|bash|0|5.3.0|2.fc43|x86_64|bash-5.3.0-2.fc43.src.rpm|fedora|YYY",
+					}, nil)
+				out, err := pm.ListAvailablePackages(api.PackageQuery{})
 				assertTwoPackagesAntBash(out, t)
 				return err
 			},
 		},
+		{
+			name: "DownloadSuccess",
+			testFunc: func(t *testing.T) error {
+				mockExec.EXPECT().
+					Run(dnfBin, []string{"download", "--resolve", "--alldeps", "--destdir", "/tmp/bundle", "foo", "bar"}).
+					Return(nil)
+				return pm.Download([]string{"foo", "bar"}, "/tmp/bundle")
+			},
+		},
+		{
+			name: "DownloadFailure",
+			testFunc: func(t *testing.T) error {
+				mockExec.EXPECT().
+					Run(dnfBin, []string{"download", "--resolve", "--alldeps", "--destdir", "/tmp/bundle", "foo"}).
+					Return(fmt.Errorf("no network"))
+				return pm.Download([]string{"foo"}, "/tmp/bundle")
+			},
+			expectErr: true,
+		},
+		{
+			name: "DownloadInstallRoot",
+			testFunc: func(t *testing.T) error {
+				pm.installRoot = "/mnt/sysimage"
+				mockExec.EXPECT().
+					Run(dnfBin, []string{"--installroot", "/mnt/sysimage", "download", "--resolve", "--alldeps", "--destdir", "/tmp/bundle", "foo"}).
+					Return(nil)
+				return pm.Download([]string{"foo"}, "/tmp/bundle")
+			},
+		},
+		{
+			name: "DownloadNothing",
+			testFunc: func(t *testing.T) error {
+				return pm.Download(nil, "/tmp/bundle")
+			},
+		},
+		{
+			name: "CreateRepositorySuccess",
+			testFunc: func(t *testing.T) error {
+				mockExec.EXPECT().
+					Run("mycreaterepo", []string{"/tmp/bundle"}).
+					Return(nil)
+				return pm.CreateRepository("/tmp/bundle")
+			},
+		},
+		{
+			name: "CreateRepositoryFailure",
+			testFunc: func(t *testing.T) error {
+				mockExec.EXPECT().
+					Run("mycreaterepo", []string{"/tmp/bundle"}).
+					Return(fmt.Errorf("createrepo_c: command not found"))
+				return pm.CreateRepository("/tmp/bundle")
+			},
+			expectErr: true,
+		},
 		{
 			name: "ListInstalledFailure",
 			testFunc: func(t *testing.T) error {
@@ -170,6 +393,23 @@ func TestDnf(t *testing.T) {
 			},
 			expectErr: true,
 		},
+		{
+			// Failing so that the result is not cached for other tests.
+			name: "ListInstalledInstallRoot",
+			testFunc: func(t *testing.T) error {
+				pm.installRoot = "/mnt/sysimage"
+				mockExec.EXPECT().
+					RunCapture("rpm", []string{
+						"-qa", "--qf",
+						"QQQ|%|NAME?{%{NAME}}||%|EPOCH?{%{EPOCH}}||%|VERSION?{%{VERSION}}||%|RELEASE?{%{RELEASE}}||%|ARCH?{%{ARCH}}||%|SOURCERPM?{%{SOURCERPM}}|||YYY\n",
+						"--root", "/mnt/sysimage",
+					}).
+					Return(nil, fmt.Errorf("fatal error"))
+				_, err := pm.ListInstalledPackages()
+				return err
+			},
+			expectErr: true,
+		},
 		{
 			name: "ListInstalledSuccess",
 			testFunc: func(t *testing.T) error {
@@ -197,24 +437,113 @@ func TestDnf(t *testing.T) {
 				return err
 			},
 		},
+		{
+			// Relies on installed packages cached by previous tests.
+			name: "ImageModeRemove",
+			testFunc: func(t *testing.T) error {
+				pm.imageMode = true
+				pm.ostreeLayer = true
+				mockExec.EXPECT().
+					RunCapture("myostree", []string{"status", "--json"}).
+					Return([]string{
+						`{"deployments": [`,
+						`  {"booted": false, "requested-packages": ["bash", "vim", "foo-1.0-1.noarch"]},`,
+						`  {"booted": true, "requested-packages": ["ant-junit"]}`,
+						`]}`,
+					}, nil)
+				mockExec.EXPECT().
+					Run("myostree", []string{"uninstall", "bash", "foo-1.0-1.noarch"}).
+					Return(nil)
+				return pm.Remove([]string{"bash-5.3.0-2.fc43.x86_64", "ant-junit-1.10.15-32.fc43.noarch", "foo"}, false, false)
+			},
+		},
+		{
+			name: "ImageModeRemoveNotLayered",
+			testFunc: func(t *testing.T) error {
+				pm.imageMode = true
+				pm.ostreeLayer = true
+				mockExec.EXPECT().
+					RunCapture("myostree", []string{"status", "--json"}).
+					Return([]string{`{"deployments": [{"requested-packages": ["vim"]}]}`}, nil)
+				return pm.Remove([]string{"bash", "cuda-toolkit"}, false, false)
+			},
+		},
+		{
+			name: "ImageModeRemoveStatusFailure",
+			testFunc: func(t *testing.T) error {
+				pm.imageMode = true
+				pm.ostreeLayer = true
+				mockExec.EXPECT().
+					RunCapture("myostree", []string{"status", "--json"}).
+					Return(nil, fmt.Errorf("rpm-ostree failed"))
+				return pm.Remove([]string{"bash"}, false, false)
+			},
+			expectErr: true,
+		},
 		{
 			name: "NewPackageManager",
 			testFunc: func(t *testing.T) error {
-				pm := NewPackageManager(mockExec)
+				pm := NewPackageManager(mockExec, config.Default(), sysinfo.SysInfo{}, nil)
 				if pm == nil {
 					t.Errorf("Expected PackageManager, got nil")
 				}
 				return nil
 			},
 		},
+		{
+			name: "NewPackageManagerInstallRoot",
+			testFunc: func(t *testing.T) error {
+				cfg := config.Default()
+				cfg.InstalledBackend = config.InstalledBackendSqlite
+				local := []api.LocalRepository{{ID: "local", Path: "/srv/bundle"}}
+				pm := NewPackageManager(mockExec, cfg, sysinfo.SysInfo{Root: "/mnt/sysimage"}, local).(*pkgMgr)
+				if pm.installRoot != "/mnt/sysimage" {
+					t.Errorf("Expected install root /mnt/sysimage, got %s", pm.installRoot)
+				}
+				if pm.rpmdbPath != "/mnt/sysimage"+cfg.RpmDBPath {
+					t.Errorf("Expected RPM database in install root, got %s", pm.rpmdbPath)
+				}
+				if !reflect.DeepEqual(pm.localRepos, local) {
+					t.Errorf("Expected local repositories %v, got %v", local, pm.localRepos)
+				}
+				return nil
+			},
+		},
+		{
+			name: "ReadRpmDBSuccess",
+			testFunc: func(t *testing.T) error {
+				pm.rpmdbPath = "../rpmdb/testdata/rpmdb.sqlite"
+				out, err := pm.readRpmDB()
+				if len(out) != 44 {
+					t.Errorf("Expected exactly 44 packages, got %d", len(out))
+				}
+				for _, pkg := range out {
+					if pkg.Name == "nvidia-driver" && pkg.SourceName != "nvidia-driver" {
+						t.Errorf("Expected nvidia-driver source name, got %s", pkg.SourceName)
+					}
+				}
+				return err
+			},
+		},
+		{
+			name: "ReadRpmDBFailure",
+			testFunc: func(t *testing.T) error {
+				pm.rpmdbPath = "testdata/does-not-exist.sqlite"
+				_, err := pm.readRpmDB()
+				return err
+			},
+			expectErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			mockExec = mocks.NewMockExecutor(ctrl)
 			pm = pkgMgr{
-				bin:  dnfBin,
-				exec: mockExec,
+				bin:           dnfBin,
+				createrepoBin: "mycreaterepo",
+				rpmOstreeBin:  "myostree",
+				exec:          mockExec,
 			}
 			err := tt.testFunc(t)
 			if (err != nil) != tt.expectErr {
@@ -1,11 +1,18 @@
 package dnf
 
 import (
-	"strings"
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+	"github.com/mizdebsk/rhel-drivers/internal/rpmver"
 )
 
 func parseNameFromNVRA(nvra string) string {
-	last := max(0, strings.LastIndex(nvra, "-"))
-	prev := max(0, strings.LastIndex(nvra[:last], "-"))
-	return nvra[:prev]
+	if nvra == "" {
+		return ""
+	}
+	nevra, err := rpmver.ParseNEVRA(nvra)
+	if err != nil {
+		log.Debugf("%v", err)
+		return ""
+	}
+	return nevra.Name
 }
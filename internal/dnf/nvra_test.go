@@ -41,12 +41,12 @@ func TestParseNameFromNVRA(t *testing.T) {
 		{
 			name:     "TrailingDash",
 			input:    "trailing-dash-1.0-",
-			expected: "trailing-dash",
+			expected: "",
 		},
 		{
 			name:     "TwoTrailingDashes",
 			input:    "C--",
-			expected: "C",
+			expected: "",
 		},
 		{
 			name:     "LeadingSpaces",
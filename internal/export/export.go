@@ -0,0 +1,118 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatKickstart = "kickstart"
+	FormatAnsible   = "ansible"
+	FormatCloudInit = "cloud-init"
+)
+
+var Formats = []string{FormatKickstart, FormatAnsible, FormatCloudInit}
+
+// CheckFormat returns an error if given export format is not known.
+func CheckFormat(format string) error {
+	if !slices.Contains(Formats, format) {
+		return fmt.Errorf("unknown export format %q (expected one of: %s)", format, strings.Join(Formats, ", "))
+	}
+	return nil
+}
+
+// Write renders provisioning snippet in given format that runs given
+// command to enable repositories, if any, and installs given packages.
+// Repositories are enabled persistently, so that drivers are updated
+// along with the rest of the system.
+func Write(w io.Writer, format string, enable, packages []string) error {
+	var out string
+	switch format {
+	case FormatKickstart:
+		out = kickstart(enable, packages)
+	case FormatAnsible:
+		out = ansible(enable, packages)
+	case FormatCloudInit:
+		out = cloudInit(enable, packages)
+	default:
+		return CheckFormat(format)
+	}
+	if _, err := io.WriteString(w, out); err != nil {
+		return fmt.Errorf("failed to write %s snippet: %w", format, err)
+	}
+	return nil
+}
+
+func kickstart(enable, packages []string) string {
+	var sb strings.Builder
+	if len(enable) == 0 {
+		sb.WriteString("%packages\n")
+		for _, pkg := range packages {
+			sb.WriteString(pkg + "\n")
+		}
+		sb.WriteString("%end\n")
+		return sb.String()
+	}
+	// Repositories that are not installation sources can't be used in
+	// %packages, so install from the installed system instead.
+	// Package specifications are quoted, as they may contain globs.
+	sb.WriteString("%post\n")
+	sb.WriteString(strings.Join(enable, " ") + "\n")
+	sb.WriteString("dnf -y install")
+	for _, pkg := range packages {
+		sb.WriteString(" \\\n    '" + pkg + "'")
+	}
+	sb.WriteString("\n%end\n")
+	return sb.String()
+}
+
+func ansible(enable, packages []string) string {
+	var sb strings.Builder
+	if len(enable) > 0 {
+		sb.WriteString("- name: Enable repositories of hardware drivers\n")
+		sb.WriteString("  ansible.builtin.command:\n")
+		sb.WriteString("    argv:\n")
+		for _, arg := range enable {
+			sb.WriteString("      - " + yamlString(arg) + "\n")
+		}
+	}
+	sb.WriteString("- name: Install hardware drivers\n")
+	sb.WriteString("  ansible.builtin.dnf:\n")
+	sb.WriteString("    name:\n")
+	for _, pkg := range packages {
+		sb.WriteString("      - " + yamlString(pkg) + "\n")
+	}
+	sb.WriteString("    state: present\n")
+	return sb.String()
+}
+
+func cloudInit(enable, packages []string) string {
+	var sb strings.Builder
+	sb.WriteString("#cloud-config\n")
+	if len(enable) == 0 {
+		sb.WriteString("packages:\n")
+		for _, pkg := range packages {
+			sb.WriteString("  - " + yamlString(pkg) + "\n")
+		}
+		return sb.String()
+	}
+	// The packages module has no way to enable repositories and runs
+	// before runcmd, so packages are installed by runcmd as well.
+	sb.WriteString("runcmd:\n")
+	for _, cmd := range [][]string{enable, append([]string{"dnf", "-y", "install"}, packages...)} {
+		sb.WriteString("  -\n")
+		for _, arg := range cmd {
+			sb.WriteString("    - " + yamlString(arg) + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// yamlString quotes package specification, as epochs and version
+// globs could otherwise be misinterpreted by YAML parsers.
+func yamlString(s string) string {
+	return strconv.Quote(s)
+}
@@ -0,0 +1,112 @@
+package export
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	packages := []string{"nvidia-driver-3:580.95.05-1.el10.x86_64", "kmod-nvidia-580.95.05-*"}
+	enable := []string{"/usr/sbin/subscription-manager", "repos",
+		"--enable", "rhel-10-for-x86_64-extensions-rpms", "--enable", "rhel-10-for-x86_64-supplementary-rpms"}
+	tests := []struct {
+		name      string
+		format    string
+		enable    []string
+		expected  string
+		expectErr bool
+	}{
+		{
+			format: FormatKickstart,
+			expected: "%packages\n" +
+				"nvidia-driver-3:580.95.05-1.el10.x86_64\n" +
+				"kmod-nvidia-580.95.05-*\n" +
+				"%end\n",
+		},
+		{
+			name:   "kickstart-repos",
+			format: FormatKickstart,
+			enable: enable,
+			expected: "%post\n" +
+				"/usr/sbin/subscription-manager repos --enable rhel-10-for-x86_64-extensions-rpms --enable rhel-10-for-x86_64-supplementary-rpms\n" +
+				"dnf -y install \\\n" +
+				"    'nvidia-driver-3:580.95.05-1.el10.x86_64' \\\n" +
+				"    'kmod-nvidia-580.95.05-*'\n" +
+				"%end\n",
+		},
+		{
+			format: FormatAnsible,
+			expected: "- name: Install hardware drivers\n" +
+				"  ansible.builtin.dnf:\n" +
+				"    name:\n" +
+				"      - \"nvidia-driver-3:580.95.05-1.el10.x86_64\"\n" +
+				"      - \"kmod-nvidia-580.95.05-*\"\n" +
+				"    state: present\n",
+		},
+		{
+			name:   "ansible-repos",
+			format: FormatAnsible,
+			enable: enable,
+			expected: "- name: Enable repositories of hardware drivers\n" +
+				"  ansible.builtin.command:\n" +
+				"    argv:\n" +
+				"      - \"/usr/sbin/subscription-manager\"\n" +
+				"      - \"repos\"\n" +
+				"      - \"--enable\"\n" +
+				"      - \"rhel-10-for-x86_64-extensions-rpms\"\n" +
+				"      - \"--enable\"\n" +
+				"      - \"rhel-10-for-x86_64-supplementary-rpms\"\n" +
+				"- name: Install hardware drivers\n" +
+				"  ansible.builtin.dnf:\n" +
+				"    name:\n" +
+				"      - \"nvidia-driver-3:580.95.05-1.el10.x86_64\"\n" +
+				"      - \"kmod-nvidia-580.95.05-*\"\n" +
+				"    state: present\n",
+		},
+		{
+			format: FormatCloudInit,
+			expected: "#cloud-config\n" +
+				"packages:\n" +
+				"  - \"nvidia-driver-3:580.95.05-1.el10.x86_64\"\n" +
+				"  - \"kmod-nvidia-580.95.05-*\"\n",
+		},
+		{
+			name:   "cloud-init-repos",
+			format: FormatCloudInit,
+			enable: []string{"dnf", "config-manager", "--set-enabled", "crb"},
+			expected: "#cloud-config\n" +
+				"runcmd:\n" +
+				"  -\n" +
+				"    - \"dnf\"\n" +
+				"    - \"config-manager\"\n" +
+				"    - \"--set-enabled\"\n" +
+				"    - \"crb\"\n" +
+				"  -\n" +
+				"    - \"dnf\"\n" +
+				"    - \"-y\"\n" +
+				"    - \"install\"\n" +
+				"    - \"nvidia-driver-3:580.95.05-1.el10.x86_64\"\n" +
+				"    - \"kmod-nvidia-580.95.05-*\"\n",
+		},
+		{
+			format:    "puppet",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		if tt.name == "" {
+			tt.name = tt.format
+		}
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			err := Write(&out, tt.format, tt.enable, packages)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Expected error: %v, but got: %v", tt.expectErr, err)
+			}
+			if out.String() != tt.expected {
+				t.Errorf("got output:\n%s\nwant:\n%s", out.String(), tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package localrepo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+)
+
+const (
+	repoIDPrefix = "rhel-drivers-"
+	repomdPath   = "repodata/repomd.xml"
+)
+
+// Source is a set of repositories found in local directories or ISO
+// images.  It doubles as a repository manager, as there is nothing to
+// enable when installing from local repositories.
+type Source struct {
+	Repos    []api.LocalRepository
+	executor api.Executor
+	mounts   []string
+}
+
+var _ api.RepositoryManager = (*Source)(nil)
+
+// Open looks for repositories in given paths, each of which may be
+// a repository directory, a directory with repositories in its
+// subdirectories (like an installation DVD) or an ISO image thereof.
+// Close must be called to unmount any mounted images.
+func Open(executor api.Executor, paths []string) (*Source, error) {
+	src := &Source{executor: executor}
+	for _, path := range paths {
+		if err := src.add(path); err != nil {
+			src.Close()
+			return nil, err
+		}
+	}
+	return src, nil
+}
+
+func (src *Source) add(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("invalid path %s: %w", path, err)
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("cannot access local repository %s: %w", path, err)
+	}
+	dir := path
+	if !stat.IsDir() {
+		if !strings.EqualFold(filepath.Ext(path), ".iso") {
+			return fmt.Errorf("local repository %s is neither a directory nor an ISO image", path)
+		}
+		if dir, err = src.mountISO(path); err != nil {
+			return err
+		}
+	}
+
+	if isRepository(dir) {
+		src.addRepo(filepath.Base(path), dir)
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read directory %s: %w", dir, err)
+	}
+	found := false
+	for _, entry := range entries {
+		sub := filepath.Join(dir, entry.Name())
+		if entry.IsDir() && isRepository(sub) {
+			src.addRepo(entry.Name(), sub)
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("no repository metadata found in %s (you may need to run createrepo_c)", path)
+	}
+	return nil
+}
+
+func isRepository(dir string) bool {
+	stat, err := os.Stat(filepath.Join(dir, repomdPath))
+	return err == nil && stat.Mode().IsRegular()
+}
+
+var invalidRepoIDChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+func (src *Source) addRepo(name, dir string) {
+	base := repoIDPrefix + strings.ToLower(invalidRepoIDChars.ReplaceAllString(name, "-"))
+	id := base
+	for i := 2; src.hasRepo(id); i++ {
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+	log.Logf("using local repository %s from %s", id, dir)
+	src.Repos = append(src.Repos, api.LocalRepository{ID: id, Path: dir})
+}
+
+func (src *Source) hasRepo(id string) bool {
+	for _, repo := range src.Repos {
+		if repo.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (src *Source) mountISO(path string) (string, error) {
+	dir, err := os.MkdirTemp("", "rhel-drivers-iso-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create mount point: %w", err)
+	}
+	log.Logf("mounting %s on %s", path, dir)
+	if err := src.executor.Run("mount", []string{"-o", "loop,ro", path, dir}); err != nil {
+		if err := os.Remove(dir); err != nil {
+			log.Warnf("failed to remove %s: %v", dir, err)
+		}
+		return "", fmt.Errorf("failed to mount ISO image %s: %w", path, err)
+	}
+	src.mounts = append(src.mounts, dir)
+	return dir, nil
+}
+
+// Close unmounts ISO images mounted by Open.
+func (src *Source) Close() {
+	for _, dir := range src.mounts {
+		log.Logf("unmounting %s", dir)
+		if err := src.executor.Run("umount", []string{dir}); err != nil {
+			log.Warnf("failed to unmount %s: %v", dir, err)
+			continue
+		}
+		if err := os.Remove(dir); err != nil {
+			log.Warnf("failed to remove %s: %v", dir, err)
+		}
+	}
+	src.mounts = nil
+}
+
+func (src *Source) EnsureRepositoriesEnabled(channels []string) error {
+	log.Logf("using local repositories only, not enabling system repositories")
+	return nil
+}
+
+func (src *Source) RollbackRepositories() error {
+	return nil
+}
+
+func (src *Source) RestoreRepositories() error {
+	return fmt.Errorf("restoring system repositories is not possible with local repositories")
+}
+
+// EnableCommand returns nothing, as local repositories are passed to
+// dnf directly rather than enabled.
+func (src *Source) EnableCommand(ids []string) []string {
+	return nil
+}
+
+// ListRepositories returns local repositories, which are all enabled
+// and provide whatever channels are needed.
+func (src *Source) ListRepositories(channels []string) ([]api.RepositoryStatus, error) {
+	var statuses []api.RepositoryStatus
+	for _, repo := range src.Repos {
+		statuses = append(statuses, api.RepositoryStatus{ID: repo.ID, Defined: true, Enabled: true})
+	}
+	return statuses, nil
+}
@@ -0,0 +1,145 @@
+package localrepo
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/mocks"
+)
+
+func abs(t *testing.T, path string) string {
+	p, err := filepath.Abs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name      string
+		paths     []string
+		expected  []api.LocalRepository
+		expectErr bool
+	}{
+		{
+			name:  "SingleRepository",
+			paths: []string{"testdata/repo"},
+			expected: []api.LocalRepository{
+				{ID: "rhel-drivers-repo", Path: abs(t, "testdata/repo")},
+			},
+		},
+		{
+			name:  "RepositoriesInSubdirectories",
+			paths: []string{"testdata/dvd"},
+			expected: []api.LocalRepository{
+				{ID: "rhel-drivers-appstream", Path: abs(t, "testdata/dvd/AppStream")},
+				{ID: "rhel-drivers-baseos", Path: abs(t, "testdata/dvd/BaseOS")},
+			},
+		},
+		{
+			name:  "DuplicateNames",
+			paths: []string{"testdata/repo", "testdata/repo"},
+			expected: []api.LocalRepository{
+				{ID: "rhel-drivers-repo", Path: abs(t, "testdata/repo")},
+				{ID: "rhel-drivers-repo-2", Path: abs(t, "testdata/repo")},
+			},
+		},
+		{
+			name:      "NoRepository",
+			paths:     []string{"testdata/norepo"},
+			expectErr: true,
+		},
+		{
+			name:      "MissingPath",
+			paths:     []string{"testdata/does-not-exist"},
+			expectErr: true,
+		},
+		{
+			name:      "NotAnImage",
+			paths:     []string{"testdata/file.txt"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			src, err := Open(mocks.NewMockExecutor(ctrl), tt.paths)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Open() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if tt.expectErr {
+				return
+			}
+			defer src.Close()
+			if !reflect.DeepEqual(src.Repos, tt.expected) {
+				t.Errorf("Open() repos = %+v, want %+v", src.Repos, tt.expected)
+			}
+			if err := src.EnsureRepositoriesEnabled([]string{api.ChannelBaseOS}); err != nil {
+				t.Errorf("EnsureRepositoriesEnabled() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestOpenISO(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockExec := mocks.NewMockExecutor(ctrl)
+	image := abs(t, "testdata/image.iso")
+	var mountPoint string
+
+	gomock.InOrder(
+		mockExec.EXPECT().
+			Run("mount", gomock.Any()).
+			DoAndReturn(func(command string, args []string) error {
+				if len(args) != 4 || args[0] != "-o" || args[1] != "loop,ro" || args[2] != image {
+					t.Errorf("unexpected mount arguments: %v", args)
+				}
+				mountPoint = args[3]
+				repodata := filepath.Join(mountPoint, "BaseOS", "repodata")
+				if err := os.MkdirAll(repodata, 0o755); err != nil {
+					return err
+				}
+				return os.WriteFile(filepath.Join(repodata, "repomd.xml"), nil, 0o644)
+			}),
+		mockExec.EXPECT().
+			Run("umount", gomock.Any()).
+			DoAndReturn(func(command string, args []string) error {
+				if len(args) != 1 || args[0] != mountPoint {
+					t.Errorf("unexpected umount arguments: %v", args)
+				}
+				return os.RemoveAll(filepath.Join(mountPoint, "BaseOS"))
+			}),
+	)
+
+	src, err := Open(mockExec, []string{"testdata/image.iso"})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	expected := []api.LocalRepository{
+		{ID: "rhel-drivers-baseos", Path: filepath.Join(mountPoint, "BaseOS")},
+	}
+	if !reflect.DeepEqual(src.Repos, expected) {
+		t.Errorf("Open() repos = %+v, want %+v", src.Repos, expected)
+	}
+	src.Close()
+	if _, err := os.Stat(mountPoint); !os.IsNotExist(err) {
+		t.Errorf("mount point %s was not removed: %v", mountPoint, err)
+	}
+}
+
+func TestOpenISOMountFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockExec := mocks.NewMockExecutor(ctrl)
+	mockExec.EXPECT().Run("mount", gomock.Any()).Return(os.ErrPermission)
+
+	if _, err := Open(mockExec, []string{"testdata/image.iso"}); err == nil {
+		t.Fatalf("Open() error = nil, want non-nil")
+	}
+}
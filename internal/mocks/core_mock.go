@@ -8,6 +8,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	api "github.com/mizdebsk/rhel-drivers/internal/api"
 )
 
 // MockRepositoryManager is a mock of RepositoryManager interface.
@@ -33,16 +34,125 @@ func (m *MockRepositoryManager) EXPECT() *MockRepositoryManagerMockRecorder {
 	return m.recorder
 }
 
+// EnableCommand mocks base method.
+func (m *MockRepositoryManager) EnableCommand(ids []string) []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableCommand", ids)
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// EnableCommand indicates an expected call of EnableCommand.
+func (mr *MockRepositoryManagerMockRecorder) EnableCommand(ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableCommand", reflect.TypeOf((*MockRepositoryManager)(nil).EnableCommand), ids)
+}
+
 // EnsureRepositoriesEnabled mocks base method.
-func (m *MockRepositoryManager) EnsureRepositoriesEnabled() error {
+func (m *MockRepositoryManager) EnsureRepositoriesEnabled(channels []string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "EnsureRepositoriesEnabled")
+	ret := m.ctrl.Call(m, "EnsureRepositoriesEnabled", channels)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // EnsureRepositoriesEnabled indicates an expected call of EnsureRepositoriesEnabled.
-func (mr *MockRepositoryManagerMockRecorder) EnsureRepositoriesEnabled() *gomock.Call {
+func (mr *MockRepositoryManagerMockRecorder) EnsureRepositoriesEnabled(channels interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureRepositoriesEnabled", reflect.TypeOf((*MockRepositoryManager)(nil).EnsureRepositoriesEnabled), channels)
+}
+
+// ListRepositories mocks base method.
+func (m *MockRepositoryManager) ListRepositories(channels []string) ([]api.RepositoryStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRepositories", channels)
+	ret0, _ := ret[0].([]api.RepositoryStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRepositories indicates an expected call of ListRepositories.
+func (mr *MockRepositoryManagerMockRecorder) ListRepositories(channels interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRepositories", reflect.TypeOf((*MockRepositoryManager)(nil).ListRepositories), channels)
+}
+
+// RestoreRepositories mocks base method.
+func (m *MockRepositoryManager) RestoreRepositories() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreRepositories")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestoreRepositories indicates an expected call of RestoreRepositories.
+func (mr *MockRepositoryManagerMockRecorder) RestoreRepositories() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreRepositories", reflect.TypeOf((*MockRepositoryManager)(nil).RestoreRepositories))
+}
+
+// RollbackRepositories mocks base method.
+func (m *MockRepositoryManager) RollbackRepositories() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RollbackRepositories")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RollbackRepositories indicates an expected call of RollbackRepositories.
+func (mr *MockRepositoryManagerMockRecorder) RollbackRepositories() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RollbackRepositories", reflect.TypeOf((*MockRepositoryManager)(nil).RollbackRepositories))
+}
+
+// MockPolicyChecker is a mock of PolicyChecker interface.
+type MockPolicyChecker struct {
+	ctrl     *gomock.Controller
+	recorder *MockPolicyCheckerMockRecorder
+}
+
+// MockPolicyCheckerMockRecorder is the mock recorder for MockPolicyChecker.
+type MockPolicyCheckerMockRecorder struct {
+	mock *MockPolicyChecker
+}
+
+// NewMockPolicyChecker creates a new mock instance.
+func NewMockPolicyChecker(ctrl *gomock.Controller) *MockPolicyChecker {
+	mock := &MockPolicyChecker{ctrl: ctrl}
+	mock.recorder = &MockPolicyCheckerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPolicyChecker) EXPECT() *MockPolicyCheckerMockRecorder {
+	return m.recorder
+}
+
+// CheckRepositories mocks base method.
+func (m *MockPolicyChecker) CheckRepositories(repos []string) ([]api.PolicyFinding, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckRepositories", repos)
+	ret0, _ := ret[0].([]api.PolicyFinding)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckRepositories indicates an expected call of CheckRepositories.
+func (mr *MockPolicyCheckerMockRecorder) CheckRepositories(repos interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckRepositories", reflect.TypeOf((*MockPolicyChecker)(nil).CheckRepositories), repos)
+}
+
+// Policy mocks base method.
+func (m *MockPolicyChecker) Policy() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Policy")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Policy indicates an expected call of Policy.
+func (mr *MockPolicyCheckerMockRecorder) Policy() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureRepositoriesEnabled", reflect.TypeOf((*MockRepositoryManager)(nil).EnsureRepositoriesEnabled))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Policy", reflect.TypeOf((*MockPolicyChecker)(nil).Policy))
 }
@@ -34,6 +34,34 @@ func (m *MockPackageManager) EXPECT() *MockPackageManagerMockRecorder {
 	return m.recorder
 }
 
+// CreateRepository mocks base method.
+func (m *MockPackageManager) CreateRepository(dir string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRepository", dir)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateRepository indicates an expected call of CreateRepository.
+func (mr *MockPackageManagerMockRecorder) CreateRepository(dir interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRepository", reflect.TypeOf((*MockPackageManager)(nil).CreateRepository), dir)
+}
+
+// Download mocks base method.
+func (m *MockPackageManager) Download(packages []string, destDir string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Download", packages, destDir)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Download indicates an expected call of Download.
+func (mr *MockPackageManagerMockRecorder) Download(packages, destDir interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Download", reflect.TypeOf((*MockPackageManager)(nil).Download), packages, destDir)
+}
+
 // Install mocks base method.
 func (m *MockPackageManager) Install(packages []string, batchMode, dryRun bool) error {
 	m.ctrl.T.Helper()
@@ -49,18 +77,18 @@ func (mr *MockPackageManagerMockRecorder) Install(packages, batchMode, dryRun in
 }
 
 // ListAvailablePackages mocks base method.
-func (m *MockPackageManager) ListAvailablePackages() ([]api.PackageInfo, error) {
+func (m *MockPackageManager) ListAvailablePackages(query api.PackageQuery) ([]api.PackageInfo, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListAvailablePackages")
+	ret := m.ctrl.Call(m, "ListAvailablePackages", query)
 	ret0, _ := ret[0].([]api.PackageInfo)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ListAvailablePackages indicates an expected call of ListAvailablePackages.
-func (mr *MockPackageManagerMockRecorder) ListAvailablePackages() *gomock.Call {
+func (mr *MockPackageManagerMockRecorder) ListAvailablePackages(query interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAvailablePackages", reflect.TypeOf((*MockPackageManager)(nil).ListAvailablePackages))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAvailablePackages", reflect.TypeOf((*MockPackageManager)(nil).ListAvailablePackages), query)
 }
 
 // ListInstalledPackages mocks base method.
@@ -77,19 +77,33 @@ func (mr *MockProviderMockRecorder) GetName() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetName", reflect.TypeOf((*MockProvider)(nil).GetName))
 }
 
+// GetRequiredChannels mocks base method.
+func (m *MockProvider) GetRequiredChannels(osVersion int, arch string) []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRequiredChannels", osVersion, arch)
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// GetRequiredChannels indicates an expected call of GetRequiredChannels.
+func (mr *MockProviderMockRecorder) GetRequiredChannels(osVersion, arch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRequiredChannels", reflect.TypeOf((*MockProvider)(nil).GetRequiredChannels), osVersion, arch)
+}
+
 // Install mocks base method.
-func (m *MockProvider) Install(drivers []api.DriverID) ([]string, error) {
+func (m *MockProvider) Install(drivers []api.DriverID, multilib bool) ([]string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Install", drivers)
+	ret := m.ctrl.Call(m, "Install", drivers, multilib)
 	ret0, _ := ret[0].([]string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Install indicates an expected call of Install.
-func (mr *MockProviderMockRecorder) Install(drivers interface{}) *gomock.Call {
+func (mr *MockProviderMockRecorder) Install(drivers, multilib interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Install", reflect.TypeOf((*MockProvider)(nil).Install), drivers)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Install", reflect.TypeOf((*MockProvider)(nil).Install), drivers, multilib)
 }
 
 // ListAvailable mocks base method.
@@ -2,17 +2,48 @@ package amd
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/mizdebsk/rhel-drivers/internal/api"
 	"github.com/mizdebsk/rhel-drivers/internal/log"
+	"github.com/mizdebsk/rhel-drivers/internal/sysinfo"
 )
 
 type prov struct {
-	PM api.PackageManager
+	PM      api.PackageManager
+	RM      api.RepositoryManager
+	SysInfo sysinfo.SysInfo
 }
 
 var _ api.Provider = (*prov)(nil)
 
+var packageQuery = api.PackageQuery{
+	Names: []string{"kmod-amdgpu*"},
+}
+
+// packageQuery returns query for kernel module packages in repositories
+// of required channels.
+func (p *prov) packageQuery() api.PackageQuery {
+	query := packageQuery
+	query.Repos = api.RepositoryIDs(p.RM, p.GetRequiredChannels(p.SysInfo.OsVersion, p.SysInfo.Arch))
+	return query
+}
+
+// Kernel module package for the standard kernel.  Modules for kernel
+// variants are packaged separately with variant appended to the name,
+// eg. "kmod-amdgpu-rt".
+const kmodName = "kmod-amdgpu"
+
+// Kernel modules from Red Hat repositories are assumed to be signed with
+// Red Hat key, as shipped in RHEL; packages are not inspected.
+// Signature of modules from other repositories is unknown.
+const kmodSigner = "Red Hat"
+
+func isKmod(name string) bool {
+	return name == kmodName || strings.HasPrefix(name, kmodName+"-")
+}
+
 func (p *prov) GetID() string {
 	return "amdgpu"
 }
@@ -20,17 +51,100 @@ func (p *prov) GetName() string {
 	return "AMD GPU"
 }
 
-func NewProvider(pm api.PackageManager) api.Provider {
+// GetRequiredChannels returns channels with kernel module packages,
+// which are built only for x86_64.
+func (p *prov) GetRequiredChannels(osVersion int, arch string) []string {
+	if arch != "x86_64" {
+		return nil
+	}
+	return []string{api.ChannelBaseOS, api.ChannelAppStream, api.ChannelExtensions}
+}
+
+func NewProvider(pm api.PackageManager, rm api.RepositoryManager, systemInfo sysinfo.SysInfo) api.Provider {
 	return &prov{
-		PM: pm,
+		PM:      pm,
+		RM:      rm,
+		SysInfo: systemInfo,
 	}
 }
 
-func (p *prov) Install(drivers []api.DriverID) ([]string, error) {
+func (p *prov) Install(drivers []api.DriverID, multilib bool) ([]string, error) {
 	if len(drivers) == 0 {
 		return []string{}, nil
 	}
-	return []string{"kmod-amdgpu"}, nil
+	all, err := p.PM.ListAvailablePackages(p.packageQuery())
+	if err != nil {
+		return []string{}, fmt.Errorf("failed to list available packages: %w", err)
+	}
+	name, err := p.selectKmod(all)
+	if err != nil {
+		return []string{}, err
+	}
+	what := "kernel module package " + name
+	if !p.fromRedHat(all, name) {
+		p.SysInfo.ModuleSigning.CheckUnverifiedModule(what)
+		return []string{name}, nil
+	}
+	if err := p.SysInfo.ModuleSigning.CheckModule(what, kmodSigner); err != nil {
+		return []string{}, err
+	}
+	return []string{name}, nil
+}
+
+// fromRedHat reports whether package with given name is available only
+// from Red Hat repositories of required channels.
+func (p *prov) fromRedHat(all []api.PackageInfo, name string) bool {
+	ids := api.RedHatRepositoryIDs(p.RM, p.GetRequiredChannels(p.SysInfo.OsVersion, p.SysInfo.Arch))
+	found := false
+	for _, pkg := range all {
+		if pkg.Name != name {
+			continue
+		}
+		if !slices.Contains(ids, pkg.Repo) {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// selectKmod returns name of kernel module package for the variant of
+// kernel drivers are installed for, falling back to the standard one.
+func (p *prov) selectKmod(all []api.PackageInfo) (string, error) {
+	kernel, err := p.targetKernel()
+	if err != nil {
+		return "", err
+	}
+	if kernel.Variant == "" {
+		return kmodName, nil
+	}
+	name := kmodName + "-" + kernel.Variant
+	for _, pkg := range all {
+		if pkg.Name == name {
+			return name, nil
+		}
+	}
+	log.Warnf("no prebuilt %s kernel module found for kernel %s, the driver will work only with the standard kernel", p.GetName(), kernel)
+	return kmodName, nil
+}
+
+// targetKernel returns the kernel drivers are installed for.  Bootable
+// container images and alternate roots don't run their own kernel, so
+// the newest kernel installed there is used instead.
+func (p *prov) targetKernel() (sysinfo.Kernel, error) {
+	kernel := p.SysInfo.TargetKernel()
+	if kernel.Release != "" && !p.SysInfo.BootcBuild {
+		return kernel, nil
+	}
+	inst, err := p.PM.ListInstalledPackages()
+	if err != nil {
+		return kernel, fmt.Errorf("failed to list installed packages: %w", err)
+	}
+	if installed := api.NewestInstalledKernel(inst); installed.Release != "" {
+		log.Logf("installing for the newest installed kernel %s", installed)
+		return installed, nil
+	}
+	return kernel, nil
 }
 
 func (p *prov) ListInstalled() ([]api.DriverID, error) {
@@ -40,7 +154,7 @@ func (p *prov) ListInstalled() ([]api.DriverID, error) {
 	}
 	var drivers []api.PackageInfo
 	for _, pkg := range all {
-		if pkg.Name == "kmod-amdgpu" {
+		if isKmod(pkg.Name) {
 			drivers = append(drivers, pkg)
 		}
 	}
@@ -59,17 +173,30 @@ func (p *prov) Remove(drivers []api.DriverID) ([]string, error) {
 	if len(drivers) == 0 {
 		return []string{}, nil
 	}
-	return []string{"kmod-amdgpu"}, nil
+	inst, err := p.PM.ListInstalledPackages()
+	if err != nil {
+		return []string{}, fmt.Errorf("failed to list installed packages: %w", err)
+	}
+	var pkgs []string
+	for _, pkg := range inst {
+		if isKmod(pkg.Name) && !slices.Contains(pkgs, pkg.Name) {
+			pkgs = append(pkgs, pkg.Name)
+		}
+	}
+	if len(pkgs) == 0 {
+		return []string{kmodName}, nil
+	}
+	return pkgs, nil
 }
 
 func (p *prov) ListAvailable() ([]api.DriverID, error) {
-	all, err := p.PM.ListAvailablePackages()
+	all, err := p.PM.ListAvailablePackages(p.packageQuery())
 	if err != nil {
 		return []api.DriverID{}, err
 	}
 	var drivers []api.PackageInfo
 	for _, pkg := range all {
-		if pkg.Name == "kmod-amdgpu" {
+		if pkg.Name == kmodName {
 			drivers = append(drivers, pkg)
 		}
 	}
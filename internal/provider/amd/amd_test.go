@@ -0,0 +1,142 @@
+package amd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/mocks"
+	"github.com/mizdebsk/rhel-drivers/internal/sysinfo"
+)
+
+var testPackages = []api.PackageInfo{
+	{Name: "kmod-amdgpu", Version: "6.14.14", Release: "1.el10", Arch: "x86_64", Repo: "extensions"},
+	{Name: "kmod-amdgpu-rt", Version: "6.14.14", Release: "1.el10", Arch: "x86_64", Repo: "extensions"},
+}
+
+func TestInstall(t *testing.T) {
+	tests := []struct {
+		name      string
+		kernel    string
+		bootc     bool
+		installed []api.PackageInfo
+		signing   sysinfo.ModuleSigning
+		custom    bool
+		want      []string
+		expectErr bool
+	}{
+		{
+			name:   "standard kernel",
+			kernel: "6.12.0-55.el10.x86_64",
+			want:   []string{"kmod-amdgpu"},
+		},
+		{
+			name: "unknown kernel",
+			want: []string{"kmod-amdgpu"},
+		},
+		{
+			name:      "installed variant in image",
+			kernel:    "6.12.0-55.el10.x86_64",
+			bootc:     true,
+			installed: []api.PackageInfo{{Name: "kernel-rt-core", Version: "6.12.0", Release: "55.el10", Arch: "x86_64"}},
+			want:      []string{"kmod-amdgpu-rt"},
+		},
+		{
+			name:   "variant with module",
+			kernel: "6.12.0-55.el10.x86_64+rt",
+			want:   []string{"kmod-amdgpu-rt"},
+		},
+		{
+			name:   "variant without module",
+			kernel: "6.12.0-55.el10.x86_64+debug",
+			want:   []string{"kmod-amdgpu"},
+		},
+		{
+			name:    "secure boot with trusted key",
+			kernel:  "6.12.0-55.el10.x86_64",
+			signing: sysinfo.ModuleSigning{SecureBoot: true, TrustedKeys: []string{"Red Hat Enterprise Linux kernel signing key"}},
+			want:    []string{"kmod-amdgpu"},
+		},
+		{
+			name:      "secure boot without trusted key",
+			kernel:    "6.12.0-55.el10.x86_64",
+			signing:   sysinfo.ModuleSigning{SecureBoot: true},
+			expectErr: true,
+		},
+		{
+			name:    "secure boot with module from custom repository",
+			kernel:  "6.12.0-55.el10.x86_64",
+			signing: sysinfo.ModuleSigning{SecureBoot: true},
+			custom:  true,
+			want:    []string{"kmod-amdgpu"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			pm := mocks.NewMockPackageManager(ctrl)
+			pm.EXPECT().ListAvailablePackages(gomock.Any()).Return(testPackages, nil).AnyTimes()
+			pm.EXPECT().ListInstalledPackages().Return(tt.installed, nil).AnyTimes()
+			rm := mocks.NewMockRepositoryManager(ctrl)
+			rm.EXPECT().ListRepositories(gomock.Any()).Return([]api.RepositoryStatus{
+				{Channel: api.ChannelExtensions, ID: "extensions", Defined: true, Enabled: true, RedHat: !tt.custom},
+			}, nil).AnyTimes()
+			p := NewProvider(pm, rm, sysinfo.SysInfo{Arch: "x86_64", Kernel: sysinfo.ParseKernel(tt.kernel), BootcBuild: tt.bootc, ModuleSigning: tt.signing})
+			got, err := p.Install([]api.DriverID{{ProviderID: "amdgpu", Version: "latest"}}, false)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Install() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if !tt.expectErr && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Install() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemove(t *testing.T) {
+	tests := []struct {
+		name      string
+		installed []api.PackageInfo
+		want      []string
+	}{
+		{
+			name:      "installed modules",
+			installed: testPackages,
+			want:      []string{"kmod-amdgpu", "kmod-amdgpu-rt"},
+		},
+		{
+			name: "nothing installed",
+			want: []string{"kmod-amdgpu"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			pm := mocks.NewMockPackageManager(ctrl)
+			pm.EXPECT().ListInstalledPackages().Return(tt.installed, nil)
+			p := NewProvider(pm, nil, sysinfo.SysInfo{Arch: "x86_64"})
+			got, err := p.Remove([]api.DriverID{{ProviderID: "amdgpu", Version: "latest"}})
+			if err != nil {
+				t.Fatalf("Remove() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Remove() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetRequiredChannels(t *testing.T) {
+	p := NewProvider(nil, nil, sysinfo.SysInfo{})
+	want := []string{api.ChannelBaseOS, api.ChannelAppStream, api.ChannelExtensions}
+	if got := p.GetRequiredChannels(10, "x86_64"); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetRequiredChannels(10, x86_64) = %v, want %v", got, want)
+	}
+	if got := p.GetRequiredChannels(10, "aarch64"); got != nil {
+		t.Errorf("GetRequiredChannels(10, aarch64) = %v, want none", got)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/mizdebsk/rhel-drivers/internal/log"
@@ -24,6 +25,11 @@ const (
 type autoDetector struct {
 	compatibleGPUs string
 	modaliasRoot   string
+	// Set when running in a virtual machine, where GPUs may be vGPUs.
+	virtual bool
+	// Subsystem device IDs of NVIDIA boards of each chip, filled in
+	// when compatible devices are loaded.
+	boards map[string][]string
 }
 
 func newAutoDetector() autoDetector {
@@ -51,9 +57,11 @@ func (d *autoDetector) Detect() (bool, error) {
 
 type compatibleGPUFile struct {
 	Chips []struct {
-		Name     string   `json:"name"`
-		DevID    string   `json:"devid"`
-		Features []string `json:"features"`
+		Name        string   `json:"name"`
+		DevID       string   `json:"devid"`
+		SubVendorID string   `json:"subvendorid"`
+		SubDevID    string   `json:"subdevid"`
+		Features    []string `json:"features"`
 	} `json:"chips"`
 }
 
@@ -73,6 +81,7 @@ func (d *autoDetector) loadCompatibleDevices() (map[string]string, error) {
 	}
 
 	result := make(map[string]string)
+	d.boards = make(map[string][]string)
 	for _, chip := range s.Chips {
 		if !hasFeature(chip.Features, "kernelopen") {
 			continue
@@ -82,6 +91,11 @@ func (d *autoDetector) loadCompatibleDevices() (map[string]string, error) {
 			continue
 		}
 		result[dev] = chip.Name
+		if normalizeDevID(chip.SubVendorID) == nvidiaVendor {
+			if sub := normalizeDevID(chip.SubDevID); sub != "" {
+				d.boards[dev] = append(d.boards[dev], sub)
+			}
+		}
 	}
 
 	return result, nil
@@ -175,14 +189,16 @@ func (d *autoDetector) isCompatibleNvidiaDisplay(modalias string, compatible map
 	//bus := m[1]
 	vendor := strings.ToLower(m[2])
 	device := strings.ToLower(m[3])
-	//subVendor := strings.ToLower(m[4])
-	//subDevice := strings.ToLower(m[5])
+	subVendor := strings.ToLower(m[4])
+	subDevice := strings.ToLower(m[5])
 	baseClass := strings.ToLower(m[6])
 	//subClass := strings.ToLower(m[7])
 	//iface := strings.ToLower(m[8])
 
 	vendor4 := vendor[len(vendor)-4:]
 	device4 := device[len(device)-4:]
+	subVendor4 := subVendor[len(subVendor)-4:]
+	subDevice4 := subDevice[len(subDevice)-4:]
 
 	if vendor4 != nvidiaVendor {
 		return false
@@ -192,8 +208,22 @@ func (d *autoDetector) isCompatibleNvidiaDisplay(modalias string, compatible map
 	}
 
 	if name, ok := compatible[device4]; ok {
+		if d.isVGPU(device4, subVendor4, subDevice4) {
+			log.Warnf("found NVIDIA vGPU on %s (subsystem %s:%s), which needs NVIDIA vGPU guest (GRID) driver, not provided by RHEL",
+				name, subVendor4, subDevice4)
+			return false
+		}
 		log.Infof("found compatible hardware: %s", name)
 		return true
 	}
 	return false
 }
+
+// isVGPU reports whether device is a vGPU assigned to this virtual
+// machine.  vGPUs have device ID of the physical GPU, but NVIDIA
+// subsystem ID identifying vGPU type, which is not any of NVIDIA boards
+// with the GPU.
+func (d *autoDetector) isVGPU(device, subVendor, subDevice string) bool {
+	boards := d.boards[device]
+	return d.virtual && subVendor == nvidiaVendor && len(boards) > 0 && !slices.Contains(boards, subDevice)
+}
@@ -178,3 +178,33 @@ func TestDetect_WithA100SysfsAndHwdata(t *testing.T) {
 		t.Fatalf("Detect() = %v, want true (A100 PCIe 40GB should be detected)", found)
 	}
 }
+
+func TestDetect_VGPUGuest(t *testing.T) {
+	tests := []struct {
+		name    string
+		sysfs   string
+		virtual bool
+		want    bool
+	}{
+		{name: "PassedThrough", sysfs: "testdata/sysfs-A100-PCIE-40GB", virtual: true, want: true},
+		{name: "VGPU", sysfs: "testdata/sysfs-A100-vGPU", virtual: true, want: false},
+		// Unknown board on bare metal can't be a vGPU.
+		{name: "UnknownBoard", sysfs: "testdata/sysfs-A100-vGPU", virtual: false, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newAutoDetector()
+			d.compatibleGPUs = "testdata/hwdata.json"
+			d.modaliasRoot = tt.sysfs
+			d.virtual = tt.virtual
+			found, err := d.Detect()
+			if err != nil {
+				t.Fatalf("Detect() error = %v", err)
+			}
+			if found != tt.want {
+				t.Fatalf("Detect() = %v, want %v", found, tt.want)
+			}
+		})
+	}
+}
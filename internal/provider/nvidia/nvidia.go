@@ -2,24 +2,55 @@ package nvidia
 
 import (
 	"fmt"
+	"slices"
 	"sort"
+	"strings"
 
 	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/log"
 	"github.com/mizdebsk/rhel-drivers/internal/rpmver"
+	"github.com/mizdebsk/rhel-drivers/internal/sysinfo"
 )
 
 type prov struct {
-	PM api.PackageManager
+	PM      api.PackageManager
+	RM      api.RepositoryManager
+	SysInfo sysinfo.SysInfo
 }
 
 var _ api.Provider = (*prov)(nil)
 
-func NewProvider(pm api.PackageManager) api.Provider {
+func NewProvider(pm api.PackageManager, rm api.RepositoryManager, systemInfo sysinfo.SysInfo) api.Provider {
 	return &prov{
-		PM: pm,
+		PM:      pm,
+		RM:      rm,
+		SysInfo: systemInfo,
 	}
 }
 
+var packageQuery = api.PackageQuery{
+	Names: []string{"nvidia-driver*", "nvidia-fabric*", kmodPrefix + "*"},
+}
+
+// packageQuery returns query for driver packages in repositories of
+// required channels.
+func (p *prov) packageQuery() api.PackageQuery {
+	query := packageQuery
+	query.Repos = api.RepositoryIDs(p.RM, p.GetRequiredChannels(p.SysInfo.OsVersion, p.SysInfo.Arch))
+	return query
+}
+
+// kmodPrefix starts names of precompiled kernel module packages, which
+// continue with driver version and release of the kernel the module was
+// built for, eg. "kmod-nvidia-580.95.05-6.12.0-55" or, for kernel
+// variants, "kmod-nvidia-580.95.05-6.12.0-55+rt".
+const kmodPrefix = "kmod-nvidia-"
+
+// Precompiled kernel modules from Red Hat repositories are assumed to be
+// signed with Red Hat key, as shipped in RHEL; packages are not
+// inspected.  Signature of modules from other repositories is unknown.
+const kmodSigner = "Red Hat"
+
 func (p *prov) GetID() string {
 	return "nvidia"
 }
@@ -27,12 +58,50 @@ func (p *prov) GetName() string {
 	return "NVIDIA"
 }
 
-func selectPackagesByNameVersion(all []api.PackageInfo, name, version string, latest bool) []string {
+// GetRequiredChannels returns channels with driver packages, which are
+// built only for x86_64 and aarch64.  CUDA libraries installed along
+// with drivers come from Supplementary on RHEL 10 and later.
+func (p *prov) GetRequiredChannels(osVersion int, arch string) []string {
+	if arch != "x86_64" && arch != "aarch64" {
+		return nil
+	}
+	channels := []string{api.ChannelBaseOS, api.ChannelAppStream, api.ChannelExtensions}
+	if osVersion >= 10 {
+		channels = append(channels, api.ChannelSupplementary)
+	}
+	return channels
+}
+
+func matchesDriver(pkg api.PackageInfo, name string, driver api.DriverID) bool {
+	return pkg.Name == name && matchesEVR(pkg, driver)
+}
+
+// matchesEVR reports whether package is of given driver build, ignoring
+// its name.
+func matchesEVR(pkg api.PackageInfo, driver api.DriverID) bool {
+	return driver.Matches(api.DriverID{
+		ProviderID: driver.ProviderID,
+		Epoch:      pkg.Epoch,
+		Version:    pkg.Version,
+		Release:    pkg.Release,
+	})
+}
+
+// archMatches reports whether package architecture is one of given
+// arches.  Nil arches match any binary package.
+func archMatches(arch string, arches []string) bool {
+	if arch == "src" || arch == "nosrc" {
+		return false
+	}
+	return arches == nil || slices.Contains(arches, arch)
+}
+
+func selectPackagesByNameVersion(all []api.PackageInfo, name string, driver api.DriverID, arches []string, latest bool) []string {
 	if latest {
 		var best *api.PackageInfo
 		for _, pkg := range all {
-			if pkg.Name == name && pkg.Version == version {
-				if best == nil || rpmver.CompareEVR(best.Epoch, best.Version, best.Release, pkg.Epoch, pkg.Version, pkg.Release) < 0 {
+			if matchesDriver(pkg, name, driver) && archMatches(pkg.Arch, arches) {
+				if best == nil || best.EVR().Compare(pkg.EVR()) < 0 {
 					best = &pkg
 				}
 			}
@@ -44,7 +113,8 @@ func selectPackagesByNameVersion(all []api.PackageInfo, name, version string, la
 	} else {
 		var filtered []string
 		for _, pkg := range all {
-			if pkg.Name == name && pkg.Version == version {
+			// The same build may be available from several repositories.
+			if matchesDriver(pkg, name, driver) && archMatches(pkg.Arch, arches) && !slices.Contains(filtered, pkg.NEVRA()) {
 				filtered = append(filtered, pkg.NEVRA())
 			}
 		}
@@ -52,20 +122,35 @@ func selectPackagesByNameVersion(all []api.PackageInfo, name, version string, la
 	}
 }
 
-func packageSetVersioned(all []api.PackageInfo, version string, latest bool) []string {
+func selectPackageSet(all []api.PackageInfo, names []string, driver api.DriverID, arches []string, latest bool) []string {
 	var pkgs []string
+	for _, name := range names {
+		selectedPkgs := selectPackagesByNameVersion(all, name, driver, arches, latest)
+		pkgs = append(pkgs, selectedPkgs...)
+	}
+	return pkgs
+}
+
+func packageSetVersioned(all []api.PackageInfo, driver api.DriverID, arches []string, latest bool) []string {
 	names := []string{
 		"nvidia-driver",
 		"nvidia-driver-cuda",
 		"nvidia-fabricmanager",
 		"nvidia-fabric-manager-devel",
 	}
-	for _, name := range names {
-		selectedPkgs := selectPackagesByNameVersion(all, name, version, latest)
-		pkgs = append(pkgs, selectedPkgs...)
+	return selectPackageSet(all, names, driver, arches, latest)
+}
+
+// packageSetMultilib lists 32-bit compatibility libraries, installed
+// only on request.
+func packageSetMultilib(all []api.PackageInfo, driver api.DriverID, arches []string, latest bool) []string {
+	names := []string{
+		"nvidia-driver-libs",
+		"nvidia-driver-cuda-libs",
 	}
-	return pkgs
+	return selectPackageSet(all, names, driver, arches, latest)
 }
+
 func packageSetStatic() []string {
 	return []string{
 		"cublasmp",
@@ -78,10 +163,20 @@ func packageSetStatic() []string {
 	}
 }
 
-func (p *prov) Install(driversInst []api.DriverID) ([]string, error) {
+// nativeArches lists architectures of packages that can be installed
+// on this system, not counting multilib.
+func (p *prov) nativeArches() []string {
+	return []string{p.SysInfo.Arch, "noarch"}
+}
+
+func (p *prov) Install(driversInst []api.DriverID, multilib bool) ([]string, error) {
 	if p.PM == nil {
 		return []string{}, fmt.Errorf("no PackageManager provided for NVIDIA installer")
 	}
+	if n := len(p.SysInfo.VGPUDevices); n > 0 {
+		return []string{}, fmt.Errorf("this host has %d NVIDIA vGPU mediated devices, which are managed by NVIDIA vGPU Manager; "+
+			"installing the standard driver would conflict with it", n)
+	}
 	driversAvail, err := p.ListAvailable()
 	if err != nil {
 		return []string{}, err
@@ -92,47 +187,168 @@ func (p *prov) Install(driversInst []api.DriverID) ([]string, error) {
 outer:
 	for _, driver := range driversInst {
 		for _, av := range driversAvail {
-			if driver.Version == av.Version {
+			if driver.Matches(av) {
 				continue outer
 			}
 		}
 		return []string{}, fmt.Errorf("no NVIDIA driver version %s available", driver)
 	}
 
-	avail, err := p.PM.ListAvailablePackages()
+	avail, err := p.PM.ListAvailablePackages(p.packageQuery())
 	if err != nil {
 		return []string{}, fmt.Errorf("failed to list available packages: %w", err)
 	}
 
+	multilibArch := ""
+	if multilib {
+		multilibArch = sysinfo.MultilibArch(p.SysInfo.Arch)
+		if multilibArch == "" {
+			log.Warnf("32-bit compatibility libraries are not available on %s", p.SysInfo.Arch)
+		}
+	}
+
 	var pkgs []string
 	for _, driver := range driversInst {
-		pkgs = append(pkgs, packageSetVersioned(avail, driver.Version, true)...)
+		driver, ok := p.pinDriver(avail, driver)
+		if !ok {
+			return []string{}, fmt.Errorf("no NVIDIA driver packages for %s found for version %s", p.SysInfo.Arch, driver.FullVersion())
+		}
+		selected := packageSetVersioned(avail, driver, p.nativeArches(), true)
+		kmod, err := p.selectKmod(avail, driver)
+		if err != nil {
+			return []string{}, err
+		}
+		if kmod != "" {
+			selected = append(selected, kmod)
+		}
+		if multilibArch != "" {
+			selected = append(selected, packageSetMultilib(avail, driver, []string{multilibArch}, true)...)
+		}
+		for _, nevra := range selected {
+			log.Infof("selected package %s", nevra)
+		}
+		pkgs = append(pkgs, selected...)
 	}
 	pkgs = append(pkgs, packageSetStatic()...)
 	return pkgs, nil
 }
 
+// pinDriver returns given driver with epoch and release of the newest
+// matching nvidia-driver build for native architecture, so that all
+// packages, including 32-bit compatibility libraries, are selected from
+// the same build.
+func (p *prov) pinDriver(all []api.PackageInfo, driver api.DriverID) (api.DriverID, bool) {
+	var best *api.PackageInfo
+	for _, pkg := range all {
+		if matchesDriver(pkg, "nvidia-driver", driver) && archMatches(pkg.Arch, p.nativeArches()) {
+			if best == nil || best.EVR().Compare(pkg.EVR()) < 0 {
+				best = &pkg
+			}
+		}
+	}
+	if best == nil {
+		return driver, false
+	}
+	driver.Epoch = best.Epoch
+	driver.Release = best.Release
+	return driver, true
+}
+
+// selectKmod returns the newest precompiled kernel module of given
+// driver build for the kernel drivers are installed for, or empty
+// string if there is none.  The module must come from the same build
+// as the driver, so that userspace and kernel parts are not mixed.  Error is returned if the kernel would
+// refuse to load the module.
+func (p *prov) selectKmod(all []api.PackageInfo, driver api.DriverID) (string, error) {
+	kernel, err := p.targetKernel()
+	if err != nil {
+		return "", err
+	}
+	if kernel.Release == "" {
+		log.Debugf("kernel is not known, not selecting NVIDIA kernel module")
+		return "", nil
+	}
+	var best *api.PackageInfo
+	for _, pkg := range all {
+		built, ok := kmodKernel(pkg.Name, driver)
+		if ok && matchesEVR(pkg, driver) && archMatches(pkg.Arch, p.nativeArches()) && kernel.Matches(built) {
+			if best == nil || best.EVR().Compare(pkg.EVR()) < 0 {
+				best = &pkg
+			}
+		}
+	}
+	if best == nil {
+		log.Warnf("no prebuilt NVIDIA kernel module version %s found for kernel %s", driver.FullVersion(), kernel)
+		// Module will have to be built locally, without signature.
+		return "", p.SysInfo.ModuleSigning.CheckModule("locally built NVIDIA kernel module", "")
+	}
+	what := "kernel module package " + best.NEVRA()
+	if !p.fromRedHat(all, best.NEVRA()) {
+		p.SysInfo.ModuleSigning.CheckUnverifiedModule(what)
+		return best.NEVRA(), nil
+	}
+	if err := p.SysInfo.ModuleSigning.CheckModule(what, kmodSigner); err != nil {
+		return "", err
+	}
+	return best.NEVRA(), nil
+}
+
+// fromRedHat reports whether package with given NEVRA is available only
+// from Red Hat repositories of required channels.
+func (p *prov) fromRedHat(all []api.PackageInfo, nevra string) bool {
+	ids := api.RedHatRepositoryIDs(p.RM, p.GetRequiredChannels(p.SysInfo.OsVersion, p.SysInfo.Arch))
+	for _, pkg := range all {
+		if pkg.NEVRA() == nevra && !slices.Contains(ids, pkg.Repo) {
+			return false
+		}
+	}
+	return len(ids) > 0
+}
+
+// targetKernel returns the kernel drivers are installed for.  Bootable
+// container images and alternate roots don't run their own kernel, so
+// the newest kernel installed there is used instead.
+func (p *prov) targetKernel() (sysinfo.Kernel, error) {
+	kernel := p.SysInfo.TargetKernel()
+	if kernel.Release != "" && !p.SysInfo.BootcBuild {
+		return kernel, nil
+	}
+	inst, err := p.PM.ListInstalledPackages()
+	if err != nil {
+		return kernel, fmt.Errorf("failed to list installed packages: %w", err)
+	}
+	if installed := api.NewestInstalledKernel(inst); installed.Release != "" {
+		log.Logf("installing for the newest installed kernel %s", installed)
+		return installed, nil
+	}
+	return kernel, nil
+}
+
+// kmodKernel returns release of the kernel given package is a kernel
+// module for, if it is a module of given driver.
+func kmodKernel(name string, driver api.DriverID) (string, bool) {
+	return strings.CutPrefix(name, kmodPrefix+driver.Version+"-")
+}
+
+func installedKmods(inst []api.PackageInfo, driver api.DriverID) []string {
+	var pkgs []string
+	for _, pkg := range inst {
+		if _, ok := kmodKernel(pkg.Name, driver); ok {
+			pkgs = append(pkgs, pkg.NEVRA())
+		}
+	}
+	return pkgs
+}
+
 func (p *prov) ListAvailable() ([]api.DriverID, error) {
-	all, err := p.PM.ListAvailablePackages()
+	all, err := p.PM.ListAvailablePackages(p.packageQuery())
 	if err != nil {
 		return nil, fmt.Errorf("failed to list available packages: %w", err)
 	}
 	if len(all) == 0 {
 		return nil, nil
 	}
-	var drivers []api.DriverID
-	for _, pkg := range all {
-		if pkg.Name == "nvidia-driver" {
-			drivers = append(drivers, api.DriverID{
-				ProviderID: p.GetID(),
-				Version:    pkg.Version,
-			})
-		}
-	}
-	sort.Slice(drivers, func(i, j int) bool {
-		return rpmver.RpmVersionCompare(drivers[i].Version, drivers[j].Version) > 0
-	})
-	return drivers, nil
+	return p.driversFromPackages(all), nil
 }
 
 func (p *prov) ListInstalled() ([]api.DriverID, error) {
@@ -144,19 +360,49 @@ func (p *prov) ListInstalled() ([]api.DriverID, error) {
 	if err != nil {
 		return []api.DriverID{}, err
 	}
+	return p.driversFromPackages(all), nil
+}
+
+// driversFromPackages lists driver builds for native architecture,
+// newest first, one entry per EVR with all repositories it is available
+// from.  Architecture specific builds take precedence over noarch ones.
+func (p *prov) driversFromPackages(all []api.PackageInfo) []api.DriverID {
 	var drivers []api.DriverID
+	var repos [][]string
+	arches := p.nativeArches()
 	for _, pkg := range all {
-		if pkg.Name == "nvidia-driver" {
+		if pkg.Name != "nvidia-driver" || !archMatches(pkg.Arch, arches) {
+			continue
+		}
+		i := slices.IndexFunc(drivers, func(d api.DriverID) bool {
+			return rpmver.CompareEVR(d.Epoch, d.Version, d.Release, pkg.Epoch, pkg.Version, pkg.Release) == 0
+		})
+		if i < 0 {
 			drivers = append(drivers, api.DriverID{
 				ProviderID: p.GetID(),
+				Epoch:      pkg.Epoch,
 				Version:    pkg.Version,
+				Release:    pkg.Release,
+				Arch:       pkg.Arch,
 			})
+			repos = append(repos, nil)
+			i = len(drivers) - 1
+		} else if slices.Index(arches, pkg.Arch) < slices.Index(arches, drivers[i].Arch) {
+			drivers[i].Arch = pkg.Arch
+		}
+		if pkg.Repo != "" && !slices.Contains(repos[i], pkg.Repo) {
+			repos[i] = append(repos[i], pkg.Repo)
 		}
 	}
+	for i := range drivers {
+		slices.Sort(repos[i])
+		drivers[i].Repo = strings.Join(repos[i], ",")
+	}
 	sort.Slice(drivers, func(i, j int) bool {
-		return rpmver.RpmVersionCompare(drivers[i].Version, drivers[j].Version) > 0
+		a, b := drivers[i], drivers[j]
+		return rpmver.CompareEVR(a.Epoch, a.Version, a.Release, b.Epoch, b.Version, b.Release) > 0
 	})
-	return drivers, nil
+	return drivers
 }
 
 func (p *prov) Remove(drivers []api.DriverID) ([]string, error) {
@@ -168,7 +414,9 @@ func (p *prov) Remove(drivers []api.DriverID) ([]string, error) {
 
 	var pkgs []string
 	for _, driver := range drivers {
-		pkgs = append(pkgs, packageSetVersioned(inst, driver.Version, false)...)
+		pkgs = append(pkgs, packageSetVersioned(inst, driver, nil, false)...)
+		pkgs = append(pkgs, packageSetMultilib(inst, driver, nil, false)...)
+		pkgs = append(pkgs, installedKmods(inst, driver)...)
 	}
 	pkgs = append(pkgs, packageSetStatic()...)
 	return pkgs, nil
@@ -176,5 +424,6 @@ func (p *prov) Remove(drivers []api.DriverID) ([]string, error) {
 
 func (p *prov) DetectHardware() (bool, error) {
 	detector := newAutoDetector()
+	detector.virtual = p.SysInfo.Hypervisor != ""
 	return detector.Detect()
 }
@@ -0,0 +1,452 @@
+package nvidia
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/mocks"
+	"github.com/mizdebsk/rhel-drivers/internal/sysinfo"
+)
+
+var testPackages = []api.PackageInfo{
+	{Name: "nvidia-driver", Epoch: "3", Version: "570.172.08", Release: "1.el10", Arch: "x86_64", Repo: "base"},
+	{Name: "nvidia-driver", Epoch: "3", Version: "580.95.05", Release: "1.el10", Arch: "x86_64", Repo: "base"},
+	{Name: "nvidia-driver", Epoch: "3", Version: "580.95.05", Release: "1.el10", Arch: "src", Repo: "base-source"},
+	{Name: "nvidia-driver", Epoch: "3", Version: "580.95.05", Release: "3.el10", Arch: "aarch64", Repo: "base"},
+	{Name: "nvidia-driver", Epoch: "3", Version: "580.95.05", Release: "2.el10", Arch: "x86_64", Repo: "updates"},
+	{Name: "nvidia-driver", Epoch: "3", Version: "580.95.05", Release: "2.el10", Arch: "x86_64", Repo: "mirror"},
+	{Name: "nvidia-driver-cuda", Epoch: "3", Version: "580.95.05", Release: "1.el10", Arch: "x86_64", Repo: "base"},
+	{Name: "nvidia-driver-cuda", Epoch: "3", Version: "580.95.05", Release: "2.el10", Arch: "x86_64", Repo: "updates"},
+	{Name: "nvidia-driver-cuda", Epoch: "3", Version: "580.95.05", Release: "2.el10", Arch: "i686", Repo: "updates"},
+	{Name: "nvidia-driver-libs", Epoch: "3", Version: "580.95.05", Release: "2.el10", Arch: "x86_64", Repo: "updates"},
+	{Name: "nvidia-driver-libs", Epoch: "3", Version: "580.95.05", Release: "2.el10", Arch: "i686", Repo: "updates"},
+	// 32-bit libraries of a build without native packages.
+	{Name: "nvidia-driver-libs", Epoch: "3", Version: "580.95.05", Release: "4.el10", Arch: "i686", Repo: "updates-testing"},
+	{Name: "kmod-nvidia-580.95.05-6.12.0-55", Epoch: "3", Version: "580.95.05", Release: "1.el10", Arch: "x86_64", Repo: "base"},
+	{Name: "kmod-nvidia-580.95.05-6.12.0-55", Epoch: "3", Version: "580.95.05", Release: "2.el10", Arch: "x86_64", Repo: "updates"},
+	{Name: "kmod-nvidia-580.95.05-6.12.0-55+rt", Epoch: "3", Version: "580.95.05", Release: "2.el10", Arch: "x86_64", Repo: "updates"},
+	{Name: "kmod-nvidia-580.95.05-6.12.0-61", Epoch: "3", Version: "580.95.05", Release: "2.el10", Arch: "x86_64", Repo: "updates"},
+	{Name: "kmod-nvidia-570.172.08-6.12.0-55", Epoch: "3", Version: "570.172.08", Release: "1.el10", Arch: "x86_64", Repo: "base"},
+}
+
+func TestDriversFromPackages(t *testing.T) {
+	p := &prov{SysInfo: sysinfo.SysInfo{Arch: "x86_64"}}
+	got := p.driversFromPackages(testPackages)
+	want := []api.DriverID{
+		{ProviderID: "nvidia", Epoch: "3", Version: "580.95.05", Release: "2.el10", Arch: "x86_64", Repo: "mirror,updates"},
+		{ProviderID: "nvidia", Epoch: "3", Version: "580.95.05", Release: "1.el10", Arch: "x86_64", Repo: "base"},
+		{ProviderID: "nvidia", Epoch: "3", Version: "570.172.08", Release: "1.el10", Arch: "x86_64", Repo: "base"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("driversFromPackages() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDriversFromPackagesEpochAndArch(t *testing.T) {
+	p := &prov{SysInfo: sysinfo.SysInfo{Arch: "x86_64"}}
+	got := p.driversFromPackages([]api.PackageInfo{
+		{Name: "nvidia-driver", Epoch: "3", Version: "580.95.05", Release: "1.el10", Arch: "noarch", Repo: "updates"},
+		{Name: "nvidia-driver", Epoch: "3", Version: "580.95.05", Release: "1.el10", Arch: "x86_64", Repo: "base"},
+		{Name: "nvidia-driver", Epoch: "2", Version: "580.95.05", Release: "1.el10", Arch: "x86_64", Repo: "old"},
+		{Name: "nvidia-driver", Version: "580.95.05", Release: "1.el10", Arch: "x86_64", Repo: "older"},
+		{Name: "nvidia-driver", Epoch: "0", Version: "580.95.05", Release: "1.el10", Arch: "x86_64", Repo: "base"},
+	})
+	want := []api.DriverID{
+		{ProviderID: "nvidia", Epoch: "3", Version: "580.95.05", Release: "1.el10", Arch: "x86_64", Repo: "base,updates"},
+		{ProviderID: "nvidia", Epoch: "2", Version: "580.95.05", Release: "1.el10", Arch: "x86_64", Repo: "old"},
+		{ProviderID: "nvidia", Version: "580.95.05", Release: "1.el10", Arch: "x86_64", Repo: "base,older"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("driversFromPackages() = %+v, want %+v", got, want)
+	}
+}
+
+func TestPackageSetVersioned(t *testing.T) {
+	native := []string{"x86_64", "noarch"}
+	tests := []struct {
+		name   string
+		driver api.DriverID
+		arches []string
+		latest bool
+		want   []string
+	}{
+		{
+			name:   "latest release",
+			driver: api.DriverID{Version: "580.95.05"},
+			arches: native,
+			latest: true,
+			want: []string{
+				"nvidia-driver-3:580.95.05-2.el10.x86_64",
+				"nvidia-driver-cuda-3:580.95.05-2.el10.x86_64",
+			},
+		},
+		{
+			name:   "specific release",
+			driver: api.DriverID{Version: "580.95.05", Release: "1.el10"},
+			arches: native,
+			latest: true,
+			want: []string{
+				"nvidia-driver-3:580.95.05-1.el10.x86_64",
+				"nvidia-driver-cuda-3:580.95.05-1.el10.x86_64",
+			},
+		},
+		{
+			name:   "foreign arch only",
+			driver: api.DriverID{Version: "580.95.05", Release: "3.el10"},
+			arches: native,
+			latest: true,
+			want:   nil,
+		},
+		{
+			name:   "all matching any arch",
+			driver: api.DriverID{Version: "580.95.05", Release: "2.el10"},
+			arches: nil,
+			latest: false,
+			want: []string{
+				"nvidia-driver-3:580.95.05-2.el10.x86_64",
+				"nvidia-driver-cuda-3:580.95.05-2.el10.x86_64",
+				"nvidia-driver-cuda-3:580.95.05-2.el10.i686",
+			},
+		},
+		{
+			name:   "other epoch",
+			driver: api.DriverID{Epoch: "2", Version: "580.95.05", Release: "2.el10"},
+			arches: native,
+			latest: true,
+			want:   nil,
+		},
+		{
+			name:   "no match",
+			driver: api.DriverID{Version: "580.95.05", Release: "9.el10"},
+			arches: native,
+			latest: true,
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := packageSetVersioned(testPackages, tt.driver, tt.arches, tt.latest)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("packageSetVersioned() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstall(t *testing.T) {
+	static := packageSetStatic()
+	tests := []struct {
+		name      string
+		arch      string
+		kernel    string
+		bootc     bool
+		installed []api.PackageInfo
+		signing   sysinfo.ModuleSigning
+		custom    bool
+		vgpus     []string
+		drivers   []api.DriverID
+		multilib  bool
+		want      []string
+		expectErr bool
+	}{
+		{
+			name:    "native only",
+			arch:    "x86_64",
+			drivers: []api.DriverID{{ProviderID: "nvidia", Version: "580.95.05"}},
+			want: append([]string{
+				"nvidia-driver-3:580.95.05-2.el10.x86_64",
+				"nvidia-driver-cuda-3:580.95.05-2.el10.x86_64",
+			}, static...),
+		},
+		{
+			name:     "with multilib",
+			arch:     "x86_64",
+			drivers:  []api.DriverID{{ProviderID: "nvidia", Version: "580.95.05"}},
+			multilib: true,
+			want: append([]string{
+				"nvidia-driver-3:580.95.05-2.el10.x86_64",
+				"nvidia-driver-cuda-3:580.95.05-2.el10.x86_64",
+				"nvidia-driver-libs-3:580.95.05-2.el10.i686",
+			}, static...),
+		},
+		{
+			name:     "multilib of specific release",
+			arch:     "x86_64",
+			drivers:  []api.DriverID{{ProviderID: "nvidia", Version: "580.95.05", Release: "1.el10"}},
+			multilib: true,
+			want: append([]string{
+				"nvidia-driver-3:580.95.05-1.el10.x86_64",
+				"nvidia-driver-cuda-3:580.95.05-1.el10.x86_64",
+			}, static...),
+		},
+		{
+			name:     "multilib unsupported on arch",
+			arch:     "aarch64",
+			drivers:  []api.DriverID{{ProviderID: "nvidia", Version: "580.95.05"}},
+			multilib: true,
+			want: append([]string{
+				"nvidia-driver-3:580.95.05-3.el10.aarch64",
+			}, static...),
+		},
+		{
+			name:    "kernel module",
+			arch:    "x86_64",
+			kernel:  "6.12.0-55.el10.x86_64",
+			drivers: []api.DriverID{{ProviderID: "nvidia", Version: "580.95.05"}},
+			want: append([]string{
+				"nvidia-driver-3:580.95.05-2.el10.x86_64",
+				"nvidia-driver-cuda-3:580.95.05-2.el10.x86_64",
+				"kmod-nvidia-580.95.05-6.12.0-55-3:580.95.05-2.el10.x86_64",
+			}, static...),
+		},
+		{
+			name:    "kernel module of specific release",
+			arch:    "x86_64",
+			kernel:  "6.12.0-55.el10.x86_64",
+			drivers: []api.DriverID{{ProviderID: "nvidia", Version: "580.95.05", Release: "1.el10"}},
+			want: append([]string{
+				"nvidia-driver-3:580.95.05-1.el10.x86_64",
+				"nvidia-driver-cuda-3:580.95.05-1.el10.x86_64",
+				"kmod-nvidia-580.95.05-6.12.0-55-3:580.95.05-1.el10.x86_64",
+			}, static...),
+		},
+		{
+			name:    "no kernel module of specific release",
+			arch:    "x86_64",
+			kernel:  "6.12.0-61.el10.x86_64",
+			drivers: []api.DriverID{{ProviderID: "nvidia", Version: "580.95.05", Release: "1.el10"}},
+			want: append([]string{
+				"nvidia-driver-3:580.95.05-1.el10.x86_64",
+				"nvidia-driver-cuda-3:580.95.05-1.el10.x86_64",
+			}, static...),
+		},
+		{
+			name:      "kernel module for installed kernel in image",
+			arch:      "x86_64",
+			kernel:    "6.12.0-61.el10.x86_64+debug",
+			bootc:     true,
+			installed: []api.PackageInfo{{Name: "kernel-core", Version: "6.12.0", Release: "55.el10", Arch: "x86_64"}},
+			drivers:   []api.DriverID{{ProviderID: "nvidia", Version: "580.95.05"}},
+			want: append([]string{
+				"nvidia-driver-3:580.95.05-2.el10.x86_64",
+				"nvidia-driver-cuda-3:580.95.05-2.el10.x86_64",
+				"kmod-nvidia-580.95.05-6.12.0-55-3:580.95.05-2.el10.x86_64",
+			}, static...),
+		},
+		{
+			name:    "kernel module for variant",
+			arch:    "x86_64",
+			kernel:  "6.12.0-55.el10.x86_64+rt",
+			drivers: []api.DriverID{{ProviderID: "nvidia", Version: "580.95.05"}},
+			want: append([]string{
+				"nvidia-driver-3:580.95.05-2.el10.x86_64",
+				"nvidia-driver-cuda-3:580.95.05-2.el10.x86_64",
+				"kmod-nvidia-580.95.05-6.12.0-55+rt-3:580.95.05-2.el10.x86_64",
+			}, static...),
+		},
+		{
+			name:    "no kernel module for variant",
+			arch:    "x86_64",
+			kernel:  "6.12.0-61.el10.x86_64+debug",
+			drivers: []api.DriverID{{ProviderID: "nvidia", Version: "580.95.05"}},
+			want: append([]string{
+				"nvidia-driver-3:580.95.05-2.el10.x86_64",
+				"nvidia-driver-cuda-3:580.95.05-2.el10.x86_64",
+			}, static...),
+		},
+		{
+			name:    "secure boot with trusted module",
+			arch:    "x86_64",
+			kernel:  "6.12.0-55.el10.x86_64",
+			signing: sysinfo.ModuleSigning{SecureBoot: true, TrustedKeys: []string{"Red Hat Enterprise Linux kernel signing key"}},
+			drivers: []api.DriverID{{ProviderID: "nvidia", Version: "580.95.05"}},
+			want: append([]string{
+				"nvidia-driver-3:580.95.05-2.el10.x86_64",
+				"nvidia-driver-cuda-3:580.95.05-2.el10.x86_64",
+				"kmod-nvidia-580.95.05-6.12.0-55-3:580.95.05-2.el10.x86_64",
+			}, static...),
+		},
+		{
+			name:      "secure boot with untrusted module",
+			arch:      "x86_64",
+			kernel:    "6.12.0-55.el10.x86_64",
+			signing:   sysinfo.ModuleSigning{SecureBoot: true, TrustedKeys: []string{"AlmaLinux kernel signing key"}},
+			drivers:   []api.DriverID{{ProviderID: "nvidia", Version: "580.95.05"}},
+			expectErr: true,
+		},
+		{
+			name:    "secure boot with module from custom repository",
+			arch:    "x86_64",
+			kernel:  "6.12.0-55.el10.x86_64",
+			signing: sysinfo.ModuleSigning{SecureBoot: true, TrustedKeys: []string{"AlmaLinux kernel signing key"}},
+			custom:  true,
+			drivers: []api.DriverID{{ProviderID: "nvidia", Version: "580.95.05"}},
+			want: append([]string{
+				"nvidia-driver-3:580.95.05-2.el10.x86_64",
+				"nvidia-driver-cuda-3:580.95.05-2.el10.x86_64",
+				"kmod-nvidia-580.95.05-6.12.0-55-3:580.95.05-2.el10.x86_64",
+			}, static...),
+		},
+		{
+			name:      "secure boot without prebuilt module",
+			arch:      "x86_64",
+			kernel:    "6.12.0-61.el10.x86_64+debug",
+			signing:   sysinfo.ModuleSigning{SecureBoot: true, TrustedKeys: []string{"Red Hat Enterprise Linux kernel signing key"}},
+			drivers:   []api.DriverID{{ProviderID: "nvidia", Version: "580.95.05"}},
+			expectErr: true,
+		},
+		{
+			name:      "vgpu host",
+			arch:      "x86_64",
+			vgpus:     []string{"c0a7b6f4-9e2d-4d1a-8f3b-6e5c4d3b2a19"},
+			drivers:   []api.DriverID{{ProviderID: "nvidia", Version: "580.95.05"}},
+			expectErr: true,
+		},
+		{
+			name:      "no builds for arch",
+			arch:      "ppc64le",
+			drivers:   []api.DriverID{{ProviderID: "nvidia", Version: "580.95.05"}},
+			expectErr: true,
+		},
+		{
+			name:      "version not available",
+			arch:      "x86_64",
+			drivers:   []api.DriverID{{ProviderID: "nvidia", Version: "999.99.99"}},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			pm := mocks.NewMockPackageManager(ctrl)
+			pm.EXPECT().ListAvailablePackages(gomock.Any()).Return(testPackages, nil).AnyTimes()
+			pm.EXPECT().ListInstalledPackages().Return(tt.installed, nil).AnyTimes()
+			rm := mocks.NewMockRepositoryManager(ctrl)
+			rm.EXPECT().ListRepositories(gomock.Any()).Return([]api.RepositoryStatus{
+				{Channel: api.ChannelAppStream, ID: "base", Defined: true, Enabled: true, RedHat: !tt.custom},
+				{Channel: api.ChannelExtensions, ID: "updates", Defined: true, Enabled: true, RedHat: !tt.custom},
+			}, nil).AnyTimes()
+			p := NewProvider(pm, rm, sysinfo.SysInfo{Arch: tt.arch, Kernel: sysinfo.ParseKernel(tt.kernel), BootcBuild: tt.bootc, ModuleSigning: tt.signing, VGPUDevices: tt.vgpus})
+			got, err := p.Install(tt.drivers, tt.multilib)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Install() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if !tt.expectErr && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Install() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveKernelModules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	pm := mocks.NewMockPackageManager(ctrl)
+	pm.EXPECT().ListInstalledPackages().Return(testPackages, nil)
+	p := NewProvider(pm, nil, sysinfo.SysInfo{Arch: "x86_64"})
+	got, err := p.Remove([]api.DriverID{{ProviderID: "nvidia", Version: "570.172.08", Release: "1.el10"}})
+	if err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	want := append([]string{
+		"nvidia-driver-3:570.172.08-1.el10.x86_64",
+		"kmod-nvidia-570.172.08-6.12.0-55-3:570.172.08-1.el10.x86_64",
+	}, packageSetStatic()...)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Remove() = %v, want %v", got, want)
+	}
+}
+
+func TestGetRequiredChannels(t *testing.T) {
+	tests := []struct {
+		name      string
+		osVersion int
+		arch      string
+		want      []string
+	}{
+		{
+			name:      "RHEL 10",
+			osVersion: 10,
+			arch:      "x86_64",
+			want:      []string{api.ChannelBaseOS, api.ChannelAppStream, api.ChannelExtensions, api.ChannelSupplementary},
+		},
+		{
+			name:      "RHEL 9",
+			osVersion: 9,
+			arch:      "aarch64",
+			want:      []string{api.ChannelBaseOS, api.ChannelAppStream, api.ChannelExtensions},
+		},
+		{
+			name:      "unsupported arch",
+			osVersion: 10,
+			arch:      "s390x",
+			want:      nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProvider(nil, nil, sysinfo.SysInfo{})
+			if got := p.GetRequiredChannels(tt.osVersion, tt.arch); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetRequiredChannels(%d, %q) = %v, want %v", tt.osVersion, tt.arch, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListAvailableRepos(t *testing.T) {
+	channels := []string{api.ChannelBaseOS, api.ChannelAppStream, api.ChannelExtensions, api.ChannelSupplementary}
+	tests := []struct {
+		name     string
+		statuses []api.RepositoryStatus
+		repos    []string
+	}{
+		{
+			name: "ChannelRepositories",
+			statuses: []api.RepositoryStatus{
+				{Channel: api.ChannelBaseOS, ID: "rhel-10-for-x86_64-baseos-rpms", Defined: true, Enabled: true},
+				{Channel: api.ChannelAppStream, ID: "rhel-10-for-x86_64-appstream-rpms", Defined: true, Enabled: true},
+				{Channel: api.ChannelExtensions, ID: "rhel-10-for-x86_64-extensions-rpms", Defined: true},
+				{Channel: api.ChannelSupplementary, ID: "rhel-10-for-x86_64-supplementary-rpms", Defined: true},
+			},
+			repos: []string{"rhel-10-for-x86_64-baseos-rpms", "rhel-10-for-x86_64-appstream-rpms",
+				"rhel-10-for-x86_64-extensions-rpms", "rhel-10-for-x86_64-supplementary-rpms"},
+		},
+		{
+			name: "EnabledByDefault",
+			statuses: []api.RepositoryStatus{
+				{Channel: api.ChannelBaseOS},
+				{Channel: api.ChannelAppStream},
+				{Channel: api.ChannelExtensions, ID: "extras-common", Defined: true, Enabled: true},
+				{Channel: api.ChannelSupplementary, Unavailable: true},
+			},
+		},
+		{
+			name: "NotDefined",
+			statuses: []api.RepositoryStatus{
+				{Channel: api.ChannelBaseOS, ID: "rhel-10-for-x86_64-baseos-rpms", Defined: true, Enabled: true},
+				{Channel: api.ChannelAppStream, ID: "rhel-10-for-x86_64-appstream-rpms", Defined: true, Enabled: true},
+				{Channel: api.ChannelExtensions, ID: "rhel-10-for-x86_64-extensions-rpms"},
+				{Channel: api.ChannelSupplementary, ID: "rhel-10-for-x86_64-supplementary-rpms", Defined: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			pm := mocks.NewMockPackageManager(ctrl)
+			rm := mocks.NewMockRepositoryManager(ctrl)
+			rm.EXPECT().ListRepositories(channels).Return(tt.statuses, nil)
+			query := api.PackageQuery{Names: packageQuery.Names, Repos: tt.repos}
+			pm.EXPECT().ListAvailablePackages(query).Return(testPackages, nil)
+			p := NewProvider(pm, rm, sysinfo.SysInfo{OsVersion: 10, Arch: "x86_64"})
+			if _, err := p.ListAvailable(); err != nil {
+				t.Fatalf("ListAvailable() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package repostate
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+)
+
+// DefaultPath is where repositories enabled by rhel-drivers are
+// recorded, so that they can be disabled again with "repos --restore".
+const DefaultPath = "/var/lib/rhel-drivers/enabled-repos"
+
+// Journal keeps track of repositories that were disabled before
+// rhel-drivers enabled them.  Repositories enabled in the current run
+// are remembered separately, so that they can be rolled back if
+// installation does not go through.
+type Journal struct {
+	path    string
+	session []string
+}
+
+func NewJournal(path string) *Journal {
+	return &Journal{path: path}
+}
+
+// Record notes that given repositories were enabled.
+func (j *Journal) Record(ids []string) error {
+	for _, id := range ids {
+		if !slices.Contains(j.session, id) {
+			j.session = append(j.session, id)
+		}
+	}
+	all, err := j.load()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if !slices.Contains(all, id) {
+			all = append(all, id)
+		}
+	}
+	return j.save(all)
+}
+
+// Rollback disables repositories enabled in the current run.
+func (j *Journal) Rollback(disable func(ids []string) error) error {
+	if len(j.session) == 0 {
+		return nil
+	}
+	log.Infof("restoring repository configuration")
+	if err := disable(j.session); err != nil {
+		return fmt.Errorf("failed to disable repositories: %w", err)
+	}
+	all, err := j.load()
+	if err != nil {
+		return err
+	}
+	all = slices.DeleteFunc(all, func(id string) bool { return slices.Contains(j.session, id) })
+	j.session = nil
+	return j.save(all)
+}
+
+// Restore disables all repositories recorded as enabled by rhel-drivers.
+func (j *Journal) Restore(disable func(ids []string) error) error {
+	all, err := j.load()
+	if err != nil {
+		return err
+	}
+	if len(all) == 0 {
+		log.Infof("no repositories were enabled by rhel-drivers")
+		return nil
+	}
+	log.Infof("disabling repositories enabled by rhel-drivers: %s", strings.Join(all, ", "))
+	if err := disable(all); err != nil {
+		return fmt.Errorf("failed to disable repositories: %w", err)
+	}
+	j.session = nil
+	return j.save(nil)
+}
+
+func (j *Journal) load() ([]string, error) {
+	f, err := os.Open(j.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read repository state: %w", err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Warnf("failed to close file %s: %v", j.path, err)
+		}
+	}()
+	var ids []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if id := strings.TrimSpace(scanner.Text()); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read repository state: %w", err)
+	}
+	return ids, nil
+}
+
+func (j *Journal) save(ids []string) error {
+	if len(ids) == 0 {
+		if err := os.Remove(j.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove repository state: %w", err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(j.path), 0o755); err != nil {
+		return fmt.Errorf("failed to save repository state: %w", err)
+	}
+	data := strings.Join(ids, "\n") + "\n"
+	if err := os.WriteFile(j.path, []byte(data), 0o644); err != nil {
+		return fmt.Errorf("failed to save repository state: %w", err)
+	}
+	return nil
+}
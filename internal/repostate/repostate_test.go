@@ -0,0 +1,89 @@
+package repostate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "enabled-repos")
+
+	// Earlier run enabled one repository.
+	if err := NewJournal(path).Record([]string{"extras"}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	j := NewJournal(path)
+	if err := j.Record([]string{"crb", "extras"}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	assertState(t, path, "extras\ncrb\n")
+
+	var disabled []string
+	disable := func(ids []string) error {
+		disabled = append(disabled, ids...)
+		return nil
+	}
+
+	// Rollback only touches repositories enabled in this run.
+	if err := j.Rollback(disable); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	if expected := []string{"crb", "extras"}; !reflect.DeepEqual(disabled, expected) {
+		t.Errorf("Rollback() disabled %v, expected %v", disabled, expected)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected state file to be removed, got %v", err)
+	}
+
+	disabled = nil
+	if err := j.Rollback(disable); err != nil || disabled != nil {
+		t.Errorf("second Rollback() disabled %v, error %v", disabled, err)
+	}
+}
+
+func TestJournalRestore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "enabled-repos")
+	if err := NewJournal(path).Record([]string{"crb"}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if err := NewJournal(path).Record([]string{"extras"}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	j := NewJournal(path)
+	if err := j.Restore(func(ids []string) error { return fmt.Errorf("dnf failed") }); err == nil {
+		t.Errorf("expected Restore() to fail")
+	}
+	assertState(t, path, "crb\nextras\n")
+
+	var disabled []string
+	if err := j.Restore(func(ids []string) error { disabled = ids; return nil }); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if expected := []string{"crb", "extras"}; !reflect.DeepEqual(disabled, expected) {
+		t.Errorf("Restore() disabled %v, expected %v", disabled, expected)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected state file to be removed, got %v", err)
+	}
+
+	disabled = nil
+	if err := j.Restore(func(ids []string) error { disabled = ids; return nil }); err != nil || disabled != nil {
+		t.Errorf("Restore() with empty state disabled %v, error %v", disabled, err)
+	}
+}
+
+func assertState(t *testing.T, path, expected string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read state: %v", err)
+	}
+	if string(data) != expected {
+		t.Errorf("state = %q, expected %q", data, expected)
+	}
+}
@@ -0,0 +1,271 @@
+package rhsm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+)
+
+const (
+	dbusName      = "com.redhat.RHSM1"
+	dbusPath      = "/com/redhat/RHSM1"
+	dbusErrorName = "com.redhat.RHSM1.Error"
+	// Empty locale makes RHSM use its default for error messages.
+	dbusLocale = ""
+)
+
+// ServiceError is an error reported by the RHSM D-Bus service.
+type ServiceError struct {
+	Method    string
+	Exception string
+	Message   string
+}
+
+func (e *ServiceError) Error() string {
+	if e.Exception != "" {
+		return fmt.Sprintf("%s: %s (%s)", e.Method, e.Message, e.Exception)
+	}
+	return fmt.Sprintf("%s: %s", e.Method, e.Message)
+}
+
+// Overall entitlement status of systems using Simple Content Access.
+const entitlementStatusSCA = "Disabled"
+
+// entitlementStatus is the overall status reported by RHSM.
+type entitlementStatus struct {
+	Status string `json:"status"`
+	Valid  bool   `json:"valid"`
+}
+
+// rhsmClient is the part of RHSM D-Bus API used to enable repositories.
+type rhsmClient interface {
+	ConsumerUUID() (string, error)
+	ConfigValue(name string) (string, error)
+	EntitlementStatus() (entitlementStatus, error)
+	RepoOverrides() (map[string]map[string]string, error)
+	AddRepoOverrides(overrides map[string]map[string]string) error
+	RemoveRepoOverrides(options map[string][]string) error
+	Close()
+}
+
+// dbusRepoMgr enables RHEL repositories through RHSM D-Bus API rather
+// than subscription-manager command, by adding repository overrides
+// just like "subscription-manager repos --enable" does.
+type dbusRepoMgr struct {
+	repoMgr
+	connect func() (rhsmClient, error)
+}
+
+var _ api.RepositoryManager = (*dbusRepoMgr)(nil)
+
+func (rm *dbusRepoMgr) EnsureRepositoriesEnabled(channels []string) error {
+	if !rm.systemInfo.IsRhel {
+		return rm.repoMgr.EnsureRepositoriesEnabled(channels)
+	}
+	log.Logf("detected RHEL %s", rm.systemInfo.Release())
+
+	client, err := rm.connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to RHSM D-Bus service: %w", err)
+	}
+	defer client.Close()
+
+	uuid, err := client.ConsumerUUID()
+	if err != nil {
+		return fmt.Errorf("failed to check registration: %w", err)
+	}
+	if uuid == "" {
+		return &SubscriptionError{Problem: NotRegistered}
+	}
+	log.Logf("system is registered as consumer %s", uuid)
+
+	manageRepos, err := client.ConfigValue("rhsm.manage_repos")
+	if err != nil {
+		return fmt.Errorf("failed to read RHSM configuration: %w", err)
+	}
+	if manageRepos == "0" {
+		return fmt.Errorf("repository management is disabled in RHSM configuration (manage_repos = 0)")
+	}
+
+	status, err := client.EntitlementStatus()
+	if err != nil {
+		return fmt.Errorf("failed to get entitlement status: %w", err)
+	}
+	log.Logf("entitlement status: %s", status.Status)
+	if !status.Valid && status.Status != entitlementStatusSCA {
+		return &SubscriptionError{Problem: NotEntitled}
+	}
+
+	overrides, err := client.RepoOverrides()
+	if err != nil {
+		return fmt.Errorf("failed to get repository overrides: %w", err)
+	}
+	toEnable, err := rm.disabledRepos(channels, overrides)
+	if err != nil {
+		return err
+	}
+	if len(toEnable) == 0 {
+		log.Logf("all required repositories are already enabled")
+		return nil
+	}
+
+	add := make(map[string]map[string]string, len(toEnable))
+	for _, repo := range toEnable {
+		add[repo] = map[string]string{"enabled": "1"}
+	}
+	log.Logf("adding repository overrides through RHSM D-Bus API")
+	if err := client.AddRepoOverrides(add); err != nil {
+		return fmt.Errorf("failed to enable repositories: %w", err)
+	}
+
+	log.Logf("repositories were enabled successfully")
+	if err := rm.journal.Record(toEnable); err != nil {
+		log.Warnf("%v", err)
+	}
+	return nil
+}
+
+// ListRepositories returns status of repositories that provide given
+// channels, taking into account overrides known to RHSM, which may not
+// have been written to redhat.repo yet.
+func (rm *dbusRepoMgr) ListRepositories(channels []string) ([]api.RepositoryStatus, error) {
+	if !rm.systemInfo.IsRhel {
+		return rm.repoMgr.ListRepositories(channels)
+	}
+	client, err := rm.connect()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to RHSM D-Bus service: %w", err)
+	}
+	defer client.Close()
+	overrides, err := client.RepoOverrides()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repository overrides: %w", err)
+	}
+	return rm.channelStatus(channels, overrides)
+}
+
+func (rm *dbusRepoMgr) RollbackRepositories() error {
+	return rm.journal.Rollback(rm.disableRepos)
+}
+
+func (rm *dbusRepoMgr) RestoreRepositories() error {
+	return rm.journal.Restore(rm.disableRepos)
+}
+
+func (rm *dbusRepoMgr) disableRepos(repos []string) error {
+	client, err := rm.connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to RHSM D-Bus service: %w", err)
+	}
+	defer client.Close()
+	// Journaled repositories were disabled before the override enabling
+	// them was added, so removing it is enough to restore their state.
+	options := make(map[string][]string, len(repos))
+	for _, repo := range repos {
+		options[repo] = []string{"enabled"}
+	}
+	log.Logf("removing repository overrides through RHSM D-Bus API")
+	return client.RemoveRepoOverrides(options)
+}
+
+type dbusClient struct {
+	conn *dbus.Conn
+}
+
+func connectSystemBus() (rhsmClient, error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, err
+	}
+	return &dbusClient{conn: conn}, nil
+}
+
+func (c *dbusClient) Close() {
+	if err := c.conn.Close(); err != nil {
+		log.Warnf("failed to close D-Bus connection: %v", err)
+	}
+}
+
+// call invokes method of RHSM object, all of which take locale as the
+// last argument, and stores its result in ret unless it is nil.
+func (c *dbusClient) call(object, method string, ret any, args ...any) error {
+	name := dbusName + "." + object + "." + method
+	log.Debugf("calling D-Bus method %s", name)
+	obj := c.conn.Object(dbusName, dbus.ObjectPath(dbusPath+"/"+object))
+	call := obj.Call(name, 0, append(args, dbusLocale)...)
+	if call.Err != nil {
+		return serviceError(object+"."+method, call.Err)
+	}
+	if ret == nil {
+		return nil
+	}
+	return call.Store(ret)
+}
+
+// serviceError converts errors raised by RHSM, which carry JSON with
+// exception name and message, to ServiceError.
+func serviceError(method string, err error) error {
+	var dbusErr dbus.Error
+	if !errors.As(err, &dbusErr) {
+		return err
+	}
+	svcErr := &ServiceError{Method: method, Message: dbusErr.Error()}
+	if dbusErr.Name == dbusErrorName && len(dbusErr.Body) > 0 {
+		if body, ok := dbusErr.Body[0].(string); ok {
+			var details struct {
+				Exception string `json:"exception"`
+				Message   string `json:"message"`
+			}
+			if json.Unmarshal([]byte(body), &details) == nil && details.Message != "" {
+				svcErr.Exception = details.Exception
+				svcErr.Message = details.Message
+			}
+		}
+	}
+	return svcErr
+}
+
+func (c *dbusClient) ConsumerUUID() (string, error) {
+	var uuid string
+	err := c.call("Consumer", "GetUuid", &uuid)
+	return uuid, err
+}
+
+func (c *dbusClient) ConfigValue(name string) (string, error) {
+	var value dbus.Variant
+	if err := c.call("Config", "Get", &value, name); err != nil {
+		return "", err
+	}
+	return fmt.Sprint(value.Value()), nil
+}
+
+func (c *dbusClient) EntitlementStatus() (entitlementStatus, error) {
+	var status entitlementStatus
+	var data string
+	if err := c.call("Entitlement", "GetStatus", &data, ""); err != nil {
+		return status, err
+	}
+	if err := json.Unmarshal([]byte(data), &status); err != nil {
+		return status, fmt.Errorf("invalid entitlement status: %w", err)
+	}
+	return status, nil
+}
+
+func (c *dbusClient) RepoOverrides() (map[string]map[string]string, error) {
+	var overrides map[string]map[string]string
+	err := c.call("RepoOverride", "GetOverrides", &overrides)
+	return overrides, err
+}
+
+func (c *dbusClient) AddRepoOverrides(overrides map[string]map[string]string) error {
+	return c.call("RepoOverride", "AddOverrides", nil, overrides)
+}
+
+func (c *dbusClient) RemoveRepoOverrides(options map[string][]string) error {
+	return c.call("RepoOverride", "RemoveOverrides", nil, options)
+}
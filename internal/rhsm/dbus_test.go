@@ -0,0 +1,507 @@
+package rhsm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/repostate"
+	"github.com/mizdebsk/rhel-drivers/internal/sysinfo"
+)
+
+// fakeRHSM stands in for RHSM D-Bus service.
+type fakeRHSM struct {
+	uuid      string
+	config    map[string]string
+	status    entitlementStatus
+	overrides map[string]map[string]string
+	added     map[string]map[string]string
+	removed   map[string][]string
+	addErr    error
+	closed    bool
+}
+
+func (f *fakeRHSM) ConsumerUUID() (string, error) { return f.uuid, nil }
+
+func (f *fakeRHSM) ConfigValue(name string) (string, error) {
+	value, ok := f.config[name]
+	if !ok {
+		return "", serviceError("Config.Get", dbus.Error{
+			Name: dbusErrorName,
+			Body: []any{`{"exception": "NoOptionError", "message": "No option '` + name + `'"}`},
+		})
+	}
+	return value, nil
+}
+
+func (f *fakeRHSM) EntitlementStatus() (entitlementStatus, error) { return f.status, nil }
+
+func (f *fakeRHSM) RepoOverrides() (map[string]map[string]string, error) { return f.overrides, nil }
+
+func (f *fakeRHSM) AddRepoOverrides(overrides map[string]map[string]string) error {
+	f.added = overrides
+	return f.addErr
+}
+
+func (f *fakeRHSM) RemoveRepoOverrides(options map[string][]string) error {
+	f.removed = options
+	return nil
+}
+
+func (f *fakeRHSM) Close() { f.closed = true }
+
+func TestDBusRepoMgr(t *testing.T) {
+	sparc := sysinfo.SysInfo{IsRhel: true, OsVersion: 5, Arch: "sparc"}
+	registered := func() *fakeRHSM {
+		return &fakeRHSM{
+			uuid:   "c3b1e7a2-0d4f-4a8e-9a51-6f2b8d1e4c70",
+			config: map[string]string{"rhsm.manage_repos": "1"},
+			status: entitlementStatus{Status: "Current", Valid: true},
+		}
+	}
+	tests := []struct {
+		name          string
+		sysInfo       sysinfo.SysInfo
+		fake          func() *fakeRHSM
+		connectErr    error
+		expectAdded   map[string]map[string]string
+		expectProblem *SubscriptionProblem
+		anyErr        bool
+	}{
+		{
+			name:    "EnableRepos",
+			sysInfo: sparc,
+			fake:    registered,
+			expectAdded: map[string]map[string]string{
+				"rhel-5-for-sparc-baseos-rpms":        {"enabled": "1"},
+				"rhel-5-for-sparc-appstream-rpms":     {"enabled": "1"},
+				"rhel-5-for-sparc-extensions-rpms":    {"enabled": "1"},
+				"rhel-5-for-sparc-supplementary-rpms": {"enabled": "1"},
+			},
+		},
+		{
+			name:    "OverridesAlreadyPresent",
+			sysInfo: sparc,
+			fake: func() *fakeRHSM {
+				f := registered()
+				f.overrides = map[string]map[string]string{
+					"rhel-5-for-sparc-baseos-rpms":     {"enabled": "1"},
+					"rhel-5-for-sparc-appstream-rpms":  {"enabled": "1", "gpgcheck": "0"},
+					"rhel-5-for-sparc-extensions-rpms": {"enabled": "0"},
+				}
+				return f
+			},
+			expectAdded: map[string]map[string]string{
+				"rhel-5-for-sparc-extensions-rpms":    {"enabled": "1"},
+				"rhel-5-for-sparc-supplementary-rpms": {"enabled": "1"},
+			},
+		},
+		{
+			name:    "ReposAlreadyEnabled",
+			sysInfo: sysinfo.SysInfo{IsRhel: true, OsVersion: 10, Arch: "x86_64"},
+			fake:    registered,
+		},
+		{
+			name:    "OverrideDisablesRepo",
+			sysInfo: sysinfo.SysInfo{IsRhel: true, OsVersion: 10, Arch: "x86_64"},
+			fake: func() *fakeRHSM {
+				f := registered()
+				f.overrides = map[string]map[string]string{
+					"rhel-10-for-x86_64-supplementary-rpms": {"enabled": "0"},
+				}
+				return f
+			},
+			expectAdded: map[string]map[string]string{
+				"rhel-10-for-x86_64-supplementary-rpms": {"enabled": "1"},
+			},
+		},
+		{
+			name:    "NotRegistered",
+			sysInfo: sparc,
+			fake: func() *fakeRHSM {
+				f := registered()
+				f.uuid = ""
+				return f
+			},
+			expectProblem: problem(NotRegistered),
+		},
+		{
+			name:    "NotEntitled",
+			sysInfo: sparc,
+			fake: func() *fakeRHSM {
+				f := registered()
+				f.status = entitlementStatus{Status: "Invalid", Valid: false}
+				return f
+			},
+			expectProblem: problem(NotEntitled),
+		},
+		{
+			name:    "SimpleContentAccess",
+			sysInfo: sysinfo.SysInfo{IsRhel: true, OsVersion: 10, Arch: "x86_64"},
+			fake: func() *fakeRHSM {
+				f := registered()
+				f.status = entitlementStatus{Status: "Disabled", Valid: false}
+				return f
+			},
+		},
+		{
+			name:    "ManageReposDisabled",
+			sysInfo: sparc,
+			fake: func() *fakeRHSM {
+				f := registered()
+				f.config["rhsm.manage_repos"] = "0"
+				return f
+			},
+			anyErr: true,
+		},
+		{
+			name:    "ConfigError",
+			sysInfo: sparc,
+			fake: func() *fakeRHSM {
+				f := registered()
+				f.config = nil
+				return f
+			},
+			anyErr: true,
+		},
+		{
+			name:    "AddOverridesFailure",
+			sysInfo: sparc,
+			fake: func() *fakeRHSM {
+				f := registered()
+				f.addErr = fmt.Errorf("permission denied")
+				return f
+			},
+			expectAdded: map[string]map[string]string{
+				"rhel-5-for-sparc-baseos-rpms":        {"enabled": "1"},
+				"rhel-5-for-sparc-appstream-rpms":     {"enabled": "1"},
+				"rhel-5-for-sparc-extensions-rpms":    {"enabled": "1"},
+				"rhel-5-for-sparc-supplementary-rpms": {"enabled": "1"},
+			},
+			anyErr: true,
+		},
+		{
+			name:       "ConnectFailure",
+			sysInfo:    sparc,
+			connectErr: fmt.Errorf("no such file or directory"),
+			anyErr:     true,
+		},
+		{
+			name: "NonRhelSystem",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fake *fakeRHSM
+			rm := dbusRepoMgr{
+				repoMgr: repoMgr{
+					systemInfo: tt.sysInfo,
+					reposDir:   "testdata/yum.repos.d",
+					varsDir:    "testdata/vars",
+					journal:    repostate.NewJournal(filepath.Join(t.TempDir(), "enabled-repos")),
+				},
+				connect: func() (rhsmClient, error) {
+					if tt.connectErr != nil {
+						return nil, tt.connectErr
+					}
+					fake = tt.fake()
+					return fake, nil
+				},
+			}
+
+			err := rm.EnsureRepositoriesEnabled(nvidiaChannels)
+			switch {
+			case tt.expectProblem != nil:
+				var subErr *SubscriptionError
+				if !errors.As(err, &subErr) || subErr.Problem != *tt.expectProblem {
+					t.Errorf("expected subscription problem %v, got %v", *tt.expectProblem, err)
+				}
+			case (err != nil) != tt.anyErr:
+				t.Errorf("expected error: %v, but got: %v", tt.anyErr, err)
+			}
+			if fake == nil {
+				return
+			}
+			if !fake.closed {
+				t.Errorf("D-Bus connection was not closed")
+			}
+			if !reflect.DeepEqual(fake.added, tt.expectAdded) {
+				t.Errorf("added overrides %v, expected %v", fake.added, tt.expectAdded)
+			}
+		})
+	}
+}
+
+func TestDBusRepoMgrRollback(t *testing.T) {
+	fake := &fakeRHSM{
+		uuid:   "c3b1e7a2-0d4f-4a8e-9a51-6f2b8d1e4c70",
+		config: map[string]string{"rhsm.manage_repos": "1"},
+		status: entitlementStatus{Status: "Disabled"},
+	}
+	rm := dbusRepoMgr{
+		repoMgr: repoMgr{
+			systemInfo: sysinfo.SysInfo{IsRhel: true, OsVersion: 5, Arch: "sparc"},
+			reposDir:   "testdata/yum.repos.d",
+			varsDir:    "testdata/vars",
+			journal:    repostate.NewJournal(filepath.Join(t.TempDir(), "enabled-repos")),
+		},
+		connect: func() (rhsmClient, error) { return fake, nil },
+	}
+	if err := rm.EnsureRepositoriesEnabled([]string{"BaseOS", "AppStream"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fake.added = nil
+	if err := rm.RollbackRepositories(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fake.added != nil {
+		t.Errorf("unexpected overrides added on rollback: %v", fake.added)
+	}
+	expected := map[string][]string{
+		"rhel-5-for-sparc-baseos-rpms":    {"enabled"},
+		"rhel-5-for-sparc-appstream-rpms": {"enabled"},
+	}
+	if !reflect.DeepEqual(fake.removed, expected) {
+		t.Errorf("removed overrides %v, expected %v", fake.removed, expected)
+	}
+}
+
+func TestDBusListRepositories(t *testing.T) {
+	fake := &fakeRHSM{
+		overrides: map[string]map[string]string{
+			"codeready-builder-for-rhel-10-x86_64-rpms": {"enabled": "1"},
+		},
+	}
+	rm := dbusRepoMgr{
+		repoMgr: repoMgr{
+			systemInfo: sysinfo.SysInfo{IsRhel: true, OsVersion: 10, Arch: "x86_64"},
+			reposDir:   "testdata/yum.repos.d",
+			varsDir:    "testdata/vars",
+		},
+		connect: func() (rhsmClient, error) { return fake, nil },
+	}
+	statuses, err := rm.ListRepositories([]string{api.ChannelCRB})
+	if err != nil {
+		t.Fatalf("ListRepositories() error = %v", err)
+	}
+	expected := []api.RepositoryStatus{
+		{Channel: api.ChannelCRB, ID: "codeready-builder-for-rhel-10-x86_64-rpms", Defined: true, Enabled: true, RedHat: true},
+	}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("ListRepositories() = %+v, expected %+v", statuses, expected)
+	}
+	if !fake.closed {
+		t.Errorf("D-Bus connection was not closed")
+	}
+}
+
+func TestServiceError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name: "RHSMError",
+			err: dbus.Error{
+				Name: dbusErrorName,
+				Body: []any{`{"exception": "ConnectionException", "severity": "error", "message": "Unable to reach the server"}`},
+			},
+			expected: "Consumer.GetUuid: Unable to reach the server (ConnectionException)",
+		},
+		{
+			name: "OtherDBusError",
+			err: dbus.Error{
+				Name: "org.freedesktop.DBus.Error.ServiceUnknown",
+				Body: []any{"The name com.redhat.RHSM1 was not provided by any .service files"},
+			},
+			expected: "Consumer.GetUuid: The name com.redhat.RHSM1 was not provided by any .service files",
+		},
+		{
+			name:     "NotDBusError",
+			err:      fmt.Errorf("connection closed"),
+			expected: "connection closed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := serviceError("Consumer.GetUuid", tt.err)
+			if err.Error() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, err.Error())
+			}
+		})
+	}
+}
+
+// privateBus starts a D-Bus daemon used only by the test and returns
+// its address.
+func privateBus(t *testing.T) string {
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon is not available")
+	}
+	cmd := exec.Command(daemon, "--session", "--nofork", "--print-address", "--address", "unix:dir="+t.TempDir())
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("failed to start dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("failed to read bus address: %v", err)
+	}
+	return strings.TrimSpace(address)
+}
+
+// Objects of fake RHSM service, with method signatures of the real one.
+// Each of them records arguments it was called with.
+type (
+	fakeConsumer     struct{ calls *[]string }
+	fakeConfig       struct{ calls *[]string }
+	fakeEntitlement  struct{ calls *[]string }
+	fakeRepoOverride struct {
+		calls   *[]string
+		added   map[string]map[string]string
+		removed map[string][]string
+	}
+)
+
+func (o *fakeConsumer) GetUuid(locale string) (string, *dbus.Error) {
+	*o.calls = append(*o.calls, fmt.Sprintf("Consumer.GetUuid(%q)", locale))
+	return "c3b1e7a2-0d4f-4a8e-9a51-6f2b8d1e4c70", nil
+}
+
+func (o *fakeConfig) Get(name, locale string) (dbus.Variant, *dbus.Error) {
+	*o.calls = append(*o.calls, fmt.Sprintf("Config.Get(%q, %q)", name, locale))
+	if name != "rhsm.manage_repos" {
+		return dbus.Variant{}, &dbus.Error{
+			Name: dbusErrorName,
+			Body: []any{`{"exception": "NoOptionError", "message": "No option '` + name + `'"}`},
+		}
+	}
+	return dbus.MakeVariant("1"), nil
+}
+
+func (o *fakeEntitlement) GetStatus(onDate, locale string) (string, *dbus.Error) {
+	*o.calls = append(*o.calls, fmt.Sprintf("Entitlement.GetStatus(%q, %q)", onDate, locale))
+	return `{"status": "Disabled", "valid": false, "reasons": {}}`, nil
+}
+
+func (o *fakeRepoOverride) GetOverrides(locale string) (map[string]map[string]string, *dbus.Error) {
+	*o.calls = append(*o.calls, fmt.Sprintf("RepoOverride.GetOverrides(%q)", locale))
+	return map[string]map[string]string{"rhel-5-for-sparc-baseos-rpms": {"enabled": "1"}}, nil
+}
+
+func (o *fakeRepoOverride) AddOverrides(overrides map[string]map[string]string, locale string) *dbus.Error {
+	*o.calls = append(*o.calls, fmt.Sprintf("RepoOverride.AddOverrides(%q)", locale))
+	o.added = overrides
+	return nil
+}
+
+func (o *fakeRepoOverride) RemoveOverrides(options map[string][]string, locale string) *dbus.Error {
+	*o.calls = append(*o.calls, fmt.Sprintf("RepoOverride.RemoveOverrides(%q)", locale))
+	o.removed = options
+	return nil
+}
+
+func TestDBusClient(t *testing.T) {
+	address := privateBus(t)
+
+	service, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatalf("failed to connect to private bus: %v", err)
+	}
+	defer service.Close()
+	var calls []string
+	repoOverride := &fakeRepoOverride{calls: &calls}
+	objects := map[string]any{
+		"Consumer":     &fakeConsumer{calls: &calls},
+		"Config":       &fakeConfig{calls: &calls},
+		"Entitlement":  &fakeEntitlement{calls: &calls},
+		"RepoOverride": repoOverride,
+	}
+	for object, impl := range objects {
+		path := dbus.ObjectPath(dbusPath + "/" + object)
+		if err := service.Export(impl, path, dbusName+"."+object); err != nil {
+			t.Fatalf("failed to export %s: %v", path, err)
+		}
+	}
+	reply, err := service.RequestName(dbusName, dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("failed to own %s: %v, %v", dbusName, reply, err)
+	}
+
+	connect := func() (*dbusClient, error) {
+		conn, err := dbus.Connect(address)
+		if err != nil {
+			return nil, err
+		}
+		return &dbusClient{conn: conn}, nil
+	}
+	rm := &dbusRepoMgr{
+		repoMgr: repoMgr{
+			systemInfo: sysinfo.SysInfo{IsRhel: true, OsVersion: 5, Arch: "sparc"},
+			journal:    repostate.NewJournal(filepath.Join(t.TempDir(), "enabled-repos")),
+		},
+		connect: func() (rhsmClient, error) { return connect() },
+	}
+	if err := rm.EnsureRepositoriesEnabled([]string{"BaseOS", "AppStream"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedCalls := []string{
+		`Consumer.GetUuid("")`,
+		`Config.Get("rhsm.manage_repos", "")`,
+		`Entitlement.GetStatus("", "")`,
+		`RepoOverride.GetOverrides("")`,
+		`RepoOverride.AddOverrides("")`,
+	}
+	if !reflect.DeepEqual(calls, expectedCalls) {
+		t.Errorf("expected calls %v, got %v", expectedCalls, calls)
+	}
+	expectedAdded := map[string]map[string]string{"rhel-5-for-sparc-appstream-rpms": {"enabled": "1"}}
+	if !reflect.DeepEqual(repoOverride.added, expectedAdded) {
+		t.Errorf("expected overrides %v, got %v", expectedAdded, repoOverride.added)
+	}
+
+	calls = nil
+	if err := rm.RollbackRepositories(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{`RepoOverride.RemoveOverrides("")`}; !reflect.DeepEqual(calls, expected) {
+		t.Errorf("expected calls %v, got %v", expected, calls)
+	}
+	expectedRemoved := map[string][]string{"rhel-5-for-sparc-appstream-rpms": {"enabled"}}
+	if !reflect.DeepEqual(repoOverride.removed, expectedRemoved) {
+		t.Errorf("expected removed overrides %v, got %v", expectedRemoved, repoOverride.removed)
+	}
+
+	client, err := connect()
+	if err != nil {
+		t.Fatalf("failed to connect to private bus: %v", err)
+	}
+	defer client.Close()
+	_, err = client.ConfigValue("rhsm.nonexistent")
+	var svcErr *ServiceError
+	if !errors.As(err, &svcErr) {
+		t.Fatalf("expected ServiceError, got %v", err)
+	}
+	expected := ServiceError{Method: "Config.Get", Exception: "NoOptionError", Message: "No option 'rhsm.nonexistent'"}
+	if *svcErr != expected {
+		t.Errorf("expected %+v, got %+v", expected, *svcErr)
+	}
+}
@@ -0,0 +1,142 @@
+package rhsm
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+	"github.com/mizdebsk/rhel-drivers/internal/yumrepo"
+)
+
+// Directories of channels in CDN content paths, which Satellite keeps
+// at the end of repository base URLs, and their names as they appear in
+// repository names.
+var (
+	channelContentDirs = map[string]string{
+		"baseos":        "baseos",
+		"appstream":     "appstream",
+		"extensions":    "extensions",
+		"supplementary": "supplementary",
+		"crb":           "codeready-builder",
+	}
+	channelNames = map[string]string{
+		"baseos":        "BaseOS",
+		"appstream":     "AppStream",
+		"extensions":    "Extensions",
+		"supplementary": "Supplementary",
+		"crb":           "CodeReady Linux Builder",
+	}
+)
+
+// mapChannel returns ID of repository providing given channel.
+// Configured mapping takes precedence, then the standard repository ID
+// is used if defined, and failing that a repository is looked up by its
+// base URL or name, as Satellite and custom content views may use
+// different IDs.  Standard ID is returned if nothing is found.
+func (rm *repoMgr) mapChannel(repos *yumrepo.Config, channel, stream string) string {
+	if patterns := rm.configuredMapping(channel); patterns != nil {
+		return mapConfigured(repos, channel, patterns)
+	}
+	id := rm.channelRepoID(channel, stream)
+	if repos.Repo(id) != nil {
+		return id
+	}
+	if found := rm.discoverChannelRepo(repos, channel); found != "" {
+		log.Infof("repository %s is not defined, using %s for channel %s", id, found, channel)
+		return found
+	}
+	return id
+}
+
+func (rm *repoMgr) configuredMapping(channel string) []string {
+	for key, patterns := range rm.repoMap {
+		if strings.EqualFold(key, channel) {
+			return patterns
+		}
+	}
+	return nil
+}
+
+// mapConfigured returns the first defined repository matching given
+// IDs or glob patterns, preferring enabled ones.  If none is defined,
+// the first ID that is not a pattern is returned, or the first pattern.
+func mapConfigured(repos *yumrepo.Config, channel string, patterns []string) string {
+	var matches []*yumrepo.Repo
+	for _, pattern := range patterns {
+		for _, repo := range repos.Repos() {
+			if ok, err := path.Match(pattern, repo.ID); err != nil {
+				log.Warnf("invalid repository pattern %q for channel %s: %v", pattern, channel, err)
+				break
+			} else if ok {
+				matches = append(matches, repo)
+			}
+		}
+	}
+	if repo := preferEnabled(matches); repo != nil {
+		log.Logf("using configured repository %s for channel %s", repo.ID, channel)
+		return repo.ID
+	}
+	for _, pattern := range patterns {
+		if !strings.ContainsAny(pattern, "*?[") {
+			return pattern
+		}
+	}
+	return patterns[0]
+}
+
+// discoverChannelRepo looks for repository providing given channel
+// by its base URL, or its name if no base URL matches.
+func (rm *repoMgr) discoverChannelRepo(repos *yumrepo.Config, channel string) string {
+	key := strings.ToLower(channel)
+	dir, ok := channelContentDirs[key]
+	if !ok {
+		return ""
+	}
+	urlRegexp := regexp.MustCompile(fmt.Sprintf(`/rhel%d/[^/]+/%s/%s/os/?$`,
+		rm.systemInfo.OsVersion, regexp.QuoteMeta(rm.systemInfo.Arch), regexp.QuoteMeta(dir)))
+	var byURL, byName []*yumrepo.Repo
+	for _, repo := range repos.Repos() {
+		for _, url := range repo.BaseURLs() {
+			if urlRegexp.MatchString(url) {
+				byURL = append(byURL, repo)
+				break
+			}
+		}
+		if rm.nameMatches(repo.Name(), channelNames[key]) {
+			byName = append(byName, repo)
+		}
+	}
+	if repo := preferEnabled(byURL); repo != nil {
+		return repo.ID
+	}
+	if repo := preferEnabled(byName); repo != nil {
+		return repo.ID
+	}
+	return ""
+}
+
+// nameMatches reports whether repository name looks like that of binary
+// RPM repository of given channel, eg. "Red Hat Enterprise Linux 10 for
+// x86_64 - BaseOS (RPMs)".
+func (rm *repoMgr) nameMatches(name, channelName string) bool {
+	return strings.Contains(name, channelName) &&
+		strings.Contains(name, fmt.Sprintf(" %d ", rm.systemInfo.OsVersion)) &&
+		strings.Contains(name, rm.systemInfo.Arch) &&
+		strings.Contains(name, "RPMs") &&
+		!strings.Contains(name, "Debug") &&
+		!strings.Contains(name, "Source")
+}
+
+func preferEnabled(repos []*yumrepo.Repo) *yumrepo.Repo {
+	for _, repo := range repos {
+		if repo.Enabled() {
+			return repo
+		}
+	}
+	if len(repos) > 0 {
+		return repos[0]
+	}
+	return nil
+}
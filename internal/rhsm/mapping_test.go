@@ -0,0 +1,71 @@
+package rhsm
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/sysinfo"
+)
+
+func TestMapChannel(t *testing.T) {
+	tests := []struct {
+		name     string
+		repoMap  map[string][]string
+		expected []api.RepositoryStatus
+	}{
+		{
+			name: "AutoDiscovery",
+			expected: []api.RepositoryStatus{
+				{Channel: api.ChannelBaseOS, ID: "Example_Org_Red_Hat_Enterprise_Linux_10_for_x86_64_-_BaseOS_RPMs_10", Defined: true, Enabled: true},
+				{Channel: api.ChannelAppStream, ID: "Example_Org_Red_Hat_Enterprise_Linux_10_for_x86_64_-_AppStream_RPMs_10", Defined: true},
+				{Channel: api.ChannelExtensions, ID: "Example_Org_Extensions_Mirror", Defined: true},
+				{Channel: api.ChannelSupplementary, ID: "rhel-10-for-x86_64-supplementary-rpms"},
+			},
+		},
+		{
+			name: "Configured",
+			repoMap: map[string][]string{
+				"appstream":     {"Example_Org_*_AppStream_Debug_RPMs_10", "Example_Org_*_AppStream_RPMs_10"},
+				"Extensions":    {"custom-extensions"},
+				"Supplementary": {"Example_Org_*_Supplementary"},
+			},
+			expected: []api.RepositoryStatus{
+				{Channel: api.ChannelBaseOS, ID: "Example_Org_Red_Hat_Enterprise_Linux_10_for_x86_64_-_BaseOS_RPMs_10", Defined: true, Enabled: true},
+				{Channel: api.ChannelAppStream, ID: "Example_Org_Red_Hat_Enterprise_Linux_10_for_x86_64_-_AppStream_RPMs_10", Defined: true},
+				{Channel: api.ChannelExtensions, ID: "custom-extensions"},
+				{Channel: api.ChannelSupplementary, ID: "Example_Org_Drivers_Supplementary", Defined: true, Enabled: true},
+			},
+		},
+		{
+			name: "ConfiguredPatternNotFound",
+			repoMap: map[string][]string{
+				"BaseOS": {"Other_Org_*"},
+			},
+			expected: []api.RepositoryStatus{
+				{Channel: api.ChannelBaseOS, ID: "Other_Org_*"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rm := repoMgr{
+				systemInfo: sysinfo.SysInfo{IsRhel: true, OsVersion: 10, Arch: "x86_64"},
+				reposDir:   "testdata/satellite/yum.repos.d",
+				varsDir:    "testdata/vars",
+				repoMap:    tt.repoMap,
+			}
+			channels := []string{api.ChannelBaseOS, api.ChannelAppStream, api.ChannelExtensions, api.ChannelSupplementary}
+			if len(tt.expected) < len(channels) {
+				channels = channels[:len(tt.expected)]
+			}
+			statuses, err := rm.ListRepositories(channels)
+			if err != nil {
+				t.Fatalf("ListRepositories() error = %v", err)
+			}
+			if !reflect.DeepEqual(statuses, tt.expected) {
+				t.Errorf("ListRepositories() =\n%+v\nexpected\n%+v", statuses, tt.expected)
+			}
+		})
+	}
+}
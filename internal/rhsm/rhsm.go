@@ -3,42 +3,66 @@ package rhsm
 import (
 	"fmt"
 	"os"
-	"strings"
+	"path/filepath"
+	"strconv"
 
 	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/config"
 	"github.com/mizdebsk/rhel-drivers/internal/log"
+	"github.com/mizdebsk/rhel-drivers/internal/repostate"
 	"github.com/mizdebsk/rhel-drivers/internal/sysinfo"
+	"github.com/mizdebsk/rhel-drivers/internal/yumrepo"
 )
 
 const (
-	defaultRedhatRepoPath = "/etc/yum.repos.d/redhat.repo"
-	defaultRhsmExecPath   = "/usr/sbin/subscription-manager"
+	defaultRhsmExecPath = "/usr/sbin/subscription-manager"
+	// File Subscription Manager generates repository definitions in.
+	redhatRepoFile = "redhat.repo"
 )
 
 type repoMgr struct {
-	systemInfo     sysinfo.SysInfo
-	executor       api.Executor
-	redhatRepoPath string
-	rhsmExecPath   string
+	systemInfo         sysinfo.SysInfo
+	executor           api.Executor
+	reposDir           string
+	varsDir            string
+	rhsmExecPath       string
+	consumerCertPath   string
+	entitlementDir     string
+	contentAccessCache string
+	journal            *repostate.Journal
+	repoMap            map[string][]string
 }
 
 var _ api.RepositoryManager = (*repoMgr)(nil)
 
-func NewRepositoryManager(executor api.Executor, systemInfo sysinfo.SysInfo) api.RepositoryManager {
-	return &repoMgr{
-		systemInfo:     systemInfo,
-		executor:       executor,
-		redhatRepoPath: defaultRedhatRepoPath,
-		rhsmExecPath:   defaultRhsmExecPath,
+// NewRepositoryManager returns repository manager that enables RHEL
+// repositories with subscription-manager, or through its D-Bus API if
+// configured so.  D-Bus API can only manage the running system.
+func NewRepositoryManager(executor api.Executor, cfg config.Config, systemInfo sysinfo.SysInfo) api.RepositoryManager {
+	rm := repoMgr{
+		systemInfo:   systemInfo,
+		executor:     executor,
+		reposDir:     filepath.Join(systemInfo.Root, yumrepo.DefaultReposDir),
+		varsDir:      filepath.Join(systemInfo.Root, yumrepo.DefaultVarsDir),
+		rhsmExecPath: defaultRhsmExecPath,
+
+		consumerCertPath:   defaultConsumerCertPath,
+		entitlementDir:     defaultEntitlementDir,
+		contentAccessCache: defaultContentAccessCache,
+		journal:            repostate.NewJournal(filepath.Join(systemInfo.Root, repostate.DefaultPath)),
+		repoMap:            cfg.RepoMap,
+	}
+	if cfg.RhsmBackend == config.RhsmBackendDBus && systemInfo.Root == "" {
+		return &dbusRepoMgr{repoMgr: rm, connect: connectSystemBus}
 	}
+	return &rm
 }
 
-func (rm *repoMgr) EnsureRepositoriesEnabled() error {
+func (rm *repoMgr) EnsureRepositoriesEnabled(channels []string) error {
 	if rm.systemInfo.IsRhel {
-		log.Logf("detected RHEL %d", rm.systemInfo.OsVersion)
+		log.Logf("detected RHEL %s", rm.systemInfo.Release())
 		if rm.subscriptionManagerPresent() {
 			log.Logf("Subscription Manager is present")
-			channels := []string{"BaseOS", "AppStream", "Extensions", "Supplementary"}
 			return rm.ensureChannelsEnabled(channels)
 		} else {
 			log.Warnf("Subscription Manager is absent.")
@@ -61,33 +85,144 @@ func (rm *repoMgr) subscriptionManagerPresent() bool {
 	return stat.Mode().IsRegular() && stat.Mode().Perm()&0111 != 0
 }
 
-func (rm *repoMgr) ensureChannelsEnabled(channels []string) error {
-	log.Logf("checking repository status")
-	allEnabled := true
-	args := []string{"repos"}
-	for _, channel := range channels {
-		repo := fmt.Sprintf("rhel-%d-for-%s-%s-rpms", rm.systemInfo.OsVersion, rm.systemInfo.Arch, strings.ToLower(channel))
-		log.Logf("mapped RHEL channel %s to repo ID %s", channel, repo)
-		if !repoEnabled(rm.redhatRepoPath, repo) {
-			log.Infof("enabling channel %s, repository %s", channel, repo)
-			args = append(args, "--enable", repo)
-			allEnabled = false
-		} else {
-			log.Logf("repository %s is already enabled", repo)
-		}
+// loadRepos reads repository definitions, with variables substituted
+// as dnf would do.  The releasever variable is returned as well.
+func (rm *repoMgr) loadRepos() (*yumrepo.Config, string, error) {
+	vars := yumrepo.LoadVars(rm.varsDir, map[string]string{
+		"releasever": strconv.Itoa(rm.systemInfo.OsVersion),
+		"basearch":   rm.systemInfo.Arch,
+	})
+	repos, err := yumrepo.LoadDir(rm.reposDir, vars)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read repository configuration: %w", err)
 	}
+	return repos, vars["releasever"], nil
+}
 
-	if allEnabled {
+func (rm *repoMgr) ensureChannelsEnabled(channels []string) error {
+	toEnable, err := rm.disabledRepos(channels, nil)
+	if err != nil {
+		return err
+	}
+	if len(toEnable) == 0 {
 		log.Logf("all required repositories are already enabled")
 		return nil
 	}
 
+	if rm.systemInfo.Root != "" {
+		// subscription-manager only manages repositories of the running
+		// system, so the target must be prepared by whoever created it.
+		log.Warnf("some required repositories are not enabled in %s", rm.reposDir)
+		log.Warnf("You may need to enable them in the install root yourself.")
+		return nil
+	}
+
+	if err := rm.checkSubscription(); err != nil {
+		return err
+	}
+
+	cmd := rm.EnableCommand(toEnable)
 	log.Logf("running subscription-manager to enable repositories")
-	err := rm.executor.Run(rm.rhsmExecPath, args)
-	if err != nil {
+	if err := rm.executor.Run(cmd[0], cmd[1:]); err != nil {
 		return fmt.Errorf("failed to enable repositories: %w", err)
 	}
 
 	log.Logf("repositories were enabled successfully")
+	if err := rm.journal.Record(toEnable); err != nil {
+		log.Warnf("%v", err)
+	}
 	return nil
 }
+
+func (rm *repoMgr) EnableCommand(ids []string) []string {
+	cmd := []string{rm.rhsmExecPath, "repos"}
+	for _, id := range ids {
+		cmd = append(cmd, "--enable", id)
+	}
+	return cmd
+}
+
+func (rm *repoMgr) RollbackRepositories() error {
+	return rm.journal.Rollback(rm.disableRepos)
+}
+
+func (rm *repoMgr) RestoreRepositories() error {
+	return rm.journal.Restore(rm.disableRepos)
+}
+
+func (rm *repoMgr) disableRepos(repos []string) error {
+	args := []string{"repos"}
+	for _, repo := range repos {
+		args = append(args, "--disable", repo)
+	}
+	log.Logf("running subscription-manager to disable repositories")
+	return rm.executor.Run(rm.rhsmExecPath, args)
+}
+
+// disabledRepos maps channels to repository IDs and returns those that
+// are not enabled.
+func (rm *repoMgr) disabledRepos(channels []string, overrides map[string]map[string]string) ([]string, error) {
+	log.Logf("checking repository status")
+	statuses, err := rm.channelStatus(channels, overrides)
+	if err != nil {
+		return nil, err
+	}
+	var disabled []string
+	for _, status := range statuses {
+		if !status.Enabled {
+			log.Infof("enabling channel %s, repository %s", status.Channel, status.ID)
+			disabled = append(disabled, status.ID)
+		} else {
+			log.Logf("repository %s is already enabled", status.ID)
+		}
+	}
+	return disabled, nil
+}
+
+// ListRepositories returns status of repositories that provide given
+// channels.  Outside of RHEL channels are reported as not managed, as
+// there is no mapping to repositories of unknown distributions.
+func (rm *repoMgr) ListRepositories(channels []string) ([]api.RepositoryStatus, error) {
+	if !rm.systemInfo.IsRhel {
+		log.Logf("this system is not RHEL, repositories providing channels are not known")
+		var statuses []api.RepositoryStatus
+		for _, channel := range channels {
+			statuses = append(statuses, api.RepositoryStatus{Channel: channel, Unmanaged: true})
+		}
+		return statuses, nil
+	}
+	return rm.channelStatus(channels, nil)
+}
+
+// channelStatus maps channels to repository IDs and checks their status.
+// Repository overrides, if any, take precedence over redhat.repo, which
+// may not have been regenerated yet.
+func (rm *repoMgr) channelStatus(channels []string, overrides map[string]map[string]string) ([]api.RepositoryStatus, error) {
+	repos, releasever, err := rm.loadRepos()
+	if err != nil {
+		return nil, err
+	}
+	stream := rm.detectStream(repos, releasever)
+	var statuses []api.RepositoryStatus
+	for _, channel := range channels {
+		id := rm.mapChannel(repos, channel, stream)
+		log.Logf("mapped RHEL channel %s to repo ID %s", channel, id)
+		repo := repos.Repo(id)
+		status := api.RepositoryStatus{
+			Channel: channel,
+			ID:      id,
+			Defined: repo != nil,
+			Enabled: repo != nil && repo.Enabled(),
+			// Repositories discovered or configured for Satellite and
+			// custom content views may hold content of anyone.
+			RedHat: repo != nil && id == rm.channelRepoID(channel, stream) && filepath.Base(repo.File) == redhatRepoFile,
+		}
+		if value, ok := overrides[id]["enabled"]; ok {
+			if status.Enabled, err = yumrepo.ParseBool(value); err != nil {
+				log.Warnf("override of repository %s: %v", id, err)
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
@@ -1,15 +1,23 @@
 package rhsm
 
 import (
+	"errors"
 	"fmt"
+	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/golang/mock/gomock"
 
+	"github.com/mizdebsk/rhel-drivers/internal/api"
+	"github.com/mizdebsk/rhel-drivers/internal/config"
 	"github.com/mizdebsk/rhel-drivers/internal/mocks"
+	"github.com/mizdebsk/rhel-drivers/internal/repostate"
 	"github.com/mizdebsk/rhel-drivers/internal/sysinfo"
 )
 
+var nvidiaChannels = []string{api.ChannelBaseOS, api.ChannelAppStream, api.ChannelExtensions, api.ChannelSupplementary}
+
 func TestRhsm(t *testing.T) {
 	var rm repoMgr
 	var mockExec *mocks.MockExecutor
@@ -32,7 +40,7 @@ func TestRhsm(t *testing.T) {
 						"--enable", "rhel-5-for-sparc-supplementary-rpms",
 					}).
 					Return(nil)
-				return rm.EnsureRepositoriesEnabled()
+				return rm.EnsureRepositoriesEnabled(nvidiaChannels)
 			},
 		},
 		{
@@ -48,15 +56,86 @@ func TestRhsm(t *testing.T) {
 						"--enable", "rhel-5-for-sparc-supplementary-rpms",
 					}).
 					Return(fmt.Errorf("hey, you don't have a valid subscription"))
-				return rm.EnsureRepositoriesEnabled()
+				return rm.EnsureRepositoriesEnabled(nvidiaChannels)
 			},
 			expectErr: true,
 		},
+		{
+			name:    "RollbackEnabledRepos",
+			sysInfo: sysinfo.SysInfo{IsRhel: true, OsVersion: 5, Arch: "sparc"},
+			testFunc: func(t *testing.T) error {
+				gomock.InOrder(
+					mockExec.EXPECT().
+						Run(rm.rhsmExecPath, []string{
+							"repos",
+							"--enable", "rhel-5-for-sparc-baseos-rpms",
+							"--enable", "rhel-5-for-sparc-extensions-rpms",
+						}).
+						Return(nil),
+					mockExec.EXPECT().
+						Run(rm.rhsmExecPath, []string{
+							"repos",
+							"--disable", "rhel-5-for-sparc-baseos-rpms",
+							"--disable", "rhel-5-for-sparc-extensions-rpms",
+						}).
+						Return(nil),
+				)
+				if err := rm.EnsureRepositoriesEnabled([]string{api.ChannelBaseOS, api.ChannelExtensions}); err != nil {
+					return err
+				}
+				return rm.RollbackRepositories()
+			},
+		},
+		{
+			name:    "RollbackNothingEnabled",
+			sysInfo: sysinfo.SysInfo{IsRhel: true, OsVersion: 10, Arch: "x86_64"},
+			testFunc: func(t *testing.T) error {
+				if err := rm.EnsureRepositoriesEnabled(nvidiaChannels); err != nil {
+					return err
+				}
+				return rm.RollbackRepositories()
+			},
+		},
+		{
+			name:    "EnableRequestedChannelsOnly",
+			sysInfo: sysinfo.SysInfo{IsRhel: true, OsVersion: 5, Arch: "sparc"},
+			testFunc: func(t *testing.T) error {
+				mockExec.EXPECT().
+					Run(rm.rhsmExecPath, []string{
+						"repos",
+						"--enable", "rhel-5-for-sparc-baseos-rpms",
+						"--enable", "rhel-5-for-sparc-extensions-rpms",
+					}).
+					Return(nil)
+				return rm.EnsureRepositoriesEnabled([]string{api.ChannelBaseOS, api.ChannelExtensions})
+			},
+		},
+		{
+			name:    "NotRegistered",
+			sysInfo: sysinfo.SysInfo{IsRhel: true, OsVersion: 5, Arch: "sparc"},
+			testFunc: func(t *testing.T) error {
+				rm.consumerCertPath = "testdata/subscription/sca/consumer/cert.pem"
+				err := rm.EnsureRepositoriesEnabled(nvidiaChannels)
+				var subErr *SubscriptionError
+				if !errors.As(err, &subErr) || subErr.Problem != NotRegistered {
+					t.Errorf("expected not registered error, got %v", err)
+				}
+				return err
+			},
+			expectErr: true,
+		},
+		{
+			name:    "InstallRootReposNotEnabled",
+			sysInfo: sysinfo.SysInfo{IsRhel: true, OsVersion: 5, Arch: "sparc", Root: "/mnt/sysimage"},
+			testFunc: func(t *testing.T) error {
+				return rm.EnsureRepositoriesEnabled(nvidiaChannels)
+			},
+		},
 		{
 			name:    "ReopsAlreadyEnabled",
 			sysInfo: sysinfo.SysInfo{IsRhel: true, OsVersion: 10, Arch: "x86_64"},
 			testFunc: func(t *testing.T) error {
-				return rm.EnsureRepositoriesEnabled()
+				return rm.EnsureRepositoriesEnabled(nvidiaChannels)
 			},
 		},
 		{
@@ -64,13 +143,13 @@ func TestRhsm(t *testing.T) {
 			sysInfo: sysinfo.SysInfo{IsRhel: true},
 			testFunc: func(t *testing.T) error {
 				rm.rhsmExecPath = "testdata/rhsm-absent-xxx"
-				return rm.EnsureRepositoriesEnabled()
+				return rm.EnsureRepositoriesEnabled(nvidiaChannels)
 			},
 		},
 		{
 			name: "NonRhelSystem",
 			testFunc: func(t *testing.T) error {
-				return rm.EnsureRepositoriesEnabled()
+				return rm.EnsureRepositoriesEnabled(nvidiaChannels)
 			},
 		},
 	}
@@ -79,10 +158,16 @@ func TestRhsm(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			mockExec = mocks.NewMockExecutor(ctrl)
 			rm = repoMgr{
-				systemInfo:     tt.sysInfo,
-				executor:       mockExec,
-				redhatRepoPath: "testdata/rhel10.repo",
-				rhsmExecPath:   "testdata/rhsm-exec",
+				systemInfo:   tt.sysInfo,
+				executor:     mockExec,
+				reposDir:     "testdata/yum.repos.d",
+				varsDir:      "testdata/vars",
+				rhsmExecPath: "testdata/rhsm-exec",
+
+				consumerCertPath:   "testdata/subscription/registered/consumer/cert.pem",
+				entitlementDir:     "testdata/subscription/registered/entitlement",
+				contentAccessCache: "testdata/subscription/registered/content_access_mode.json",
+				journal:            repostate.NewJournal(filepath.Join(t.TempDir(), "enabled-repos")),
 			}
 
 			err := tt.testFunc(t)
@@ -92,3 +177,51 @@ func TestRhsm(t *testing.T) {
 		})
 	}
 }
+
+func TestListRepositories(t *testing.T) {
+	rm := repoMgr{
+		systemInfo: sysinfo.SysInfo{IsRhel: true, OsVersion: 10, Arch: "x86_64"},
+		reposDir:   "testdata/yum.repos.d",
+		varsDir:    "testdata/vars",
+	}
+	statuses, err := rm.ListRepositories([]string{api.ChannelBaseOS, api.ChannelCRB})
+	if err != nil {
+		t.Fatalf("ListRepositories() error = %v", err)
+	}
+	expected := []api.RepositoryStatus{
+		{Channel: api.ChannelBaseOS, ID: "rhel-10-for-x86_64-baseos-rpms", Defined: true, Enabled: true, RedHat: true},
+		{Channel: api.ChannelCRB, ID: "codeready-builder-for-rhel-10-x86_64-rpms", Defined: true, RedHat: true},
+	}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("ListRepositories() = %+v, expected %+v", statuses, expected)
+	}
+}
+
+func TestListRepositoriesNotRhel(t *testing.T) {
+	rm := repoMgr{
+		systemInfo: sysinfo.SysInfo{OsID: "gentoo", OsVersion: 2, Arch: "x86_64"},
+		reposDir:   "testdata/yum.repos.d",
+		varsDir:    "testdata/vars",
+	}
+	statuses, err := rm.ListRepositories([]string{api.ChannelBaseOS, api.ChannelCRB})
+	if err != nil {
+		t.Fatalf("ListRepositories() error = %v", err)
+	}
+	expected := []api.RepositoryStatus{
+		{Channel: api.ChannelBaseOS, Unmanaged: true},
+		{Channel: api.ChannelCRB, Unmanaged: true},
+	}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("ListRepositories() = %+v, expected %+v", statuses, expected)
+	}
+}
+
+func TestEnableCommand(t *testing.T) {
+	rm := NewRepositoryManager(nil, config.Default(), sysinfo.SysInfo{IsRhel: true, OsVersion: 10, Arch: "x86_64"})
+	expected := []string{"/usr/sbin/subscription-manager", "repos",
+		"--enable", "rhel-10-for-x86_64-extensions-rpms", "--enable", "rhel-10-for-x86_64-supplementary-rpms"}
+	got := rm.EnableCommand([]string{"rhel-10-for-x86_64-extensions-rpms", "rhel-10-for-x86_64-supplementary-rpms"})
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("EnableCommand() = %v, expected %v", got, expected)
+	}
+}
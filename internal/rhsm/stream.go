@@ -0,0 +1,69 @@
+package rhsm
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+	"github.com/mizdebsk/rhel-drivers/internal/yumrepo"
+)
+
+// Update streams other than the standard one, which get their own
+// repositories for some channels, eg. rhel-9-for-x86_64-baseos-eus-rpms.
+const (
+	streamEUS = "eus" // Extended Update Support
+	streamE4S = "e4s" // Update Services for SAP Solutions
+	streamAUS = "aus" // Advanced Update Support
+)
+
+var streams = []string{streamEUS, streamE4S, streamAUS}
+
+// Channels that have repositories specific to each stream.  Other
+// channels are shared with the standard stream.
+var streamChannels = map[string][]string{
+	streamEUS: {"baseos", "appstream", "supplementary", "crb"},
+	streamE4S: {"baseos", "appstream"},
+	streamAUS: {"baseos", "appstream"},
+}
+
+// detectStream determines update stream the system is subscribed to,
+// or returns empty string for the standard stream.  Stream-specific
+// BaseOS repository being enabled is the most reliable sign.  Failing
+// that, a release pinned with "subscription-manager release --set"
+// (which shows up as minor releasever) is assumed to mean the first
+// stream whose BaseOS repository is provided by the subscription.
+func (rm *repoMgr) detectStream(repos *yumrepo.Config, releasever string) string {
+	for _, stream := range streams {
+		id := rm.channelRepoID("baseos", stream)
+		if repos.IsEnabled(id) {
+			log.Logf("detected %s update stream from enabled repository %s", strings.ToUpper(stream), id)
+			return stream
+		}
+	}
+	if strings.Contains(releasever, ".") {
+		for _, stream := range streams {
+			id := rm.channelRepoID("baseos", stream)
+			if repos.Repo(id) != nil {
+				log.Infof("release is pinned to %s, assuming %s update stream as repository %s is available", releasever, strings.ToUpper(stream), id)
+				return stream
+			}
+		}
+		log.Warnf("release is pinned to %s, but no repositories of EUS, E4S or AUS update streams are available; assuming standard update stream", releasever)
+	}
+	return ""
+}
+
+// channelRepoID maps RHEL channel to repository ID for given stream.
+func (rm *repoMgr) channelRepoID(channel, stream string) string {
+	channel = strings.ToLower(channel)
+	suffix := "rpms"
+	if stream != "" && slices.Contains(streamChannels[stream], channel) {
+		suffix = stream + "-rpms"
+	}
+	if channel == "crb" {
+		// CodeReady Linux Builder does not follow the usual naming.
+		return fmt.Sprintf("codeready-builder-for-rhel-%d-%s-%s", rm.systemInfo.OsVersion, rm.systemInfo.Arch, suffix)
+	}
+	return fmt.Sprintf("rhel-%d-for-%s-%s-%s", rm.systemInfo.OsVersion, rm.systemInfo.Arch, channel, suffix)
+}
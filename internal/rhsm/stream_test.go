@@ -0,0 +1,64 @@
+package rhsm
+
+import (
+	"testing"
+
+	"github.com/mizdebsk/rhel-drivers/internal/sysinfo"
+)
+
+func TestChannelRepoID(t *testing.T) {
+	rm := repoMgr{systemInfo: sysinfo.SysInfo{OsVersion: 9, Arch: "x86_64"}}
+	tests := []struct {
+		channel  string
+		stream   string
+		expected string
+	}{
+		{"BaseOS", "", "rhel-9-for-x86_64-baseos-rpms"},
+		{"BaseOS", streamEUS, "rhel-9-for-x86_64-baseos-eus-rpms"},
+		{"AppStream", streamE4S, "rhel-9-for-x86_64-appstream-e4s-rpms"},
+		{"AppStream", streamAUS, "rhel-9-for-x86_64-appstream-aus-rpms"},
+		{"Supplementary", streamEUS, "rhel-9-for-x86_64-supplementary-eus-rpms"},
+		{"Supplementary", streamE4S, "rhel-9-for-x86_64-supplementary-rpms"},
+		{"Extensions", streamEUS, "rhel-9-for-x86_64-extensions-rpms"},
+		{"CRB", "", "codeready-builder-for-rhel-9-x86_64-rpms"},
+		{"CRB", streamEUS, "codeready-builder-for-rhel-9-x86_64-eus-rpms"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.channel+"-"+tt.stream, func(t *testing.T) {
+			if got := rm.channelRepoID(tt.channel, tt.stream); got != tt.expected {
+				t.Errorf("channelRepoID(%q, %q) = %q, expected %q", tt.channel, tt.stream, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDetectStream(t *testing.T) {
+	tests := []struct {
+		name     string
+		dir      string
+		expected string
+	}{
+		{"Standard", "testdata", ""},
+		{"EnabledEUSRepo", "testdata/eus", streamEUS},
+		{"EnabledE4SRepo", "testdata/e4s", streamE4S},
+		{"EnabledAUSRepo", "testdata/aus", streamAUS},
+		{"PinnedRelease", "testdata/pinned", streamEUS},
+		{"PinnedReleaseWithoutStreamRepos", "testdata/pinned-standard", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rm := repoMgr{
+				systemInfo: sysinfo.SysInfo{IsRhel: true, OsVersion: 9, Arch: "x86_64"},
+				reposDir:   tt.dir + "/yum.repos.d",
+				varsDir:    tt.dir + "/vars",
+			}
+			repos, releasever, err := rm.loadRepos()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := rm.detectStream(repos, releasever); got != tt.expected {
+				t.Errorf("expected stream %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package rhsm
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+)
+
+const (
+	defaultConsumerCertPath   = "/etc/pki/consumer/cert.pem"
+	defaultEntitlementDir     = "/etc/pki/entitlement"
+	defaultContentAccessCache = "/var/lib/rhsm/cache/content_access_mode.json"
+
+	// Content access mode of organizations using Simple Content Access.
+	contentAccessModeSCA = "org_environment"
+)
+
+type SubscriptionProblem int
+
+const (
+	NotRegistered SubscriptionProblem = iota
+	NotEntitled
+)
+
+// SubscriptionError is returned when RHEL repositories cannot be
+// enabled because the system is not registered or has no access to
+// content.
+type SubscriptionError struct {
+	Problem SubscriptionProblem
+}
+
+func (e *SubscriptionError) Error() string {
+	if e.Problem == NotEntitled {
+		return "system has no entitlement providing access to RHEL content"
+	}
+	return "system is not registered with Red Hat Subscription Management"
+}
+
+// Guidance describes how to resolve the problem.
+func (e *SubscriptionError) Guidance() []string {
+	if e.Problem == NotEntitled {
+		return []string{
+			"Enable Simple Content Access for your organization in the Red Hat Customer Portal,",
+			"or attach a subscription with \"subscription-manager attach --auto\".",
+		}
+	}
+	return []string{
+		"Register this system with \"subscription-manager register\", or when using",
+		"Satellite or an activation key, with \"subscription-manager register",
+		"--org=ORG --activationkey=KEY\".  Then run rhel-drivers again.",
+	}
+}
+
+// checkSubscription verifies that the system is registered and entitled
+// to content, judging by certificates Subscription Manager keeps.
+func (rm *repoMgr) checkSubscription() error {
+	if _, err := os.Stat(rm.consumerCertPath); err != nil {
+		log.Debugf("stat %s failed: %v", rm.consumerCertPath, err)
+		return &SubscriptionError{Problem: NotRegistered}
+	}
+	log.Logf("system is registered")
+	if rm.simpleContentAccess() {
+		// Content access certificate is fetched on refresh if missing.
+		log.Logf("Simple Content Access is enabled")
+		return nil
+	}
+	certs, err := filepath.Glob(filepath.Join(rm.entitlementDir, "*.pem"))
+	if err != nil {
+		return err
+	}
+	for _, cert := range certs {
+		if !strings.HasSuffix(cert, "-key.pem") {
+			log.Logf("found entitlement certificate %s", cert)
+			return nil
+		}
+	}
+	return &SubscriptionError{Problem: NotEntitled}
+}
+
+// simpleContentAccess reports whether content access mode cached by
+// Subscription Manager is Simple Content Access.
+func (rm *repoMgr) simpleContentAccess() bool {
+	data, err := os.ReadFile(rm.contentAccessCache)
+	if err != nil {
+		log.Debugf("failed to read %s: %v", rm.contentAccessCache, err)
+		return false
+	}
+	var modes map[string]string
+	if err := json.Unmarshal(data, &modes); err != nil {
+		log.Warnf("failed to parse %s: %v", rm.contentAccessCache, err)
+		return false
+	}
+	for _, mode := range modes {
+		if mode == contentAccessModeSCA {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package rhsm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckSubscription(t *testing.T) {
+	const dir = "testdata/subscription/"
+	tests := []struct {
+		name           string
+		consumerCert   string
+		entitlementDir string
+		accessCache    string
+		expectProblem  *SubscriptionProblem
+	}{
+		{
+			name:           "Entitled",
+			consumerCert:   dir + "registered/consumer/cert.pem",
+			entitlementDir: dir + "registered/entitlement",
+			accessCache:    dir + "unentitled/content_access_mode.json",
+		},
+		{
+			name:           "SimpleContentAccess",
+			consumerCert:   dir + "unentitled/consumer/cert.pem",
+			entitlementDir: dir + "unentitled/entitlement",
+			accessCache:    dir + "sca/content_access_mode.json",
+		},
+		{
+			name:           "NotRegistered",
+			consumerCert:   dir + "sca/consumer/cert.pem",
+			entitlementDir: dir + "registered/entitlement",
+			accessCache:    dir + "sca/content_access_mode.json",
+			expectProblem:  problem(NotRegistered),
+		},
+		{
+			name:           "NotEntitled",
+			consumerCert:   dir + "unentitled/consumer/cert.pem",
+			entitlementDir: dir + "unentitled/entitlement",
+			accessCache:    dir + "unentitled/content_access_mode.json",
+			expectProblem:  problem(NotEntitled),
+		},
+		{
+			name:           "NoContentAccessCache",
+			consumerCert:   dir + "unentitled/consumer/cert.pem",
+			entitlementDir: dir + "unentitled/entitlement",
+			accessCache:    dir + "unentitled/missing.json",
+			expectProblem:  problem(NotEntitled),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rm := repoMgr{
+				consumerCertPath:   tt.consumerCert,
+				entitlementDir:     tt.entitlementDir,
+				contentAccessCache: tt.accessCache,
+			}
+			err := rm.checkSubscription()
+			if tt.expectProblem == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			var subErr *SubscriptionError
+			if !errors.As(err, &subErr) {
+				t.Fatalf("expected SubscriptionError, got %v", err)
+			}
+			if subErr.Problem != *tt.expectProblem {
+				t.Errorf("expected problem %v, got %v", *tt.expectProblem, subErr.Problem)
+			}
+			if len(subErr.Guidance()) == 0 {
+				t.Errorf("expected guidance for %v", subErr)
+			}
+		})
+	}
+}
+
+func problem(p SubscriptionProblem) *SubscriptionProblem {
+	return &p
+}
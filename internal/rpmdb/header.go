@@ -0,0 +1,108 @@
+package rpmdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+)
+
+const (
+	tagName      = 1000
+	tagVersion   = 1001
+	tagRelease   = 1002
+	tagEpoch     = 1003
+	tagArch      = 1022
+	tagSourceRPM = 1044
+
+	typeInt32       = 4
+	typeString      = 6
+	typeStringArray = 8
+	typeI18NString  = 9
+
+	entryInfoSize = 16
+	// Same limits as rpm itself enforces when loading headers.
+	maxIndexEntries = 0xffff
+	maxDataLength   = 256 * 1024 * 1024
+)
+
+type headerEntry struct {
+	tag    int32
+	typ    uint32
+	offset int32
+	count  uint32
+}
+
+type header struct {
+	entries map[int32]headerEntry
+	data    []byte
+}
+
+// parseHeader parses RPM header blob as stored in the database, that is
+// without the header magic and without the lead or signature.
+func parseHeader(blob []byte) (*header, error) {
+	if len(blob) < 8 {
+		return nil, fmt.Errorf("header too short")
+	}
+	il := binary.BigEndian.Uint32(blob)
+	dl := binary.BigEndian.Uint32(blob[4:])
+	if il == 0 || il > maxIndexEntries || dl > maxDataLength {
+		return nil, fmt.Errorf("header index count %d or data length %d out of range", il, dl)
+	}
+	dataStart := 8 + uint64(il)*entryInfoSize
+	if uint64(len(blob)) < dataStart+uint64(dl) {
+		return nil, fmt.Errorf("header truncated")
+	}
+	h := &header{
+		entries: make(map[int32]headerEntry, il),
+		data:    blob[dataStart : dataStart+uint64(dl)],
+	}
+	for i := uint64(0); i < uint64(il); i++ {
+		e := blob[8+i*entryInfoSize:]
+		entry := headerEntry{
+			tag:    int32(binary.BigEndian.Uint32(e)),
+			typ:    binary.BigEndian.Uint32(e[4:]),
+			offset: int32(binary.BigEndian.Uint32(e[8:])),
+			count:  binary.BigEndian.Uint32(e[12:]),
+		}
+		if entry.offset < 0 || uint32(entry.offset) > dl {
+			return nil, fmt.Errorf("header tag %d has invalid offset %d", entry.tag, entry.offset)
+		}
+		h.entries[entry.tag] = entry
+	}
+	return h, nil
+}
+
+// getString returns value of a string tag, or the first element for
+// array and i18n string tags.  Missing tags yield empty string.
+func (h *header) getString(tag int32) (string, error) {
+	e, ok := h.entries[tag]
+	if !ok {
+		return "", nil
+	}
+	if e.typ != typeString && e.typ != typeStringArray && e.typ != typeI18NString {
+		return "", fmt.Errorf("header tag %d has type %d, expected string", tag, e.typ)
+	}
+	s := h.data[e.offset:]
+	end := bytes.IndexByte(s, 0)
+	if end < 0 {
+		return "", fmt.Errorf("header tag %d is not NUL-terminated", tag)
+	}
+	return string(s[:end]), nil
+}
+
+// getInt32String returns decimal representation of an int32 tag, or
+// empty string if the tag is missing.
+func (h *header) getInt32String(tag int32) (string, error) {
+	e, ok := h.entries[tag]
+	if !ok {
+		return "", nil
+	}
+	if e.typ != typeInt32 || e.count < 1 {
+		return "", fmt.Errorf("header tag %d has type %d, expected int32", tag, e.typ)
+	}
+	if len(h.data) < int(e.offset)+4 {
+		return "", fmt.Errorf("header tag %d truncated", tag)
+	}
+	return strconv.FormatUint(uint64(binary.BigEndian.Uint32(h.data[e.offset:])), 10), nil
+}
@@ -0,0 +1,104 @@
+package rpmdb
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func buildHeader(entries []headerEntry, data string) []byte {
+	blob := binary.BigEndian.AppendUint32(nil, uint32(len(entries)))
+	blob = binary.BigEndian.AppendUint32(blob, uint32(len(data)))
+	for _, e := range entries {
+		blob = binary.BigEndian.AppendUint32(blob, uint32(e.tag))
+		blob = binary.BigEndian.AppendUint32(blob, e.typ)
+		blob = binary.BigEndian.AppendUint32(blob, uint32(e.offset))
+		blob = binary.BigEndian.AppendUint32(blob, e.count)
+	}
+	return append(blob, data...)
+}
+
+func TestParsePackage(t *testing.T) {
+	tests := []struct {
+		name      string
+		blob      []byte
+		expected  Package
+		expectErr bool
+	}{
+		{
+			name: "AllTags",
+			blob: buildHeader([]headerEntry{
+				{tagName, typeString, 0, 1},
+				{tagVersion, typeString, 4, 1},
+				{tagRelease, typeString, 8, 1},
+				{tagEpoch, typeInt32, 12, 1},
+				{tagArch, typeString, 16, 1},
+				{tagSourceRPM, typeString, 23, 1},
+			}, "foo\x001.0\x002.x\x00\x00\x00\x00\x0ax86_64\x00foo-1.0-2.x.src.rpm\x00"),
+			expected: Package{
+				Name:      "foo",
+				Epoch:     "10",
+				Version:   "1.0",
+				Release:   "2.x",
+				Arch:      "x86_64",
+				SourceRPM: "foo-1.0-2.x.src.rpm",
+			},
+		},
+		{
+			name: "MissingOptionalTags",
+			blob: buildHeader([]headerEntry{
+				{tagName, typeString, 0, 1},
+			}, "foo\x00"),
+			expected: Package{Name: "foo"},
+		},
+		{
+			name:      "TooShort",
+			blob:      []byte{0, 0, 0},
+			expectErr: true,
+		},
+		{
+			name:      "NoEntries",
+			blob:      buildHeader(nil, ""),
+			expectErr: true,
+		},
+		{
+			name: "Truncated",
+			blob: buildHeader([]headerEntry{
+				{tagName, typeString, 0, 1},
+			}, "foo\x00")[:20],
+			expectErr: true,
+		},
+		{
+			name: "OffsetOutOfRange",
+			blob: buildHeader([]headerEntry{
+				{tagName, typeString, 100, 1},
+			}, "foo\x00"),
+			expectErr: true,
+		},
+		{
+			name: "NotTerminated",
+			blob: buildHeader([]headerEntry{
+				{tagName, typeString, 0, 1},
+			}, "foo"),
+			expectErr: true,
+		},
+		{
+			name: "WrongType",
+			blob: buildHeader([]headerEntry{
+				{tagName, typeInt32, 0, 1},
+			}, "foo\x00"),
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg, err := parsePackage(tt.blob)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("parsePackage() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if !tt.expectErr && pkg != tt.expected {
+				t.Errorf("parsePackage() = %+v, want %+v", pkg, tt.expected)
+			}
+		})
+	}
+}
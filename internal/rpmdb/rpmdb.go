@@ -0,0 +1,87 @@
+package rpmdb
+
+import (
+	"fmt"
+
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+)
+
+const packagesTable = "Packages"
+
+type Package struct {
+	Name      string
+	Epoch     string
+	Version   string
+	Release   string
+	Arch      string
+	SourceRPM string
+}
+
+// ReadPackages lists packages recorded in the RPM database stored in
+// SQLite format (the default since RHEL 9), without invoking rpm.
+func ReadPackages(path string) ([]Package, error) {
+	log.Logf("reading RPM database %s", path)
+	db, err := openSqlite(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open RPM database: %w", err)
+	}
+	defer db.close()
+
+	root, err := db.findTableRoot(packagesTable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read RPM database %s: %w", path, err)
+	}
+
+	var pkgs []Package
+	err = db.walkTable(root, func(payload []byte) error {
+		cols, err := parseRecord(payload)
+		if err != nil {
+			return fmt.Errorf("bad package record: %w", err)
+		}
+		// Columns are hnum (alias of rowid, stored as NULL) and blob.
+		if len(cols) < 2 {
+			return fmt.Errorf("bad package record: expected 2 columns, got %d", len(cols))
+		}
+		blob, ok := cols[1].([]byte)
+		if !ok {
+			return fmt.Errorf("bad package record: header is not a blob")
+		}
+		pkg, err := parsePackage(blob)
+		if err != nil {
+			return err
+		}
+		pkgs = append(pkgs, pkg)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read RPM database %s: %w", path, err)
+	}
+	log.Debugf("read %d packages from RPM database", len(pkgs))
+	return pkgs, nil
+}
+
+func parsePackage(blob []byte) (Package, error) {
+	h, err := parseHeader(blob)
+	if err != nil {
+		return Package{}, fmt.Errorf("bad package header: %w", err)
+	}
+	var pkg Package
+	for _, f := range []struct {
+		tag int32
+		dst *string
+	}{
+		{tagName, &pkg.Name},
+		{tagVersion, &pkg.Version},
+		{tagRelease, &pkg.Release},
+		{tagArch, &pkg.Arch},
+		{tagSourceRPM, &pkg.SourceRPM},
+	} {
+		if *f.dst, err = h.getString(f.tag); err != nil {
+			return Package{}, fmt.Errorf("bad package header: %w", err)
+		}
+	}
+	if pkg.Epoch, err = h.getInt32String(tagEpoch); err != nil {
+		return Package{}, fmt.Errorf("bad package header: %w", err)
+	}
+	return pkg, nil
+}
@@ -0,0 +1,134 @@
+package rpmdb
+
+import (
+	"testing"
+)
+
+func findPackage(pkgs []Package, name string) []Package {
+	var found []Package
+	for _, pkg := range pkgs {
+		if pkg.Name == name {
+			found = append(found, pkg)
+		}
+	}
+	return found
+}
+
+func TestReadPackages(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		expectLen int
+		expected  []Package
+		absent    []string
+	}{
+		{
+			name:      "Plain",
+			path:      "testdata/rpmdb.sqlite",
+			expectLen: 44,
+			expected: []Package{
+				{
+					Name:      "bash",
+					Version:   "5.2.26",
+					Release:   "6.el10",
+					Arch:      "x86_64",
+					SourceRPM: "bash-5.2.26-6.el10.src.rpm",
+				},
+				{
+					Name:      "nvidia-driver",
+					Epoch:     "3",
+					Version:   "570.172.08",
+					Release:   "1.el10",
+					Arch:      "x86_64",
+					SourceRPM: "nvidia-driver-570.172.08-1.el10.src.rpm",
+				},
+				{
+					Name:    "gpg-pubkey",
+					Version: "fd431d51",
+					Release: "4ae0493b",
+				},
+				{
+					Name:      "filler-39",
+					Version:   "1.0",
+					Release:   "39.el10",
+					Arch:      "noarch",
+					SourceRPM: "filler-1.0-39.el10.src.rpm",
+				},
+			},
+		},
+		{
+			name:      "WriteAheadLog",
+			path:      "testdata/wal/rpmdb.sqlite",
+			expectLen: 44,
+			expected: []Package{
+				{
+					Name:      "nvidia-driver",
+					Epoch:     "3",
+					Version:   "570.172.08",
+					Release:   "1.el10",
+					Arch:      "x86_64",
+					SourceRPM: "nvidia-driver-570.172.08-1.el10.src.rpm",
+				},
+				{
+					Name:      "nvidia-driver",
+					Epoch:     "3",
+					Version:   "580.95.05",
+					Release:   "1.el10",
+					Arch:      "x86_64",
+					SourceRPM: "nvidia-driver-580.95.05-1.el10.src.rpm",
+				},
+			},
+			absent: []string{"bash"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkgs, err := ReadPackages(tt.path)
+			if err != nil {
+				t.Fatalf("ReadPackages(%q) error = %v", tt.path, err)
+			}
+			if len(pkgs) != tt.expectLen {
+				t.Errorf("ReadPackages(%q) returned %d packages, want %d", tt.path, len(pkgs), tt.expectLen)
+			}
+		outer:
+			for _, want := range tt.expected {
+				for _, got := range findPackage(pkgs, want.Name) {
+					if got == want {
+						continue outer
+					}
+				}
+				t.Errorf("package %+v not found, got %+v", want, findPackage(pkgs, want.Name))
+			}
+			for _, name := range tt.absent {
+				if found := findPackage(pkgs, name); len(found) > 0 {
+					t.Errorf("package %s expected to be absent, got %+v", name, found)
+				}
+			}
+		})
+	}
+}
+
+func TestReadPackagesErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+	}{
+		{
+			name: "MissingFile",
+			path: "testdata/does-not-exist.sqlite",
+		},
+		{
+			name: "NotSqlite",
+			path: "rpmdb_test.go",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadPackages(tt.path); err == nil {
+				t.Errorf("ReadPackages(%q) expected error, got nil", tt.path)
+			}
+		})
+	}
+}
@@ -0,0 +1,396 @@
+package rpmdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+)
+
+// Minimal read-only reader of SQLite 3 database files, just enough to
+// iterate rows of a table.  Indexes, views and writes are not supported.
+// See https://www.sqlite.org/fileformat.html
+
+const (
+	sqliteMagic      = "SQLite format 3\x00"
+	sqliteHeaderSize = 100
+
+	pageTypeInteriorTable = 0x05
+	pageTypeLeafTable     = 0x0d
+
+	walMagicLE      = 0x377f0682
+	walMagicBE      = 0x377f0683
+	walHeaderSize   = 32
+	walFrameHdrSize = 24
+
+	maxTreeDepth = 64
+)
+
+type sqliteDB struct {
+	f          *os.File
+	pageSize   int
+	usableSize int
+	// Committed pages from write-ahead log, they take precedence over
+	// pages in the main database file.
+	walPages map[uint32][]byte
+}
+
+func openSqlite(path string) (*sqliteDB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	db := &sqliteDB{f: f}
+	if err := db.readHeader(); err != nil {
+		db.close()
+		return nil, fmt.Errorf("invalid SQLite database %s: %w", path, err)
+	}
+	if err := db.readWal(path + "-wal"); err != nil {
+		db.close()
+		return nil, fmt.Errorf("invalid SQLite write-ahead log %s-wal: %w", path, err)
+	}
+	return db, nil
+}
+
+func (db *sqliteDB) close() {
+	if err := db.f.Close(); err != nil {
+		log.Warnf("failed to close file %s: %v", db.f.Name(), err)
+	}
+}
+
+func (db *sqliteDB) readHeader() error {
+	hdr := make([]byte, sqliteHeaderSize)
+	if _, err := io.ReadFull(db.f, hdr); err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+	if string(hdr[:16]) != sqliteMagic {
+		return fmt.Errorf("bad magic")
+	}
+	pageSize := int(binary.BigEndian.Uint16(hdr[16:]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return fmt.Errorf("bad page size %d", pageSize)
+	}
+	if enc := binary.BigEndian.Uint32(hdr[56:]); enc != 0 && enc != 1 {
+		return fmt.Errorf("unsupported text encoding %d", enc)
+	}
+	db.pageSize = pageSize
+	db.usableSize = pageSize - int(hdr[20])
+	if db.usableSize < 480 {
+		return fmt.Errorf("bad usable page size %d", db.usableSize)
+	}
+	return nil
+}
+
+func walChecksum(order binary.ByteOrder, s0, s1 uint32, b []byte) (uint32, uint32) {
+	for i := 0; i+8 <= len(b); i += 8 {
+		s0 += order.Uint32(b[i:]) + s1
+		s1 += order.Uint32(b[i+4:]) + s0
+	}
+	return s0, s1
+}
+
+// readWal loads committed frames from the write-ahead log, if there is
+// one.  rpm keeps its database in WAL mode, so recent transactions may
+// not have been checkpointed into the main file yet.
+func (db *sqliteDB) readWal(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if len(data) < walHeaderSize {
+		return nil
+	}
+	var order binary.ByteOrder
+	switch binary.BigEndian.Uint32(data) {
+	case walMagicLE:
+		order = binary.LittleEndian
+	case walMagicBE:
+		order = binary.BigEndian
+	default:
+		return fmt.Errorf("bad magic")
+	}
+	if int(binary.BigEndian.Uint32(data[8:])) != db.pageSize {
+		return fmt.Errorf("page size mismatch")
+	}
+	salt := data[16:24]
+	s0, s1 := walChecksum(order, 0, 0, data[:24])
+	if s0 != binary.BigEndian.Uint32(data[24:]) || s1 != binary.BigEndian.Uint32(data[28:]) {
+		// Header is not valid, so the log is empty.
+		return nil
+	}
+
+	committed := make(map[uint32][]byte)
+	pending := make(map[uint32][]byte)
+	frameSize := walFrameHdrSize + db.pageSize
+	for off := walHeaderSize; off+frameSize <= len(data); off += frameSize {
+		fh := data[off : off+walFrameHdrSize]
+		page := data[off+walFrameHdrSize : off+frameSize]
+		if !bytes.Equal(fh[8:16], salt) {
+			break
+		}
+		s0, s1 = walChecksum(order, s0, s1, fh[:8])
+		s0, s1 = walChecksum(order, s0, s1, page)
+		if s0 != binary.BigEndian.Uint32(fh[16:]) || s1 != binary.BigEndian.Uint32(fh[20:]) {
+			break
+		}
+		pending[binary.BigEndian.Uint32(fh)] = page
+		if binary.BigEndian.Uint32(fh[4:]) != 0 {
+			for pgno, p := range pending {
+				committed[pgno] = p
+			}
+			clear(pending)
+		}
+	}
+	if len(committed) > 0 {
+		log.Debugf("using %d pages from SQLite write-ahead log %s", len(committed), path)
+		db.walPages = committed
+	}
+	return nil
+}
+
+func (db *sqliteDB) readPage(pgno uint32) ([]byte, error) {
+	if pgno == 0 {
+		return nil, fmt.Errorf("invalid page number 0")
+	}
+	if page, ok := db.walPages[pgno]; ok {
+		return page, nil
+	}
+	page := make([]byte, db.pageSize)
+	if _, err := db.f.ReadAt(page, int64(pgno-1)*int64(db.pageSize)); err != nil {
+		return nil, fmt.Errorf("failed to read page %d: %w", pgno, err)
+	}
+	return page, nil
+}
+
+func readVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 9; i++ {
+		if i >= len(b) {
+			return 0, 0
+		}
+		if i == 8 {
+			return v<<8 | uint64(b[i]), 9
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return v, 9
+}
+
+// walkTable calls fn with the payload of every row of the table b-tree
+// rooted at given page.
+func (db *sqliteDB) walkTable(root uint32, fn func(payload []byte) error) error {
+	return db.walkTablePage(root, 0, fn)
+}
+
+func (db *sqliteDB) walkTablePage(pgno uint32, depth int, fn func(payload []byte) error) error {
+	if depth > maxTreeDepth {
+		return fmt.Errorf("b-tree too deep")
+	}
+	page, err := db.readPage(pgno)
+	if err != nil {
+		return err
+	}
+	hdrOff := 0
+	if pgno == 1 {
+		hdrOff = sqliteHeaderSize
+	}
+	if len(page) < hdrOff+12 {
+		return fmt.Errorf("page %d too short", pgno)
+	}
+	pageType := page[hdrOff]
+	numCells := int(binary.BigEndian.Uint16(page[hdrOff+3:]))
+	var cellPtrs []byte
+	switch pageType {
+	case pageTypeLeafTable:
+		cellPtrs = page[hdrOff+8:]
+	case pageTypeInteriorTable:
+		cellPtrs = page[hdrOff+12:]
+	default:
+		return fmt.Errorf("page %d is not a table b-tree page (type 0x%02x)", pgno, pageType)
+	}
+	if len(cellPtrs) < 2*numCells {
+		return fmt.Errorf("page %d: too many cells", pgno)
+	}
+
+	for i := 0; i < numCells; i++ {
+		off := int(binary.BigEndian.Uint16(cellPtrs[2*i:]))
+		if off >= db.usableSize {
+			return fmt.Errorf("page %d: cell %d out of bounds", pgno, i)
+		}
+		cell := page[off:db.usableSize]
+		if pageType == pageTypeInteriorTable {
+			if len(cell) < 4 {
+				return fmt.Errorf("page %d: cell %d truncated", pgno, i)
+			}
+			if err := db.walkTablePage(binary.BigEndian.Uint32(cell), depth+1, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		payload, err := db.readLeafPayload(cell)
+		if err != nil {
+			return fmt.Errorf("page %d: cell %d: %w", pgno, i, err)
+		}
+		if err := fn(payload); err != nil {
+			return err
+		}
+	}
+
+	if pageType == pageTypeInteriorTable {
+		return db.walkTablePage(binary.BigEndian.Uint32(page[hdrOff+8:]), depth+1, fn)
+	}
+	return nil
+}
+
+func (db *sqliteDB) readLeafPayload(cell []byte) ([]byte, error) {
+	size, n := readVarint(cell)
+	if n == 0 {
+		return nil, fmt.Errorf("truncated payload size")
+	}
+	cell = cell[n:]
+	if _, n = readVarint(cell); n == 0 {
+		return nil, fmt.Errorf("truncated rowid")
+	}
+	cell = cell[n:]
+
+	// Payload spill computation as described in the file format spec.
+	u := uint64(db.usableSize)
+	x := u - 35
+	local := size
+	if size > x {
+		m := ((u-12)*32/255 - 23)
+		k := m + (size-m)%(u-4)
+		if k <= x {
+			local = k
+		} else {
+			local = m
+		}
+	}
+	if uint64(len(cell)) < local {
+		return nil, fmt.Errorf("truncated payload")
+	}
+	payload := make([]byte, 0, size)
+	payload = append(payload, cell[:local]...)
+	if local == size {
+		return payload, nil
+	}
+	if uint64(len(cell)) < local+4 {
+		return nil, fmt.Errorf("truncated overflow pointer")
+	}
+	next := binary.BigEndian.Uint32(cell[local:])
+	for uint64(len(payload)) < size {
+		if next == 0 {
+			return nil, fmt.Errorf("overflow chain ended prematurely")
+		}
+		page, err := db.readPage(next)
+		if err != nil {
+			return nil, err
+		}
+		next = binary.BigEndian.Uint32(page)
+		chunk := page[4:db.usableSize]
+		if remaining := size - uint64(len(payload)); uint64(len(chunk)) > remaining {
+			chunk = chunk[:remaining]
+		}
+		payload = append(payload, chunk...)
+	}
+	return payload, nil
+}
+
+// parseRecord decodes a record into column values.  Integers are
+// returned as int64, text as string, blobs as []byte and NULLs as nil.
+// Floating point values are not supported.
+func parseRecord(rec []byte) ([]any, error) {
+	hdrSize, n := readVarint(rec)
+	if n == 0 || hdrSize > uint64(len(rec)) {
+		return nil, fmt.Errorf("bad record header")
+	}
+	hdr := rec[n:hdrSize]
+	body := rec[hdrSize:]
+	var cols []any
+	for len(hdr) > 0 {
+		st, n := readVarint(hdr)
+		if n == 0 {
+			return nil, fmt.Errorf("bad record header")
+		}
+		hdr = hdr[n:]
+		var size uint64
+		switch {
+		case st == 0 || st == 8 || st == 9:
+			size = 0
+		case st >= 1 && st <= 4:
+			size = st
+		case st == 5:
+			size = 6
+		case st == 6:
+			size = 8
+		case st >= 12:
+			size = (st - 12) / 2
+		default:
+			return nil, fmt.Errorf("unsupported serial type %d", st)
+		}
+		if size > uint64(len(body)) {
+			return nil, fmt.Errorf("truncated record body")
+		}
+		val := body[:size]
+		body = body[size:]
+		switch {
+		case st == 0:
+			cols = append(cols, nil)
+		case st == 8:
+			cols = append(cols, int64(0))
+		case st == 9:
+			cols = append(cols, int64(1))
+		case st <= 6:
+			var v int64
+			if len(val) > 0 && val[0]&0x80 != 0 {
+				v = -1
+			}
+			for _, b := range val {
+				v = v<<8 | int64(b)
+			}
+			cols = append(cols, v)
+		case st%2 == 0:
+			cols = append(cols, val)
+		default:
+			cols = append(cols, string(val))
+		}
+	}
+	return cols, nil
+}
+
+// findTableRoot looks up root page of given table in sqlite_schema.
+func (db *sqliteDB) findTableRoot(name string) (uint32, error) {
+	var root int64
+	err := db.walkTable(1, func(payload []byte) error {
+		cols, err := parseRecord(payload)
+		if err != nil {
+			return fmt.Errorf("bad schema record: %w", err)
+		}
+		if len(cols) < 4 || cols[0] != "table" || cols[1] != name {
+			return nil
+		}
+		if r, ok := cols[3].(int64); ok {
+			root = r
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if root <= 0 {
+		return 0, fmt.Errorf("table %s not found", name)
+	}
+	return uint32(root), nil
+}
@@ -0,0 +1,138 @@
+package rpmver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Constraint restricts versions, for example ">= 570, < 590".  All
+// comma-separated clauses must match.  Supported operators are =, !=,
+// <, <=, >, >= and ~> ("pessimistic": ~> 580.95 means >= 580.95 and
+// < 581).  Operands of = and != may end with ".*" to match all versions
+// with given prefix, eg. "= 580.*".  A bare version means "=".
+type Constraint []clause
+
+type clause struct {
+	op      string
+	version string
+}
+
+var operators = []string{"~>", ">=", "<=", "!=", "==", ">", "<", "="}
+
+// IsConstraint reports whether given string looks like a constraint
+// rather than a plain version.
+func IsConstraint(s string) bool {
+	return strings.ContainsAny(s, "<>=!*,") || strings.Contains(s, "~>")
+}
+
+func ParseConstraint(s string) (Constraint, error) {
+	var c Constraint
+	for _, part := range strings.Split(s, ",") {
+		cl, err := parseClause(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraint %q: %w", s, err)
+		}
+		c = append(c, cl)
+	}
+	return c, nil
+}
+
+func parseClause(s string) (clause, error) {
+	cl := clause{op: "="}
+	for _, op := range operators {
+		if rest, ok := strings.CutPrefix(s, op); ok {
+			cl.op = op
+			s = strings.TrimSpace(rest)
+			break
+		}
+	}
+	if cl.op == "==" {
+		cl.op = "="
+	}
+	cl.version = s
+	if s == "" {
+		return cl, fmt.Errorf("missing version")
+	}
+	if strings.ContainsAny(s, " <>=!:-") {
+		return cl, fmt.Errorf("bad version %q", s)
+	}
+	if prefix, ok := strings.CutSuffix(s, ".*"); ok {
+		if cl.op != "=" && cl.op != "!=" {
+			return cl, fmt.Errorf("wildcard is only allowed with = and !=")
+		}
+		if prefix == "" || strings.Contains(prefix, "*") {
+			return cl, fmt.Errorf("bad wildcard %q", s)
+		}
+	} else if strings.Contains(s, "*") {
+		return cl, fmt.Errorf("wildcard must be the last version component")
+	}
+	if cl.op == "~>" {
+		if _, err := pessimisticUpperBound(s); err != nil {
+			return cl, err
+		}
+	}
+	return cl, nil
+}
+
+// pessimisticUpperBound returns the first version not matched by
+// "~> version", that is version with its last component dropped and
+// the one before incremented.
+func pessimisticUpperBound(version string) (string, error) {
+	comps := strings.Split(version, ".")
+	if len(comps) > 1 {
+		comps = comps[:len(comps)-1]
+	}
+	last := len(comps) - 1
+	n, err := strconv.Atoi(comps[last])
+	if err != nil {
+		return "", fmt.Errorf("~> requires numeric version components, got %q", version)
+	}
+	comps[last] = strconv.Itoa(n + 1)
+	return strings.Join(comps, "."), nil
+}
+
+func (cl clause) match(version string) bool {
+	if prefix, ok := strings.CutSuffix(cl.version, ".*"); ok {
+		matched := version == prefix || strings.HasPrefix(version, prefix+".")
+		return matched == (cl.op == "=")
+	}
+	cmp := RpmVersionCompare(version, cl.version)
+	switch cl.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "~>":
+		upper, _ := pessimisticUpperBound(cl.version)
+		return cmp >= 0 && RpmVersionCompare(version, upper) < 0
+	}
+	return false
+}
+
+// Match reports whether given version satisfies the constraint.
+// A nil constraint matches any version.
+func (c Constraint) Match(version string) bool {
+	for _, cl := range c {
+		if !cl.match(version) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c Constraint) String() string {
+	var parts []string
+	for _, cl := range c {
+		parts = append(parts, cl.op+" "+cl.version)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,78 @@
+package rpmver
+
+import (
+	"testing"
+)
+
+func TestConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		matching   []string
+		other      []string
+		expectErr  bool
+	}{
+		{constraint: ">= 570", matching: []string{"570", "570.86.16", "580.95.05"}, other: []string{"565.57.01", "550"}},
+		{constraint: "< 590", matching: []string{"580.95.05", "589.99"}, other: []string{"590", "590.44.01", "600"}},
+		{constraint: ">= 570, < 590", matching: []string{"570.86.16", "580.95.05"}, other: []string{"565.57.01", "590.48.01"}},
+		{constraint: "= 580.*", matching: []string{"580", "580.95.05", "580.65.06"}, other: []string{"5800.1", "581.1", "570.86.16"}},
+		{constraint: "580.*", matching: []string{"580.95.05"}, other: []string{"581.1"}},
+		{constraint: "!= 575.*", matching: []string{"570.86.16", "580.95.05"}, other: []string{"575.51.03"}},
+		{constraint: "~> 580.95", matching: []string{"580.95", "580.95.05", "580.105.08"}, other: []string{"580.82.09", "581.0", "590.48.01"}},
+		{constraint: "~> 580", matching: []string{"580", "580.95.05"}, other: []string{"581", "570.86.16"}},
+		{constraint: "~>580.95.05", matching: []string{"580.95.05", "580.95.10"}, other: []string{"580.96", "580.95.04"}},
+		{constraint: "= 580.95.05", matching: []string{"580.95.05"}, other: []string{"580.95.06", "580.95"}},
+		{constraint: "== 580.95.05", matching: []string{"580.95.05"}, other: []string{"580.95.06"}},
+		{constraint: "> 580~beta", matching: []string{"580"}, other: []string{"580~alpha"}},
+		{constraint: "<= 570", matching: []string{"570", "565"}, other: []string{"570.1"}},
+		{constraint: "", expectErr: true},
+		{constraint: ">=", expectErr: true},
+		{constraint: ">= 570,", expectErr: true},
+		{constraint: "> 580.*", expectErr: true},
+		{constraint: "= 58*.1", expectErr: true},
+		{constraint: "~> rc.1", expectErr: true},
+		{constraint: ">> 570", expectErr: true},
+		{constraint: "= 580-1.el10", expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.constraint, func(t *testing.T) {
+			c, err := ParseConstraint(tc.constraint)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("ParseConstraint(%q) error = %v; expected error: %v", tc.constraint, err, tc.expectErr)
+			}
+			for _, v := range tc.matching {
+				if !c.Match(v) {
+					t.Errorf("%q does not match %q", tc.constraint, v)
+				}
+			}
+			for _, v := range tc.other {
+				if c.Match(v) {
+					t.Errorf("%q unexpectedly matches %q", tc.constraint, v)
+				}
+			}
+		})
+	}
+}
+
+func TestIsConstraint(t *testing.T) {
+	for s, expected := range map[string]bool{
+		"580.95.05":  false,
+		"580.95-1":   false,
+		"580~beta":   false,
+		">= 570":     true,
+		"580.*":      true,
+		"~> 580.95":  true,
+		"570,!= 575": true,
+	} {
+		if IsConstraint(s) != expected {
+			t.Errorf("IsConstraint(%q) = %v; want %v", s, !expected, expected)
+		}
+	}
+}
+
+func TestNilConstraintMatchesAll(t *testing.T) {
+	var c Constraint
+	if !c.Match("580.95.05") {
+		t.Errorf("nil constraint does not match")
+	}
+}
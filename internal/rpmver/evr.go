@@ -1,20 +1,77 @@
 package rpmver
 
-func CompareEVR(epoch1, version1, release1, epoch2, version2, release2 string) int {
-	e1 := "0"
-	if epoch1 != "" {
-		e1 = epoch1
+import (
+	"fmt"
+	"strings"
+)
+
+// EVR is epoch, version and release of a package.  Empty epoch is
+// equivalent to epoch 0.  Empty release is not a wildcard: Compare sorts
+// it before any release, so callers that want to match any release have
+// to compare releases only when given.
+type EVR struct {
+	Epoch   string
+	Version string
+	Release string
+}
+
+// ParseEVR parses "[epoch:]version[-release]".
+func ParseEVR(s string) (EVR, error) {
+	var evr EVR
+	rest := s
+	if epoch, after, ok := strings.Cut(rest, ":"); ok {
+		evr.Epoch = epoch
+		rest = after
+	}
+	evr.Version, evr.Release, _ = strings.Cut(rest, "-")
+	if err := evr.validate(); err != nil {
+		return EVR{}, fmt.Errorf("invalid EVR %q: %w", s, err)
 	}
-	e2 := "0"
-	if epoch2 != "" {
-		e2 = epoch2
+	if strings.HasSuffix(rest, "-") {
+		return EVR{}, fmt.Errorf("invalid EVR %q: empty release", s)
 	}
+	return evr, nil
+}
 
-	if e1 < e2 {
-		return -1
+func (evr EVR) validate() error {
+	if evr.Epoch != "" && strings.Trim(evr.Epoch, "0123456789") != "" {
+		return fmt.Errorf("epoch %q is not a number", evr.Epoch)
 	}
-	if e1 > e2 {
-		return 1
+	if evr.Version == "" {
+		return fmt.Errorf("empty version")
+	}
+	if strings.ContainsAny(evr.Version, ":-") {
+		return fmt.Errorf("version %q contains ':' or '-'", evr.Version)
+	}
+	if strings.ContainsAny(evr.Release, ":-") {
+		return fmt.Errorf("release %q contains ':' or '-'", evr.Release)
+	}
+	return nil
+}
+
+func (evr EVR) String() string {
+	s := evr.Version
+	if evr.Epoch != "" && evr.Epoch != "0" {
+		s = evr.Epoch + ":" + s
+	}
+	if evr.Release != "" {
+		s += "-" + evr.Release
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 if evr is older than, same as or newer
+// than other, the same way as rpm does.
+func (evr EVR) Compare(other EVR) int {
+	return CompareEVR(evr.Epoch, evr.Version, evr.Release, other.Epoch, other.Version, other.Release)
+}
+
+func CompareEVR(epoch1, version1, release1, epoch2, version2, release2 string) int {
+	// Epochs are numbers, so compare them as such rather than as strings
+	// ("10" is newer than "9").  rpmvercmp does just that for strings of
+	// digits, including ignoring leading zeros.
+	if ecmp := RpmVersionCompare(normalizeEpoch(epoch1), normalizeEpoch(epoch2)); ecmp != 0 {
+		return ecmp
 	}
 	vcmp := RpmVersionCompare(version1, version2)
 	if vcmp != 0 {
@@ -22,3 +79,10 @@ func CompareEVR(epoch1, version1, release1, epoch2, version2, release2 string) i
 	}
 	return RpmVersionCompare(release1, release2)
 }
+
+func normalizeEpoch(epoch string) string {
+	if epoch == "" {
+		return "0"
+	}
+	return epoch
+}
@@ -35,6 +35,14 @@ func TestCompareEVR(t *testing.T) {
 		{"empty release2", "0", "1.0", "1", "0", "1.0", "", 1},
 
 		{"all fields empty", "", "", "", "", "", "", 0},
+
+		{"numeric epoch 10 vs 9", "10", "1.0", "1", "9", "1.0", "1", 1},
+		{"numeric epoch 9 vs 10", "9", "1.0", "1", "10", "1.0", "1", -1},
+		{"epoch leading zeros", "01", "1.0", "1", "1", "1.0", "1", 0},
+		{"epoch dominates version", "1", "1.0", "1", "0", "99.0", "1", 1},
+		{"epoch dominates release", "2", "1.0", "1", "1", "1.0", "99", 1},
+		{"tilde in release", "0", "1.0", "1~beta", "0", "1.0", "1", -1},
+		{"caret in version", "0", "1.0^git1", "1", "0", "1.0", "1", 1},
 	}
 
 	for _, tc := range tests {
@@ -47,3 +55,48 @@ func TestCompareEVR(t *testing.T) {
 		})
 	}
 }
+
+func TestParseEVR(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  EVR
+		str       string
+		expectErr bool
+	}{
+		{input: "580.95.05", expected: EVR{Version: "580.95.05"}, str: "580.95.05"},
+		{input: "580.95.05-1.el10", expected: EVR{Version: "580.95.05", Release: "1.el10"}, str: "580.95.05-1.el10"},
+		{input: "3:580.95.05-1.el10", expected: EVR{Epoch: "3", Version: "580.95.05", Release: "1.el10"}, str: "3:580.95.05-1.el10"},
+		{input: "0:1.0", expected: EVR{Epoch: "0", Version: "1.0"}, str: "1.0"},
+		{input: "1.0~rc1^git2-3", expected: EVR{Version: "1.0~rc1^git2", Release: "3"}, str: "1.0~rc1^git2-3"},
+		{input: "", expectErr: true},
+		{input: "1:", expectErr: true},
+		{input: "-1", expectErr: true},
+		{input: "1.0-", expectErr: true},
+		{input: "x:1.0-1", expectErr: true},
+		{input: "1:2:3", expectErr: true},
+		{input: "1.0-1-2", expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			evr, err := ParseEVR(tc.input)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("ParseEVR(%q) error = %v; expected error: %v", tc.input, err, tc.expectErr)
+			}
+			if evr != tc.expected {
+				t.Errorf("ParseEVR(%q) = %+v; want %+v", tc.input, evr, tc.expected)
+			}
+			if err == nil && evr.String() != tc.str {
+				t.Errorf("ParseEVR(%q).String() = %q; want %q", tc.input, evr.String(), tc.str)
+			}
+		})
+	}
+}
+
+func TestEVRCompare(t *testing.T) {
+	a := EVR{Epoch: "10", Version: "1.0", Release: "1"}
+	b := EVR{Epoch: "9", Version: "2.0", Release: "1"}
+	if a.Compare(b) != 1 || b.Compare(a) != -1 || a.Compare(a) != 0 {
+		t.Errorf("EVR.Compare does not order %v and %v by numeric epoch", a, b)
+	}
+}
@@ -0,0 +1,48 @@
+package rpmver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NEVRA identifies a package build for a particular architecture.
+type NEVRA struct {
+	Name string
+	EVR
+	Arch string
+}
+
+// ParseNEVRA parses "name-[epoch:]version-release.arch", optionally
+// followed by ".rpm" as in file names and SOURCERPM tags.
+func ParseNEVRA(s string) (NEVRA, error) {
+	rest := strings.TrimSuffix(s, ".rpm")
+	lastDash := strings.LastIndex(rest, "-")
+	if lastDash < 0 {
+		return NEVRA{}, fmt.Errorf("invalid NEVRA %q: missing version and release", s)
+	}
+	prevDash := strings.LastIndex(rest[:lastDash], "-")
+	if prevDash < 0 {
+		return NEVRA{}, fmt.Errorf("invalid NEVRA %q: missing version or release", s)
+	}
+	var nevra NEVRA
+	nevra.Name = rest[:prevDash]
+	relArch := rest[lastDash+1:]
+	dot := strings.LastIndex(relArch, ".")
+	if dot < 0 {
+		return NEVRA{}, fmt.Errorf("invalid NEVRA %q: missing architecture", s)
+	}
+	nevra.Arch = relArch[dot+1:]
+	evr, err := ParseEVR(rest[prevDash+1:lastDash] + "-" + relArch[:dot])
+	if err != nil {
+		return NEVRA{}, fmt.Errorf("invalid NEVRA %q: %w", s, err)
+	}
+	nevra.EVR = evr
+	if nevra.Name == "" || nevra.Release == "" || nevra.Arch == "" {
+		return NEVRA{}, fmt.Errorf("invalid NEVRA %q: empty name, release or architecture", s)
+	}
+	return nevra, nil
+}
+
+func (n NEVRA) String() string {
+	return n.Name + "-" + n.EVR.String() + "." + n.Arch
+}
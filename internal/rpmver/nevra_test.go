@@ -0,0 +1,60 @@
+package rpmver
+
+import (
+	"testing"
+)
+
+func TestParseNEVRA(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  NEVRA
+		str       string
+		expectErr bool
+	}{
+		{
+			input:    "nvidia-driver-3:580.95.05-1.el10.x86_64",
+			expected: NEVRA{Name: "nvidia-driver", EVR: EVR{Epoch: "3", Version: "580.95.05", Release: "1.el10"}, Arch: "x86_64"},
+			str:      "nvidia-driver-3:580.95.05-1.el10.x86_64",
+		},
+		{
+			input:    "bash-5.3.0-2.fc43.src.rpm",
+			expected: NEVRA{Name: "bash", EVR: EVR{Version: "5.3.0", Release: "2.fc43"}, Arch: "src"},
+			str:      "bash-5.3.0-2.fc43.src",
+		},
+		{
+			input:    "this-is-a-complex-name-0.0.1-2.fc35.noarch",
+			expected: NEVRA{Name: "this-is-a-complex-name", EVR: EVR{Version: "0.0.1", Release: "2.fc35"}, Arch: "noarch"},
+			str:      "this-is-a-complex-name-0.0.1-2.fc35.noarch",
+		},
+		{
+			input:    "kernel-0:6.12.0-55.el10.aarch64",
+			expected: NEVRA{Name: "kernel", EVR: EVR{Epoch: "0", Version: "6.12.0", Release: "55.el10"}, Arch: "aarch64"},
+			str:      "kernel-6.12.0-55.el10.aarch64",
+		},
+		{input: "", expectErr: true},
+		{input: "foobar", expectErr: true},
+		{input: "foo-1.0", expectErr: true},
+		{input: "foo-1.0-1", expectErr: true},
+		{input: "-1.0-1.x86_64", expectErr: true},
+		{input: "foo--1.x86_64", expectErr: true},
+		{input: "foo-1.0-.x86_64", expectErr: true},
+		{input: "foo-1.0-1.", expectErr: true},
+		{input: "foo-x:1.0-1.x86_64", expectErr: true},
+		{input: "C--", expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			nevra, err := ParseNEVRA(tc.input)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("ParseNEVRA(%q) error = %v; expected error: %v", tc.input, err, tc.expectErr)
+			}
+			if nevra != tc.expected {
+				t.Errorf("ParseNEVRA(%q) = %+v; want %+v", tc.input, nevra, tc.expected)
+			}
+			if err == nil && nevra.String() != tc.str {
+				t.Errorf("ParseNEVRA(%q).String() = %q; want %q", tc.input, nevra.String(), tc.str)
+			}
+		})
+	}
+}
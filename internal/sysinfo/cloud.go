@@ -0,0 +1,128 @@
+package sysinfo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+)
+
+const (
+	pciDevicesDir = "/sys/bus/pci/devices"
+
+	// Chassis asset tag Azure sets on all virtual machines.
+	azureAssetTag = "7783-7084-3265-9085-8269-3286-77"
+)
+
+// PCI vendors of accelerators, and classes of display controllers and
+// processing accelerators (such as AMD Instinct).
+var (
+	acceleratorVendors = map[string]string{
+		"0x10de": "nvidia",
+		"0x1002": "amd",
+	}
+	acceleratorClasses = []string{"0x03", "0x12"}
+)
+
+type instanceFamily struct {
+	vendor string
+	// Number of accelerators by instance size.
+	counts map[string]int
+}
+
+// GPU instance families of AWS, which is the only one of supported
+// clouds that exposes instance type in DMI data.
+var awsInstanceFamilies = map[string]instanceFamily{
+	"p3":   {"nvidia", map[string]int{"2xlarge": 1, "8xlarge": 4, "16xlarge": 8}},
+	"p3dn": {"nvidia", map[string]int{"24xlarge": 8}},
+	"p4d":  {"nvidia", map[string]int{"24xlarge": 8}},
+	"p4de": {"nvidia", map[string]int{"24xlarge": 8}},
+	"p5":   {"nvidia", map[string]int{"48xlarge": 8}},
+	"p5e":  {"nvidia", map[string]int{"48xlarge": 8}},
+	"p5en": {"nvidia", map[string]int{"48xlarge": 8}},
+	"g4dn": {"nvidia", map[string]int{"xlarge": 1, "2xlarge": 1, "4xlarge": 1, "8xlarge": 1, "16xlarge": 1, "12xlarge": 4, "metal": 8}},
+	"g4ad": {"amd", map[string]int{"xlarge": 1, "2xlarge": 1, "4xlarge": 1, "8xlarge": 2, "16xlarge": 4}},
+	"g5":   {"nvidia", map[string]int{"xlarge": 1, "2xlarge": 1, "4xlarge": 1, "8xlarge": 1, "16xlarge": 1, "12xlarge": 4, "24xlarge": 4, "48xlarge": 8}},
+	"g5g":  {"nvidia", map[string]int{"xlarge": 1, "2xlarge": 1, "4xlarge": 1, "8xlarge": 1, "16xlarge": 2, "metal": 2}},
+	"g6":   {"nvidia", map[string]int{"xlarge": 1, "2xlarge": 1, "4xlarge": 1, "8xlarge": 1, "16xlarge": 1, "12xlarge": 4, "24xlarge": 4, "48xlarge": 8}},
+	"g6e":  {"nvidia", map[string]int{"xlarge": 1, "2xlarge": 1, "4xlarge": 1, "8xlarge": 1, "16xlarge": 1, "12xlarge": 4, "24xlarge": 4, "48xlarge": 8}},
+}
+
+// ExpectedAccelerators returns vendor and number of accelerators the
+// cloud instance type comes with, if known.  Only AWS instance types are
+// known, as Azure and Google Cloud do not expose them in DMI data.
+func (si SysInfo) ExpectedAccelerators() (string, int, bool) {
+	if si.Cloud != "aws" {
+		return "", 0, false
+	}
+	family, size, ok := strings.Cut(si.InstanceType, ".")
+	if !ok {
+		return "", 0, false
+	}
+	f, ok := awsInstanceFamilies[family]
+	if !ok {
+		return "", 0, false
+	}
+	count, ok := f.counts[size]
+	if !ok {
+		return "", 0, false
+	}
+	return f.vendor, count, true
+}
+
+// detectCloud identifies cloud provider and instance type from DMI
+// data.  Only AWS exposes instance type there, eg. "p4d.24xlarge".
+func detectCloud(dmiDir string) (string, string) {
+	vendor := readDMI(dmiDir, "sys_vendor")
+	product := readDMI(dmiDir, "product_name")
+	switch {
+	case vendor == "Amazon EC2":
+		log.Logf("running on AWS instance %s of type %s", readDMI(dmiDir, "board_asset_tag"), product)
+		if !strings.Contains(product, ".") {
+			return "aws", ""
+		}
+		return "aws", product
+	case readDMI(dmiDir, "chassis_asset_tag") == azureAssetTag:
+		log.Logf("running on Azure")
+		return "azure", ""
+	case vendor == "Google" || product == "Google Compute Engine":
+		log.Logf("running on Google Cloud")
+		return "gcp", ""
+	}
+	return "", ""
+}
+
+// countAccelerators counts GPUs and other accelerators on PCI bus by
+// vendor, eg. "nvidia".
+func countAccelerators(dir string) map[string]int {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Debugf("unable to list PCI devices: %v", err)
+		return nil
+	}
+	counts := make(map[string]int)
+	for _, entry := range entries {
+		vendor := readSysfsValue(filepath.Join(dir, entry.Name(), "vendor"))
+		name, ok := acceleratorVendors[vendor]
+		if !ok {
+			continue
+		}
+		class := readSysfsValue(filepath.Join(dir, entry.Name(), "class"))
+		for _, prefix := range acceleratorClasses {
+			if strings.HasPrefix(class, prefix) {
+				counts[name]++
+				break
+			}
+		}
+	}
+	return counts
+}
+
+func readSysfsValue(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,65 @@
+package sysinfo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectCloud(t *testing.T) {
+	tests := []struct {
+		dmi          string
+		cloud        string
+		instanceType string
+	}{
+		{dmi: "testdata/dmi-aws", cloud: "aws", instanceType: "p4d.24xlarge"},
+		{dmi: "testdata/dmi-azure", cloud: "azure"},
+		{dmi: "testdata/dmi-gcp", cloud: "gcp"},
+		{dmi: "testdata/dmi-hyperv"},
+		{dmi: "testdata/dmi-kvm"},
+		{dmi: "testdata/does-not-exist"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dmi, func(t *testing.T) {
+			cloud, instanceType := detectCloud(tt.dmi)
+			if cloud != tt.cloud || instanceType != tt.instanceType {
+				t.Fatalf("detectCloud(%q) = (%q, %q), want (%q, %q)", tt.dmi, cloud, instanceType, tt.cloud, tt.instanceType)
+			}
+		})
+	}
+}
+
+func TestCountAccelerators(t *testing.T) {
+	want := map[string]int{"nvidia": 4, "amd": 1}
+	if got := countAccelerators("testdata/pci"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("countAccelerators() = %v, want %v", got, want)
+	}
+}
+
+func TestExpectedAccelerators(t *testing.T) {
+	tests := []struct {
+		cloud        string
+		instanceType string
+		vendor       string
+		count        int
+		ok           bool
+	}{
+		{cloud: "aws", instanceType: "p4d.24xlarge", vendor: "nvidia", count: 8, ok: true},
+		{cloud: "aws", instanceType: "g5.12xlarge", vendor: "nvidia", count: 4, ok: true},
+		{cloud: "aws", instanceType: "g4ad.8xlarge", vendor: "amd", count: 2, ok: true},
+		{cloud: "aws", instanceType: "g5.huge"},
+		{cloud: "aws", instanceType: "m7i.large"},
+		{cloud: "aws"},
+		{cloud: "azure"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cloud+"/"+tt.instanceType, func(t *testing.T) {
+			si := SysInfo{Cloud: tt.cloud, InstanceType: tt.instanceType}
+			vendor, count, ok := si.ExpectedAccelerators()
+			if vendor != tt.vendor || count != tt.count || ok != tt.ok {
+				t.Fatalf("ExpectedAccelerators() = (%q, %d, %v), want (%q, %d, %v)", vendor, count, ok, tt.vendor, tt.count, tt.ok)
+			}
+		})
+	}
+}
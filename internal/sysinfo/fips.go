@@ -0,0 +1,39 @@
+package sysinfo
+
+import (
+	"os"
+	"strings"
+
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+)
+
+const (
+	fipsEnabledPath  = "/proc/sys/crypto/fips_enabled"
+	cryptoPolicyPath = "/etc/crypto-policies/state/current"
+)
+
+func detectFIPS(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Debugf("unable to read FIPS mode: %v", err)
+		return false
+	}
+	if strings.TrimSpace(string(data)) != "1" {
+		return false
+	}
+	log.Logf("FIPS mode is enabled")
+	return true
+}
+
+// readCryptoPolicy returns system-wide crypto policy, eg. "DEFAULT" or
+// "FIPS:OSPP", or empty string if the system has no crypto policies.
+func readCryptoPolicy(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Debugf("unable to read crypto policy: %v", err)
+		return ""
+	}
+	policy := strings.TrimSpace(string(data))
+	log.Logf("crypto policy: %s", policy)
+	return policy
+}
@@ -0,0 +1,210 @@
+package sysinfo
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+	"github.com/mizdebsk/rhel-drivers/internal/rpmver"
+)
+
+const (
+	kernelReleasePath = "/proc/sys/kernel/osrelease"
+	blsEntriesDir     = "/boot/loader/entries"
+	grubEnvPath       = "/boot/grub2/grubenv"
+)
+
+// Kernel identifies a kernel build by its release as reported by
+// "uname -r", eg. "6.12.0-55.el10.x86_64+rt".
+type Kernel struct {
+	Release string
+	// Kernel variant, eg. "rt", "64k", "debug" or "64k-debug", empty
+	// for the standard kernel.
+	Variant string
+}
+
+// ParseKernel splits kernel release into release proper and variant,
+// which RHEL kernels append after a plus sign.
+func ParseKernel(release string) Kernel {
+	_, variant, _ := strings.Cut(release, "+")
+	return Kernel{Release: release, Variant: variant}
+}
+
+func (k Kernel) String() string {
+	return k.Release
+}
+
+// Base returns kernel release without variant suffix.
+func (k Kernel) Base() string {
+	base, _, _ := strings.Cut(k.Release, "+")
+	return base
+}
+
+// Compare compares kernels by their version and release, ignoring
+// variants.
+func (k Kernel) Compare(other Kernel) int {
+	v1, r1, _ := strings.Cut(k.Base(), "-")
+	v2, r2, _ := strings.Cut(other.Base(), "-")
+	return rpmver.CompareEVR("", v1, r1, "", v2, r2)
+}
+
+// Matches reports whether module built for given kernel release can be
+// loaded by kernel k.  Variants must be the same, while dist tag and
+// architecture may be omitted from the release modules were built for,
+// eg. "6.12.0-55" matches "6.12.0-55.el10.x86_64".
+func (k Kernel) Matches(built string) bool {
+	b := ParseKernel(built)
+	if b.Variant != k.Variant {
+		return false
+	}
+	if b.Base() == k.Base() {
+		return true
+	}
+	// Rest of the release must not continue with further numbers, as in
+	// "6.12.0-55.1.el10_0", which is a different build than "6.12.0-55".
+	rest, ok := strings.CutPrefix(k.Base(), b.Base()+".")
+	return ok && rest != "" && (rest[0] < '0' || rest[0] > '9')
+}
+
+// TargetKernel returns the kernel drivers should be installed for, that
+// is the one booted by default, or the running kernel if the default is
+// not known.
+func (si SysInfo) TargetKernel() Kernel {
+	if si.DefaultKernel.Release != "" {
+		return si.DefaultKernel
+	}
+	return si.Kernel
+}
+
+// KernelFromPackage returns kernel installed by given package, if it is
+// kernel-core or the core package of a kernel variant, eg. kernel-rt-core.
+func KernelFromPackage(name, version, release, arch string) (Kernel, bool) {
+	variant, ok := strings.CutPrefix(name, "kernel-")
+	if !ok {
+		return Kernel{}, false
+	}
+	if variant == "core" {
+		variant = ""
+	} else if variant, ok = strings.CutSuffix(variant, "-core"); !ok {
+		return Kernel{}, false
+	}
+	k := Kernel{Release: version + "-" + release + "." + arch, Variant: variant}
+	if variant != "" {
+		k.Release += "+" + variant
+	}
+	return k, true
+}
+
+// NewestKernel returns the newest of given kernels, preferring the
+// standard kernel over variants of the same release.
+func NewestKernel(kernels []Kernel) Kernel {
+	if len(kernels) == 0 {
+		return Kernel{}
+	}
+	sorted := slices.Clone(kernels)
+	sortKernels(sorted)
+	return sorted[0]
+}
+
+func detectRunningKernel(path string) Kernel {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Debugf("unable to read kernel release: %v", err)
+		return Kernel{}
+	}
+	k := ParseKernel(strings.TrimSpace(string(data)))
+	log.Logf("running kernel %s", k)
+	return k
+}
+
+// detectDefaultKernel finds the kernel GRUB boots by default from Boot
+// Loader Specification entries, as managed by grubby.  That is the
+// entry saved in GRUB environment, or the newest one.
+func detectDefaultKernel(entriesDir, grubEnv string) Kernel {
+	entries, err := filepath.Glob(filepath.Join(entriesDir, "*.conf"))
+	if err != nil || len(entries) == 0 {
+		log.Debugf("no boot loader entries found in %s", entriesDir)
+		return Kernel{}
+	}
+	saved := readGrubEnv(grubEnv)["saved_entry"]
+	var kernels []Kernel
+	for _, entry := range entries {
+		k := readBLSEntry(entry)
+		if k.Release == "" {
+			continue
+		}
+		if strings.TrimSuffix(filepath.Base(entry), ".conf") == saved {
+			log.Logf("default boot kernel %s", k)
+			return k
+		}
+		kernels = append(kernels, k)
+	}
+	if len(kernels) == 0 {
+		return Kernel{}
+	}
+	sortKernels(kernels)
+	log.Logf("default boot kernel %s", kernels[0])
+	return kernels[0]
+}
+
+// readBLSEntry returns kernel booted by given entry, taken from name of
+// kernel image, or from entry version if the image is named differently.
+func readBLSEntry(file string) Kernel {
+	fields := readKeyValues(file, " ")
+	if image := path.Base(fields["linux"]); strings.HasPrefix(image, "vmlinuz-") {
+		return ParseKernel(strings.TrimPrefix(image, "vmlinuz-"))
+	}
+	if version := fields["version"]; version != "" {
+		return ParseKernel(version)
+	}
+	log.Debugf("boot loader entry %s does not identify kernel", file)
+	return Kernel{}
+}
+
+func readGrubEnv(file string) map[string]string {
+	return readKeyValues(file, "=")
+}
+
+// readKeyValues reads lines of key and value separated by sep, skipping
+// comments.  Missing file yields no values.
+func readKeyValues(file, sep string) map[string]string {
+	values := make(map[string]string)
+	f, err := os.Open(file)
+	if err != nil {
+		log.Debugf("unable to open %s: %v", file, err)
+		return values
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Warnf("failed to close file %s: %v", file, err)
+		}
+	}()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key, val, ok := strings.Cut(line, sep); ok {
+			values[key] = strings.TrimSpace(val)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Warnf("error reading %s: %v", file, err)
+	}
+	return values
+}
+
+func sortKernels(kernels []Kernel) {
+	sort.SliceStable(kernels, func(i, j int) bool {
+		if cmp := kernels[i].Compare(kernels[j]); cmp != 0 {
+			return cmp > 0
+		}
+		return kernels[i].Variant < kernels[j].Variant
+	})
+}
@@ -0,0 +1,150 @@
+package sysinfo
+
+import (
+	"testing"
+)
+
+func TestParseKernel(t *testing.T) {
+	tests := []struct {
+		release string
+		variant string
+		base    string
+	}{
+		{release: "6.12.0-55.el10.x86_64", variant: "", base: "6.12.0-55.el10.x86_64"},
+		{release: "6.12.0-55.el10.x86_64+rt", variant: "rt", base: "6.12.0-55.el10.x86_64"},
+		{release: "6.12.0-55.el10.aarch64+64k", variant: "64k", base: "6.12.0-55.el10.aarch64"},
+		{release: "6.12.0-55.el10.aarch64+64k-debug", variant: "64k-debug", base: "6.12.0-55.el10.aarch64"},
+		{release: "", variant: "", base: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.release, func(t *testing.T) {
+			k := ParseKernel(tt.release)
+			if k.Release != tt.release || k.Variant != tt.variant || k.Base() != tt.base {
+				t.Fatalf("ParseKernel(%q) = %+v with base %q, want variant %q and base %q",
+					tt.release, k, k.Base(), tt.variant, tt.base)
+			}
+		})
+	}
+}
+
+func TestKernelMatches(t *testing.T) {
+	tests := []struct {
+		kernel string
+		built  string
+		want   bool
+	}{
+		{kernel: "6.12.0-55.el10.x86_64", built: "6.12.0-55.el10.x86_64", want: true},
+		{kernel: "6.12.0-55.el10.x86_64", built: "6.12.0-55.el10", want: true},
+		{kernel: "6.12.0-55.el10.x86_64", built: "6.12.0-55", want: true},
+		{kernel: "6.12.0-55.1.el10_0.x86_64", built: "6.12.0-55", want: false},
+		{kernel: "6.12.0-55.el10.x86_64", built: "6.12.0-5", want: false},
+		{kernel: "6.12.0-55.el10.x86_64", built: "6.12.0-55.el10.x86_64+rt", want: false},
+		{kernel: "6.12.0-55.el10.x86_64+rt", built: "6.12.0-55.el10.x86_64", want: false},
+		{kernel: "6.12.0-55.el10.x86_64+rt", built: "6.12.0-55+rt", want: true},
+		{kernel: "6.12.0-55.el10.aarch64+64k", built: "6.12.0-55.el10.aarch64+64k-debug", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kernel+"/"+tt.built, func(t *testing.T) {
+			if got := ParseKernel(tt.kernel).Matches(tt.built); got != tt.want {
+				t.Fatalf("Kernel(%q).Matches(%q) = %v, want %v", tt.kernel, tt.built, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectRunningKernel(t *testing.T) {
+	want := Kernel{Release: "6.12.0-55.el10.x86_64+rt", Variant: "rt"}
+	if got := detectRunningKernel("testdata/osrelease-rt"); got != want {
+		t.Fatalf("detectRunningKernel() = %+v, want %+v", got, want)
+	}
+	if got := detectRunningKernel("testdata/does-not-exist"); got != (Kernel{}) {
+		t.Fatalf("detectRunningKernel() = %+v, want unknown kernel", got)
+	}
+}
+
+func TestKernelFromPackage(t *testing.T) {
+	tests := []struct {
+		name string
+		want Kernel
+		ok   bool
+	}{
+		{name: "kernel-core", want: Kernel{Release: "6.12.0-55.el10.x86_64"}, ok: true},
+		{name: "kernel-rt-core", want: Kernel{Release: "6.12.0-55.el10.x86_64+rt", Variant: "rt"}, ok: true},
+		{name: "kernel-64k-debug-core", want: Kernel{Release: "6.12.0-55.el10.x86_64+64k-debug", Variant: "64k-debug"}, ok: true},
+		{name: "kernel"},
+		{name: "kernel-modules"},
+		{name: "kernel-headers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := KernelFromPackage(tt.name, "6.12.0", "55.el10", "x86_64")
+			if got != tt.want || ok != tt.ok {
+				t.Fatalf("KernelFromPackage(%q) = %+v, %v; want %+v, %v", tt.name, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestNewestKernel(t *testing.T) {
+	kernels := []Kernel{
+		{Release: "6.12.0-55.el10.x86_64+rt", Variant: "rt"},
+		{Release: "6.12.0-61.el10.x86_64+debug", Variant: "debug"},
+		{Release: "6.12.0-61.el10.x86_64"},
+		{Release: "6.12.0-55.el10.x86_64"},
+	}
+	if got := NewestKernel(kernels); got != kernels[2] {
+		t.Fatalf("NewestKernel() = %+v, want %+v", got, kernels[2])
+	}
+	if kernels[0].Variant != "rt" {
+		t.Fatalf("NewestKernel() modified its argument")
+	}
+	if got := NewestKernel(nil); got != (Kernel{}) {
+		t.Fatalf("NewestKernel() = %+v, want unknown kernel", got)
+	}
+}
+
+func TestDetectDefaultKernel(t *testing.T) {
+	tests := []struct {
+		name string
+		root string
+		want Kernel
+	}{
+		{
+			name: "SavedEntry",
+			root: "testdata/kernels",
+			want: Kernel{Release: "6.12.0-55.el10.x86_64+rt", Variant: "rt"},
+		},
+		{
+			name: "NewestEntry",
+			root: "testdata/bls-newest",
+			want: Kernel{Release: "6.12.0-61.el10.x86_64+debug", Variant: "debug"},
+		},
+		{
+			name: "NoEntries",
+			root: "testdata/root",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := detectDefaultKernel(tt.root+blsEntriesDir, tt.root+grubEnvPath)
+			if got != tt.want {
+				t.Fatalf("detectDefaultKernel(%q) = %+v, want %+v", tt.root, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTargetKernel(t *testing.T) {
+	running := Kernel{Release: "6.12.0-55.el10.x86_64"}
+	def := Kernel{Release: "6.12.0-61.el10.x86_64"}
+	if got := (SysInfo{Kernel: running, DefaultKernel: def}).TargetKernel(); got != def {
+		t.Fatalf("TargetKernel() = %+v, want default kernel %+v", got, def)
+	}
+	if got := (SysInfo{Kernel: running}).TargetKernel(); got != running {
+		t.Fatalf("TargetKernel() = %+v, want running kernel %+v", got, running)
+	}
+}
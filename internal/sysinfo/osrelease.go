@@ -0,0 +1,159 @@
+package sysinfo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+)
+
+// Locations of os-release in order of precedence, see os-release(5).
+var osReleasePaths = []string{"/etc/os-release", "/usr/lib/os-release"}
+
+type osRelease struct {
+	id         string
+	idLike     []string
+	variantID  string
+	versionID  string
+	version    int
+	minor      int
+	prettyName string
+	platformID string
+	cpeName    string
+	supportEnd time.Time
+}
+
+// findOsRelease returns path of os-release file of the system installed
+// in given root directory.
+func findOsRelease(root string) string {
+	for _, path := range osReleasePaths {
+		path = filepath.Join(root, path)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(root, osReleasePaths[0])
+}
+
+func readOsRelease(path string) osRelease {
+	var rel osRelease
+	f, err := os.Open(path)
+	if err != nil {
+		log.Logf("unable to open %s for reading: %v", path, err)
+		return rel
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Warnf("failed to close file %s: %v", path, err)
+		}
+	}()
+
+	fields, err := parseOsRelease(f)
+	if err != nil {
+		log.Warnf("error parsing %s: %v", path, err)
+		return rel
+	}
+	rel.id = fields["ID"]
+	if val := fields["ID_LIKE"]; val != "" {
+		rel.idLike = strings.Fields(val)
+	}
+	rel.variantID = fields["VARIANT_ID"]
+	rel.prettyName = fields["PRETTY_NAME"]
+	rel.platformID = fields["PLATFORM_ID"]
+	rel.cpeName = fields["CPE_NAME"]
+	if rel.prettyName == "" {
+		rel.prettyName = fields["NAME"]
+	}
+	if val := fields["VERSION_ID"]; val != "" {
+		rel.versionID = val
+		major, minor, hasMinor := strings.Cut(val, ".")
+		if rel.version, err = strconv.Atoi(major); err != nil {
+			log.Warnf("invalid VERSION_ID %q in %s: %v", val, path, err)
+		}
+		if hasMinor {
+			minor, _, _ = strings.Cut(minor, ".")
+			if rel.minor, err = strconv.Atoi(minor); err != nil {
+				log.Warnf("invalid VERSION_ID %q in %s: %v", val, path, err)
+			}
+		}
+	}
+	if val := fields["SUPPORT_END"]; val != "" {
+		if rel.supportEnd, err = time.Parse(time.DateOnly, val); err != nil {
+			log.Warnf("invalid SUPPORT_END %q in %s: %v", val, path, err)
+		}
+	}
+	return rel
+}
+
+// parseOsRelease reads os-release variable assignments, which follow
+// shell syntax restricted to a single possibly quoted value per line.
+// Invalid lines are skipped with a warning, as the spec requires.
+func parseOsRelease(r io.Reader) (map[string]string, error) {
+	fields := make(map[string]string)
+	lineNum := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, val, ok := strings.Cut(line, "=")
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			log.Warnf("os-release line %d: expected KEY=value", lineNum)
+			continue
+		}
+		val, err := unquoteOsReleaseValue(val)
+		if err != nil {
+			log.Warnf("os-release line %d: %v", lineNum, err)
+			continue
+		}
+		fields[key] = val
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// unquoteOsReleaseValue removes shell quoting from value.  Within double
+// quotes, backslash escapes only $, `, ", \ and itself, while single
+// quotes preserve everything literally.
+func unquoteOsReleaseValue(val string) (string, error) {
+	var sb strings.Builder
+	var quote rune
+	escaped := false
+	for _, c := range val {
+		switch {
+		case escaped:
+			if quote == '"' && !strings.ContainsRune("$`\"\\", c) {
+				sb.WriteRune('\\')
+			}
+			sb.WriteRune(c)
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped = true
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && (c == ' ' || c == '\t'):
+			return "", fmt.Errorf("unquoted whitespace in value %s", val)
+		default:
+			sb.WriteRune(c)
+		}
+	}
+	if escaped {
+		return "", fmt.Errorf("trailing backslash in value %s", val)
+	}
+	if quote != 0 {
+		return "", fmt.Errorf("unterminated quote in value %s", val)
+	}
+	return sb.String(), nil
+}
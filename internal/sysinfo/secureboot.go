@@ -0,0 +1,203 @@
+package sysinfo
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+)
+
+const (
+	// EFI global variable holding Secure Boot state.  Content of efivarfs
+	// files starts with 4 bytes of attributes, followed by the value.
+	secureBootVarPath = "/sys/firmware/efi/efivars/SecureBoot-8be4df61-93ca-11d2-aa0d-00e098032b8c"
+	lockdownPath      = "/sys/kernel/security/lockdown"
+	sigEnforcePath    = "/sys/module/module/parameters/sig_enforce"
+	procKeysPath      = "/proc/keys"
+)
+
+// ModuleSigning describes whether the kernel requires kernel modules to
+// be signed and which keys it trusts for that.
+type ModuleSigning struct {
+	SecureBoot bool
+	// Lockdown mode, "none", "integrity" or "confidentiality", empty if
+	// the kernel does not support lockdown.
+	Lockdown string
+	// Set when module.sig_enforce is enabled on kernel command line.
+	SigEnforce bool
+	// Descriptions of asymmetric keys in kernel keyrings, including keys
+	// from .platform and .machine keyrings enrolled in firmware or MOK,
+	// eg. "Red Hat Enterprise Linux kernel signing key".
+	TrustedKeys []string
+}
+
+// Enforced reports whether the kernel refuses to load modules that are
+// not signed by a trusted key.
+func (ms ModuleSigning) Enforced() bool {
+	return ms.SecureBoot || ms.SigEnforce || (ms.Lockdown != "" && ms.Lockdown != "none")
+}
+
+// Trusts reports whether the kernel trusts any key whose description
+// contains given signer name.
+func (ms ModuleSigning) Trusts(signer string) bool {
+	for _, key := range ms.TrustedKeys {
+		if strings.Contains(key, signer) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckModule reports whether kernel module described by what can be
+// loaded, assuming it is signed by signer.  The signature itself is not
+// verified.  Empty signer means the module is not signed, eg. because
+// it is built locally.
+func (ms ModuleSigning) CheckModule(what, signer string) error {
+	trusted := signer != "" && ms.Trusts(signer)
+	switch {
+	case trusted:
+		log.Logf("%s is expected to be signed by %s key, which the kernel trusts", what, signer)
+	case signer == "":
+		log.Logf("%s is not signed", what)
+	default:
+		log.Logf("%s is expected to be signed by %s key, which the kernel does not trust", what, signer)
+	}
+	if trusted || !ms.Enforced() {
+		return nil
+	}
+	return &ModuleSigningError{Module: what, Signer: signer, SecureBoot: ms.SecureBoot}
+}
+
+// CheckUnverifiedModule reports kernel module described by what, whose
+// signer is not known, as not verified.  It is up to the user to make
+// sure the kernel trusts its key.
+func (ms ModuleSigning) CheckUnverifiedModule(what string) {
+	if ms.Enforced() {
+		log.Warnf("signature of %s was not verified, the kernel will refuse to load it unless it is signed by a trusted key", what)
+		return
+	}
+	log.Logf("signature of %s was not verified", what)
+}
+
+// ModuleSigningError is returned when installing a kernel module the
+// kernel would refuse to load.
+type ModuleSigningError struct {
+	Module     string
+	Signer     string
+	SecureBoot bool
+}
+
+func (e *ModuleSigningError) Error() string {
+	reason := "module signature enforcement"
+	if e.SecureBoot {
+		reason = "Secure Boot"
+	}
+	if e.Signer == "" {
+		return fmt.Sprintf("%s is not signed, but %s is enabled and the kernel would refuse to load it", e.Module, reason)
+	}
+	return fmt.Sprintf("%s is expected to be signed by %s key, which the kernel does not trust, but %s is enabled and the kernel would refuse to load it",
+		e.Module, e.Signer, reason)
+}
+
+// Guidance describes how to resolve the problem.
+func (e *ModuleSigningError) Guidance() []string {
+	if e.Signer == "" {
+		return []string{
+			"Sign the module with your own key and enroll the key with \"mokutil --import\",",
+			"then reboot and confirm the enrollment in MOK manager.  Alternatively disable",
+			"Secure Boot in firmware settings.",
+		}
+	}
+	return []string{
+		"Enroll the " + e.Signer + " public key with \"mokutil --import\", then reboot and",
+		"confirm the enrollment in MOK manager.  Alternatively disable Secure Boot in",
+		"firmware settings.",
+	}
+}
+
+func detectModuleSigning() ModuleSigning {
+	ms := ModuleSigning{
+		SecureBoot:  detectSecureBoot(secureBootVarPath),
+		Lockdown:    detectLockdown(lockdownPath),
+		SigEnforce:  readSysfsBool(sigEnforcePath),
+		TrustedKeys: readTrustedKeys(procKeysPath),
+	}
+	if ms.SecureBoot {
+		log.Logf("Secure Boot is enabled")
+	}
+	if ms.Lockdown != "" && ms.Lockdown != "none" {
+		log.Logf("kernel lockdown mode: %s", ms.Lockdown)
+	}
+	return ms
+}
+
+func detectSecureBoot(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Debugf("unable to read Secure Boot state: %v", err)
+		return false
+	}
+	return len(data) == 5 && data[4] == 1
+}
+
+// detectLockdown returns lockdown mode, which the kernel marks with
+// brackets among all modes, eg. "none [integrity] confidentiality".
+func detectLockdown(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Debugf("unable to read lockdown mode: %v", err)
+		return ""
+	}
+	for _, mode := range strings.Fields(string(data)) {
+		if strings.HasPrefix(mode, "[") && strings.HasSuffix(mode, "]") {
+			return strings.Trim(mode, "[]")
+		}
+	}
+	return ""
+}
+
+func readSysfsBool(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Debugf("unable to read %s: %v", path, err)
+		return false
+	}
+	val := strings.TrimSpace(string(data))
+	return val == "Y" || val == "1"
+}
+
+// readTrustedKeys lists descriptions of asymmetric keys visible in
+// /proc/keys, without key identifiers and algorithm details.
+func readTrustedKeys(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Debugf("unable to read kernel keys: %v", err)
+		return nil
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Warnf("failed to close file %s: %v", path, err)
+		}
+	}()
+	var keys []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Fields are ID, flags, usage, timeout, permissions, UID, GID,
+		// type (truncated to 9 characters) and description.
+		fields := strings.SplitN(strings.Join(strings.Fields(scanner.Text()), " "), " ", 9)
+		if len(fields) < 9 || !strings.HasPrefix(fields[7], "asymmetri") {
+			continue
+		}
+		desc, _, _ := strings.Cut(fields[8], ": X509.")
+		if i := strings.LastIndex(desc, ": "); i >= 0 {
+			desc = desc[:i]
+		}
+		keys = append(keys, desc)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Warnf("error reading %s: %v", path, err)
+	}
+	return keys
+}
@@ -0,0 +1,111 @@
+package sysinfo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDetectSecureBoot(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "testdata/secureboot-enabled", want: true},
+		{path: "testdata/secureboot-disabled", want: false},
+		{path: "testdata/does-not-exist", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := detectSecureBoot(tt.path); got != tt.want {
+				t.Fatalf("detectSecureBoot(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectLockdown(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "testdata/lockdown-integrity", want: "integrity"},
+		{path: "testdata/lockdown-none", want: "none"},
+		{path: "testdata/does-not-exist", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := detectLockdown(tt.path); got != tt.want {
+				t.Fatalf("detectLockdown(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadTrustedKeys(t *testing.T) {
+	want := []string{
+		"Red Hat Enterprise Linux kernel signing key",
+		"Red Hat Secure Boot CA 8",
+		"Microsoft Windows Production PCA 2011",
+		"Fedora Secure Boot CA",
+	}
+	if got := readTrustedKeys("testdata/proc-keys"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("readTrustedKeys() = %q, want %q", got, want)
+	}
+}
+
+func TestCheckModule(t *testing.T) {
+	keys := []string{"Red Hat Enterprise Linux kernel signing key"}
+	tests := []struct {
+		name    string
+		signing ModuleSigning
+		signer  string
+		wantErr bool
+	}{
+		{
+			name:    "TrustedKey",
+			signing: ModuleSigning{SecureBoot: true, TrustedKeys: keys},
+			signer:  "Red Hat",
+		},
+		{
+			name:    "UntrustedKey",
+			signing: ModuleSigning{SecureBoot: true, TrustedKeys: keys},
+			signer:  "NVIDIA",
+			wantErr: true,
+		},
+		{
+			name:    "Unsigned",
+			signing: ModuleSigning{SecureBoot: true, TrustedKeys: keys},
+			wantErr: true,
+		},
+		{
+			name:    "Lockdown",
+			signing: ModuleSigning{Lockdown: "integrity"},
+			wantErr: true,
+		},
+		{
+			name:    "SigEnforce",
+			signing: ModuleSigning{SigEnforce: true},
+			wantErr: true,
+		},
+		{
+			name:    "NotEnforced",
+			signing: ModuleSigning{Lockdown: "none", TrustedKeys: keys},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.signing.CheckModule("kernel module package kmod-foo", tt.signer)
+			var signErr *ModuleSigningError
+			if errors.As(err, &signErr) != tt.wantErr {
+				t.Fatalf("CheckModule() error = %v, want error: %v", err, tt.wantErr)
+			}
+			if tt.wantErr && (signErr.Signer != tt.signer || signErr.SecureBoot != tt.signing.SecureBoot || len(signErr.Guidance()) == 0) {
+				t.Fatalf("CheckModule() error = %+v, not matching %+v", signErr, tt.signing)
+			}
+		})
+	}
+}
@@ -1,33 +1,168 @@
 package sysinfo
 
 import (
-	"bufio"
 	"os"
+	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
-	"strings"
+	"time"
 
 	"github.com/mizdebsk/rhel-drivers/internal/log"
 )
 
-const osReleasePath = "/etc/os-release"
+const (
+	ostreeBootedPath = "/run/ostree-booted"
+)
 
 type SysInfo struct {
-	IsRhel    bool
-	OsVersion int
-	Arch      string
+	IsRhel bool
+	// Major and minor release from VERSION_ID, eg. 10 and 1 for "10.1".
+	// Minor release is 0 on distributions that don't have any, like
+	// CentOS Stream, OsVersionID tells these apart from x.0 releases.
+	OsVersion      int
+	OsMinorVersion int
+	OsVersionID    string
+	Arch           string
+	// Set on image mode (bootc or rpm-ostree) systems, where /usr is
+	// read-only and packages cannot be installed with dnf.
+	ImageMode bool
+	// Distribution identification from os-release, eg. "centos" with
+	// ID_LIKE "rhel fedora", or "fedora" with VARIANT_ID "eln".
+	OsID      string
+	OsIDLike  []string
+	VariantID string
+	// Further identification from os-release, eg. "platform:el10" and
+	// "cpe:/o:redhat:enterprise_linux:10.1".  SupportEnd is zero if
+	// not known.
+	OsPrettyName string
+	PlatformID   string
+	CPEName      string
+	SupportEnd   time.Time
+	// Running kernel, unknown when installing into alternate root, and
+	// the kernel booted by default.
+	Kernel        Kernel
+	DefaultKernel Kernel
+	// Secure Boot and module signature enforcement of the running
+	// kernel, not known when installing into alternate root.
+	ModuleSigning ModuleSigning
+	// Hypervisor as named by systemd-detect-virt, eg. "kvm" or
+	// "microsoft", empty on bare metal, and container engine, eg.
+	// "podman", empty outside of containers.
+	Hypervisor string
+	Container  string
+	// Set when building bootable container image, where drivers are
+	// installed into the image rather than for the running kernel.
+	BootcBuild bool
+	// UUIDs of NVIDIA vGPU mediated devices, present on vGPU hosts.
+	VGPUDevices []string
+	// Cloud provider, "aws", "azure" or "gcp", and instance type, eg.
+	// "p4d.24xlarge", if known.
+	Cloud        string
+	InstanceType string
+	// Numbers of GPUs and other accelerators on PCI bus, by vendor,
+	// eg. "nvidia" or "amd".
+	Accelerators map[string]int
+	// Set when the running kernel is in FIPS mode, and system-wide crypto
+	// policy, eg. "DEFAULT" or "FIPS:OSPP", if the system has any.
+	FIPS         bool
+	CryptoPolicy string
+	// Root directory of the target system when installing into an
+	// alternate root, empty for the running system.
+	Root string
 }
 
-func DetectSysInfo() SysInfo {
+// DetectSysInfo describes the system installed in given root directory,
+// or the running system if root is empty.  Architecture is always that
+// of the running system, as foreign architectures are not supported.
+func DetectSysInfo(root string) SysInfo {
 	arch := detectArch()
-	isRhel, osVersion := detectOs(osReleasePath)
+	rel := readOsRelease(findOsRelease(root))
+	imageMode := false
+	var kernel Kernel
+	var moduleSigning ModuleSigning
+	var hypervisor, container string
+	var bootcBuild bool
+	var vgpus []string
+	var cloud, instanceType string
+	var accelerators map[string]int
+	var fips bool
+	if root == "" {
+		imageMode = detectImageMode(ostreeBootedPath)
+		kernel = detectRunningKernel(kernelReleasePath)
+		moduleSigning = detectModuleSigning()
+		hypervisor = detectHypervisor(dmiDir, cpuinfoPath)
+		container = detectContainer(containerEnvPath, dockerEnvPath, systemdContainerPath, initCgroupPath)
+		if container != "" {
+			bootcBuild = detectBootcBuild(bootcDir)
+		}
+		vgpus = detectVGPUDevices(mdevDevicesDir)
+		logEnvironment(hypervisor, container, bootcBuild, vgpus)
+		if hypervisor != "" {
+			cloud, instanceType = detectCloud(dmiDir)
+		}
+		accelerators = countAccelerators(pciDevicesDir)
+		fips = detectFIPS(fipsEnabledPath)
+	} else {
+		log.Logf("using install root %s", root)
+	}
+	if !rel.supportEnd.IsZero() && time.Now().After(rel.supportEnd) {
+		log.Warnf("Support for %s ended on %s.", rel.prettyName, rel.supportEnd.Format(time.DateOnly))
+	}
 	return SysInfo{
-		IsRhel:    isRhel,
-		OsVersion: osVersion,
-		Arch:      arch,
+		IsRhel:         rel.id == "rhel",
+		OsVersion:      rel.version,
+		OsMinorVersion: rel.minor,
+		OsVersionID:    rel.versionID,
+		Arch:           arch,
+		ImageMode:      imageMode,
+		OsID:           rel.id,
+		OsIDLike:       rel.idLike,
+		VariantID:      rel.variantID,
+		OsPrettyName:   rel.prettyName,
+		PlatformID:     rel.platformID,
+		CPEName:        rel.cpeName,
+		SupportEnd:     rel.supportEnd,
+		Kernel:         kernel,
+		DefaultKernel:  detectDefaultKernel(filepath.Join(root, blsEntriesDir), filepath.Join(root, grubEnvPath)),
+		ModuleSigning:  moduleSigning,
+		Hypervisor:     hypervisor,
+		Container:      container,
+		BootcBuild:     bootcBuild,
+		VGPUDevices:    vgpus,
+		Cloud:          cloud,
+		InstanceType:   instanceType,
+		Accelerators:   accelerators,
+		FIPS:           fips,
+		CryptoPolicy:   readCryptoPolicy(filepath.Join(root, cryptoPolicyPath)),
+		Root:           root,
 	}
 }
 
+func logEnvironment(hypervisor, container string, bootcBuild bool, vgpus []string) {
+	if hypervisor != "" {
+		log.Logf("running in %s virtual machine", hypervisor)
+	}
+	if bootcBuild {
+		log.Logf("building bootable container image in %s", container)
+	} else if container != "" {
+		log.Logf("running in %s container", container)
+	}
+	if len(vgpus) > 0 {
+		log.Logf("found %d NVIDIA vGPU mediated devices", len(vgpus))
+	}
+}
+
+func detectImageMode(path string) bool {
+	_, err := os.Stat(path)
+	if err != nil {
+		log.Debugf("stat %s failed: %v", path, err)
+		return false
+	}
+	log.Logf("detected image mode system")
+	return true
+}
+
 func detectArch() string {
 	switch runtime.GOARCH {
 	case "amd64":
@@ -40,44 +175,29 @@ func detectArch() string {
 	}
 }
 
-func detectOs(path string) (bool, int) {
-	f, err := os.Open(path)
-	if err != nil {
-		log.Logf("unable to open %s for reading: %v", path, err)
-		return false, 0
+// Release returns full release number, eg. "10.1", falling back to
+// the major release if VERSION_ID is not known.
+func (si SysInfo) Release() string {
+	if si.OsVersionID != "" {
+		return si.OsVersionID
 	}
-	defer func() {
-		if err := f.Close(); err != nil {
-			log.Warnf("failed to close file %s: %v", path, err)
-		}
-	}()
+	return strconv.Itoa(si.OsVersion)
+}
 
-	var isRhel bool
-	var osVersion int
+// IsLike reports whether the distribution is given one or derived from
+// it, according to os-release ID and ID_LIKE.
+func (si SysInfo) IsLike(id string) bool {
+	return si.OsID == id || slices.Contains(si.OsIDLike, id)
+}
 
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if strings.HasPrefix(line, "ID=") {
-			val := strings.TrimPrefix(line, "ID=")
-			val = strings.Trim(val, `"`)
-			isRhel = val == "rhel"
-		} else if strings.HasPrefix(line, "VERSION_ID=") {
-			val := strings.TrimPrefix(line, "VERSION_ID=")
-			val = strings.Trim(val, `"`)
-			if idx := strings.IndexByte(val, '.'); idx >= 0 {
-				val = val[:idx]
-			}
-			n, err := strconv.Atoi(val)
-			if err != nil {
-				log.Warnf("invalid VERSION_ID %q in %s: %v", val, path, err)
-			}
-			osVersion = n
-		}
-	}
-	if err := scanner.Err(); err != nil {
-		log.Warnf("error parsing %s: %v", path, err)
-		return false, 0
+// MultilibArch returns architecture of 32-bit compatibility packages
+// that can be installed alongside native packages, or empty string if
+// given architecture has no multilib support.
+func MultilibArch(arch string) string {
+	switch arch {
+	case "x86_64":
+		return "i686"
+	default:
+		return ""
 	}
-	return isRhel, osVersion
 }
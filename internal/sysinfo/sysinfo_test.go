@@ -1,7 +1,9 @@
 package sysinfo
 
 import (
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestDetectRhelVersion(t *testing.T) {
@@ -11,12 +13,14 @@ func TestDetectRhelVersion(t *testing.T) {
 		path      string
 		isRhel    bool
 		osVersion int
+		osMinor   int
 	}{
 		{
 			name:      "RHEL 10.1",
 			path:      "testdata/os-release-rhel-10.1",
 			isRhel:    true,
 			osVersion: 10,
+			osMinor:   1,
 		},
 		{
 			name:      "Fedora ELN 44",
@@ -30,14 +34,280 @@ func TestDetectRhelVersion(t *testing.T) {
 			isRhel:    false,
 			osVersion: 43,
 		},
+		{
+			name:      "Rocky Linux 8.10",
+			path:      "testdata/os-release-rocky-8.10",
+			isRhel:    false,
+			osVersion: 8,
+			osMinor:   10,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			isRhel, rhelVersion := detectOs(tt.path)
-			if isRhel != tt.isRhel || rhelVersion != tt.osVersion {
-				t.Fatalf("detectOs(%q) = (%v, %v), want (%v, %v)", tt.path, isRhel, rhelVersion, tt.isRhel, tt.osVersion)
+			rel := readOsRelease(tt.path)
+			isRhel := rel.id == "rhel"
+			if isRhel != tt.isRhel || rel.version != tt.osVersion || rel.minor != tt.osMinor {
+				t.Fatalf("readOsRelease(%q) = (%v, %v.%v), want (%v, %v.%v)", tt.path,
+					isRhel, rel.version, rel.minor, tt.isRhel, tt.osVersion, tt.osMinor)
 			}
 		})
 	}
 }
+
+func TestReadOsRelease(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		path     string
+		expected osRelease
+	}{
+		{
+			path: "testdata/os-release-rhel-10.1",
+			expected: osRelease{
+				id:         "rhel",
+				idLike:     []string{"centos", "fedora"},
+				versionID:  "10.1",
+				version:    10,
+				minor:      1,
+				prettyName: "Red Hat Enterprise Linux 10.1 (Coughlan)",
+				platformID: "platform:el10",
+				cpeName:    "cpe:/o:redhat:enterprise_linux:10.1",
+			},
+		},
+		{
+			path: "testdata/os-release-eln",
+			expected: osRelease{
+				id:         "fedora",
+				variantID:  "eln",
+				versionID:  "44",
+				version:    44,
+				prettyName: "Fedora ELN",
+				cpeName:    "cpe:/o:fedoraproject:fedora:44",
+				supportEnd: date(2027, time.May, 19),
+			},
+		},
+		{
+			path: "testdata/os-release-centos-stream-10",
+			expected: osRelease{
+				id:         "centos",
+				idLike:     []string{"rhel", "fedora"},
+				versionID:  "10",
+				version:    10,
+				prettyName: "CentOS Stream 10 (Coughlan)",
+				platformID: "platform:el10",
+				cpeName:    "cpe:/o:centos:centos:10",
+			},
+		},
+		{
+			path: "testdata/os-release-almalinux-9.6",
+			expected: osRelease{
+				id:         "almalinux",
+				idLike:     []string{"rhel", "centos", "fedora"},
+				versionID:  "9.6",
+				version:    9,
+				minor:      6,
+				prettyName: "AlmaLinux 9.6 (Sage Margay)",
+				platformID: "platform:el9",
+				cpeName:    "cpe:/o:almalinux:almalinux:9::baseos",
+				supportEnd: date(2032, time.June, 1),
+			},
+		},
+		{
+			path: "testdata/os-release-rocky-8.10",
+			expected: osRelease{
+				id:         "rocky",
+				idLike:     []string{"rhel", "centos", "fedora"},
+				versionID:  "8.10",
+				version:    8,
+				minor:      10,
+				prettyName: "Rocky Linux 8.10 (Green Obsidian)",
+				platformID: "platform:el8",
+				cpeName:    "cpe:/o:rocky:rocky:8:GA",
+				supportEnd: date(2029, time.May, 31),
+			},
+		},
+		{
+			path: "testdata/os-release-quoting",
+			expected: osRelease{
+				id:         "rhel",
+				idLike:     []string{"fedora"},
+				versionID:  "9.4",
+				version:    9,
+				minor:      4,
+				prettyName: `Quoted "Linux" $9 \\n's`,
+				cpeName:    "cpe:/o:redhat:enterprise_linux:9::baseos",
+			},
+		},
+		{
+			path: "testdata/does-not-exist",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if rel := readOsRelease(tt.path); !reflect.DeepEqual(rel, tt.expected) {
+				t.Fatalf("readOsRelease(%q) = %+v, want %+v", tt.path, rel, tt.expected)
+			}
+		})
+	}
+}
+
+func TestUnquoteOsReleaseValue(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+		anyErr   bool
+	}{
+		{value: "plain", expected: "plain"},
+		{value: `"double quoted"`, expected: "double quoted"},
+		{value: `'single quoted'`, expected: "single quoted"},
+		{value: `"say \"hi\" for \$5 \\ \` + "`" + `"`, expected: `say "hi" for $5 \ ` + "`"},
+		{value: `"keep \n"`, expected: `keep \n`},
+		{value: `'no \escapes "here"'`, expected: `no \escapes "here"`},
+		{value: `escaped\ space`, expected: "escaped space"},
+		{value: `mixed"quo"'ting'`, expected: "mixedquoting"},
+		{value: "", expected: ""},
+		{value: `"unterminated`, anyErr: true},
+		{value: `unquoted space`, anyErr: true},
+		{value: `trailing\`, anyErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := unquoteOsReleaseValue(tt.value)
+			if (err != nil) != tt.anyErr {
+				t.Fatalf("unquoteOsReleaseValue(%q) error = %v, want error: %v", tt.value, err, tt.anyErr)
+			}
+			if got != tt.expected {
+				t.Fatalf("unquoteOsReleaseValue(%q) = %q, want %q", tt.value, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFindOsRelease(t *testing.T) {
+	tests := []struct {
+		root     string
+		expected string
+	}{
+		{root: "testdata/root", expected: "testdata/root/etc/os-release"},
+		{root: "testdata/root-usrlib", expected: "testdata/root-usrlib/usr/lib/os-release"},
+		{root: "testdata/does-not-exist", expected: "testdata/does-not-exist/etc/os-release"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.root, func(t *testing.T) {
+			if got := findOsRelease(tt.root); got != tt.expected {
+				t.Fatalf("findOsRelease(%q) = %q, want %q", tt.root, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIsLike(t *testing.T) {
+	si := SysInfo{OsID: "almalinux", OsIDLike: []string{"rhel", "centos", "fedora"}}
+	if !si.IsLike("almalinux") || !si.IsLike("rhel") || si.IsLike("debian") {
+		t.Fatalf("IsLike gives wrong answers for %+v", si)
+	}
+}
+
+func TestDetectImageMode(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want bool
+	}{
+		{
+			name: "ImageMode",
+			path: "testdata/ostree-booted",
+			want: true,
+		},
+		{
+			name: "PackageMode",
+			path: "testdata/does-not-exist",
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectImageMode(tt.path); got != tt.want {
+				t.Fatalf("detectImageMode(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMultilibArch(t *testing.T) {
+	tests := []struct {
+		arch string
+		want string
+	}{
+		{"x86_64", "i686"},
+		{"aarch64", ""},
+		{"ppc64le", ""},
+		{"s390x", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arch, func(t *testing.T) {
+			if got := MultilibArch(tt.arch); got != tt.want {
+				t.Fatalf("MultilibArch(%q) = %q, want %q", tt.arch, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectSysInfoInstallRoot(t *testing.T) {
+	info := DetectSysInfo("testdata/root")
+	if !info.IsRhel || info.OsVersion != 10 || info.Root != "testdata/root" || info.ImageMode {
+		t.Fatalf("DetectSysInfo(%q) = %+v, want RHEL 10 without image mode", "testdata/root", info)
+	}
+}
+
+func TestDetectSysInfoUsrLib(t *testing.T) {
+	info := DetectSysInfo("testdata/root-usrlib")
+	if info.OsID != "centos" || info.OsVersion != 9 || info.OsVersionID != "9" || info.PlatformID != "platform:el9" {
+		t.Fatalf("DetectSysInfo(%q) = %+v, want CentOS Stream 9", "testdata/root-usrlib", info)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	if got := (SysInfo{OsVersion: 10, OsMinorVersion: 1, OsVersionID: "10.1"}).Release(); got != "10.1" {
+		t.Fatalf("Release() = %q, want %q", got, "10.1")
+	}
+	if got := (SysInfo{OsVersion: 9}).Release(); got != "9" {
+		t.Fatalf("Release() = %q, want %q", got, "9")
+	}
+}
+
+func TestDetectFIPS(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "testdata/fips-enabled", want: true},
+		{path: "testdata/fips-disabled", want: false},
+		{path: "testdata/does-not-exist", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := detectFIPS(tt.path); got != tt.want {
+				t.Fatalf("detectFIPS(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectCryptoPolicy(t *testing.T) {
+	if info := DetectSysInfo("testdata/root"); info.CryptoPolicy != "FIPS:OSPP" || info.FIPS {
+		t.Fatalf("DetectSysInfo(%q) = %+v, want FIPS:OSPP crypto policy without FIPS mode of running kernel", "testdata/root", info)
+	}
+	if info := DetectSysInfo("testdata/root-usrlib"); info.CryptoPolicy != "" {
+		t.Fatalf("DetectSysInfo(%q) has crypto policy %q, want none", "testdata/root-usrlib", info.CryptoPolicy)
+	}
+}
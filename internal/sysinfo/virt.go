@@ -0,0 +1,174 @@
+package sysinfo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+)
+
+const (
+	dmiDir               = "/sys/class/dmi/id"
+	cpuinfoPath          = "/proc/cpuinfo"
+	containerEnvPath     = "/run/.containerenv"
+	dockerEnvPath        = "/.dockerenv"
+	systemdContainerPath = "/run/systemd/container"
+	initCgroupPath       = "/proc/1/cgroup"
+	bootcDir             = "/usr/lib/bootc"
+	mdevDevicesDir       = "/sys/bus/mdev/devices"
+
+	nvidiaPCIVendor = "0x10de"
+)
+
+// DMI vendor and product strings identifying hypervisors, checked in
+// order, with names used by systemd-detect-virt.
+var dmiHypervisors = []struct {
+	match      string
+	hypervisor string
+}{
+	{"KVM", "kvm"},
+	{"OpenStack", "kvm"},
+	{"KubeVirt", "kvm"},
+	{"Amazon EC2", "amazon"},
+	{"QEMU", "qemu"},
+	{"VMware", "vmware"},
+	{"VMW", "vmware"},
+	{"innotek GmbH", "oracle"},
+	{"VirtualBox", "oracle"},
+	{"Xen", "xen"},
+	{"Bochs", "bochs"},
+	{"Parallels", "parallels"},
+	{"BHYVE", "bhyve"},
+	{"Hyper-V", "microsoft"},
+	{"Google Compute Engine", "google"},
+}
+
+// Substrings of init process cgroup path identifying container engines.
+var cgroupContainers = []struct {
+	match     string
+	container string
+}{
+	{"libpod", "podman"},
+	{"/docker", "docker"},
+	{"kubepods", "kubernetes"},
+	{"/lxc/", "lxc"},
+}
+
+// detectHypervisor identifies hypervisor from DMI data, or returns
+// "vm-other" if CPU reports running under unknown hypervisor.  Empty
+// string is returned on bare metal.
+func detectHypervisor(dmiDir, cpuinfo string) string {
+	var fields []string
+	for _, name := range []string{"sys_vendor", "product_name", "board_vendor", "bios_vendor"} {
+		fields = append(fields, readDMI(dmiDir, name))
+	}
+	// EC2 bare metal instances carry the same DMI vendor as virtual
+	// ones, eg. product "g4dn.metal" or "m7i.metal-24xl".
+	if fields[0] == "Amazon EC2" && strings.Contains(fields[1], ".metal") {
+		return ""
+	}
+	for _, hv := range dmiHypervisors {
+		for _, field := range fields {
+			if strings.Contains(field, hv.match) {
+				return hv.hypervisor
+			}
+		}
+	}
+	// Microsoft makes physical machines too, so check the product.
+	if fields[0] == "Microsoft Corporation" && fields[1] == "Virtual Machine" {
+		return "microsoft"
+	}
+	if data, err := os.ReadFile(cpuinfo); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			key, val, ok := strings.Cut(line, ":")
+			if ok && strings.TrimSpace(key) == "flags" && containsWord(val, "hypervisor") {
+				return "vm-other"
+			}
+		}
+	}
+	return ""
+}
+
+// detectContainer identifies container engine the process runs in, or
+// returns empty string when not in a container.
+func detectContainer(containerEnv, dockerEnv, systemdContainer, initCgroup string) string {
+	if data, err := os.ReadFile(containerEnv); err == nil {
+		// Podman writes engine="podman-5.4.0" among other details.
+		for _, line := range strings.Split(string(data), "\n") {
+			if val, ok := strings.CutPrefix(line, "engine="); ok {
+				engine, _, _ := strings.Cut(strings.Trim(val, "\""), "-")
+				if engine != "" {
+					return engine
+				}
+			}
+		}
+		return "podman"
+	}
+	if _, err := os.Stat(dockerEnv); err == nil {
+		return "docker"
+	}
+	if data, err := os.ReadFile(systemdContainer); err == nil {
+		if name := strings.TrimSpace(string(data)); name != "" {
+			return name
+		}
+	}
+	if data, err := os.ReadFile(initCgroup); err == nil {
+		for _, c := range cgroupContainers {
+			if strings.Contains(string(data), c.match) {
+				return c.container
+			}
+		}
+	}
+	return ""
+}
+
+// detectBootcBuild reports whether a bootable container image is being
+// built, judging by bootc configuration present in the image.
+func detectBootcBuild(dir string) bool {
+	info, err := os.Stat(dir)
+	return err == nil && info.IsDir()
+}
+
+// detectVGPUDevices lists UUIDs of mediated devices created on NVIDIA
+// GPUs, which are vGPUs assigned to virtual machines.
+func detectVGPUDevices(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Debugf("unable to list mediated devices: %v", err)
+		return nil
+	}
+	var uuids []string
+	for _, entry := range entries {
+		// Device links point into directory of the parent device.
+		path, err := filepath.EvalSymlinks(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			log.Debugf("unable to resolve mediated device %s: %v", entry.Name(), err)
+			continue
+		}
+		vendor, err := os.ReadFile(filepath.Join(filepath.Dir(path), "vendor"))
+		if err != nil || strings.TrimSpace(string(vendor)) != nvidiaPCIVendor {
+			continue
+		}
+		uuids = append(uuids, entry.Name())
+	}
+	return uuids
+}
+
+func readDMI(dir, name string) string {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		log.Debugf("unable to read DMI %s: %v", name, err)
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func containsWord(s, word string) bool {
+	for _, w := range strings.Fields(s) {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
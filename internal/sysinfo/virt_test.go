@@ -0,0 +1,76 @@
+package sysinfo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectHypervisor(t *testing.T) {
+	tests := []struct {
+		name    string
+		dmi     string
+		cpuinfo string
+		want    string
+	}{
+		{name: "KVM", dmi: "testdata/dmi-kvm", cpuinfo: "testdata/cpuinfo-vm", want: "kvm"},
+		{name: "HyperV", dmi: "testdata/dmi-hyperv", cpuinfo: "testdata/cpuinfo-vm", want: "microsoft"},
+		{name: "AWS", dmi: "testdata/dmi-aws", cpuinfo: "testdata/cpuinfo-vm", want: "amazon"},
+		{name: "AWSMetal", dmi: "testdata/dmi-aws-metal", cpuinfo: "testdata/cpuinfo-metal", want: ""},
+		{name: "UnknownHypervisor", dmi: "testdata/dmi-metal", cpuinfo: "testdata/cpuinfo-vm", want: "vm-other"},
+		{name: "BareMetal", dmi: "testdata/dmi-metal", cpuinfo: "testdata/cpuinfo-metal", want: ""},
+		{name: "NoDMI", dmi: "testdata/does-not-exist", cpuinfo: "testdata/does-not-exist", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectHypervisor(tt.dmi, tt.cpuinfo); got != tt.want {
+				t.Fatalf("detectHypervisor(%q, %q) = %q, want %q", tt.dmi, tt.cpuinfo, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectContainer(t *testing.T) {
+	const none = "testdata/does-not-exist"
+	tests := []struct {
+		name             string
+		containerEnv     string
+		dockerEnv        string
+		systemdContainer string
+		initCgroup       string
+		want             string
+	}{
+		{name: "Podman", containerEnv: "testdata/containerenv", dockerEnv: none, systemdContainer: none, initCgroup: none, want: "podman"},
+		{name: "EmptyContainerEnv", containerEnv: "testdata/ostree-booted", dockerEnv: none, systemdContainer: none, initCgroup: none, want: "podman"},
+		{name: "Docker", containerEnv: none, dockerEnv: "testdata/ostree-booted", systemdContainer: none, initCgroup: none, want: "docker"},
+		{name: "Kubernetes", containerEnv: none, dockerEnv: none, systemdContainer: none, initCgroup: "testdata/cgroup-kubepods", want: "kubernetes"},
+		{name: "Host", containerEnv: none, dockerEnv: none, systemdContainer: none, initCgroup: "testdata/cgroup-host", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectContainer(tt.containerEnv, tt.dockerEnv, tt.systemdContainer, tt.initCgroup); got != tt.want {
+				t.Fatalf("detectContainer() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectBootcBuild(t *testing.T) {
+	if !detectBootcBuild("testdata/bootc/usr/lib/bootc") {
+		t.Fatalf("bootc configuration not detected")
+	}
+	if detectBootcBuild("testdata/root/usr/lib/bootc") {
+		t.Fatalf("bootc configuration detected where there is none")
+	}
+}
+
+func TestDetectVGPUDevices(t *testing.T) {
+	want := []string{"c0a7b6f4-9e2d-4d1a-8f3b-6e5c4d3b2a19"}
+	if got := detectVGPUDevices("testdata/mdev/sys/bus/mdev/devices"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("detectVGPUDevices() = %v, want %v", got, want)
+	}
+	if got := detectVGPUDevices("testdata/does-not-exist"); got != nil {
+		t.Fatalf("detectVGPUDevices() = %v, want none", got)
+	}
+}
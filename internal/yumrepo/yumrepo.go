@@ -0,0 +1,257 @@
+package yumrepo
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mizdebsk/rhel-drivers/internal/log"
+)
+
+// DefaultReposDir is where dnf looks for repository definitions.
+const DefaultReposDir = "/etc/yum.repos.d"
+
+// DefaultVarsDir holds files that define or override dnf variables,
+// one variable per file named after it.
+const DefaultVarsDir = "/etc/dnf/vars"
+
+// LoadVars returns given variables overridden by those defined in
+// files in given directory, if any.
+func LoadVars(dir string, vars map[string]string) map[string]string {
+	result := make(map[string]string, len(vars))
+	for k, v := range vars {
+		result[k] = v
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Debugf("unable to read dnf variables from %s: %v", dir, err)
+		return result
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			log.Warnf("failed to read dnf variable %s: %v", entry.Name(), err)
+			continue
+		}
+		val, _, _ := strings.Cut(string(data), "\n")
+		result[entry.Name()] = strings.TrimSpace(val)
+	}
+	return result
+}
+
+// Repo is a repository defined in a .repo file.
+type Repo struct {
+	ID string
+	// File the repository was defined in.
+	File    string
+	options map[string]string
+}
+
+// Get returns value of given option with variables substituted.
+func (r *Repo) Get(key string) (string, bool) {
+	val, ok := r.options[key]
+	return val, ok
+}
+
+func (r *Repo) Name() string {
+	if name, ok := r.Get("name"); ok {
+		return name
+	}
+	return r.ID
+}
+
+// Enabled reports whether the repository is enabled.  As in dnf,
+// repositories without the enabled option are enabled.
+func (r *Repo) Enabled() bool {
+	val, ok := r.Get("enabled")
+	if !ok {
+		return true
+	}
+	enabled, err := ParseBool(val)
+	if err != nil {
+		log.Warnf("%s: repository %s: %v", r.File, r.ID, err)
+	}
+	return enabled
+}
+
+// BaseURLs returns base URLs of the repository, which may be separated
+// by commas, whitespace or continuation lines.
+func (r *Repo) BaseURLs() []string {
+	val, _ := r.Get("baseurl")
+	return strings.FieldsFunc(val, func(c rune) bool {
+		return c == ',' || c == ' ' || c == '\t' || c == '\n'
+	})
+}
+
+// ParseBool parses boolean option value the way dnf does.
+func ParseBool(val string) (bool, error) {
+	switch strings.ToLower(val) {
+	case "1", "yes", "true", "on":
+		return true, nil
+	case "0", "no", "false", "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean value %q", val)
+}
+
+// Config is the set of repositories defined in .repo files.
+type Config struct {
+	repos []*Repo
+	byID  map[string]*Repo
+	vars  map[string]string
+}
+
+// LoadDir reads all .repo files in given directory, in lexical order.
+// Variables like $releasever and $basearch in option values are
+// substituted from vars.  A missing directory yields no repositories.
+func LoadDir(dir string, vars map[string]string) (*Config, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.repo"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return LoadFiles(paths, vars)
+}
+
+// LoadFiles reads given .repo files.  When the same repository is
+// defined in several files, the first definition is used, like dnf
+// does.
+func LoadFiles(paths []string, vars map[string]string) (*Config, error) {
+	cfg := &Config{
+		byID: make(map[string]*Repo),
+		vars: vars,
+	}
+	for _, path := range paths {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+func (cfg *Config) loadFile(path string) error {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return fmt.Errorf("failed to read repository file: %w", err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Warnf("failed to close file %s: %v", path, err)
+		}
+	}()
+
+	// Sections of this file, duplicate sections are merged.
+	sections := make(map[string]*Repo)
+	var order []*Repo
+	var repo *Repo
+	var lastKey string
+
+	lineNum := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lineNum++
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if raw[0] == ' ' || raw[0] == '\t' {
+			// Continuation of previous option value.
+			if repo == nil || lastKey == "" {
+				return fmt.Errorf("%s:%d: unexpected continuation line", path, lineNum)
+			}
+			repo.options[lastKey] += "\n" + cfg.substitute(stripComment(line))
+			continue
+		}
+		if line[0] == '[' {
+			if !strings.HasSuffix(line, "]") {
+				return fmt.Errorf("%s:%d: bad section header", path, lineNum)
+			}
+			id := strings.TrimSpace(line[1 : len(line)-1])
+			if id == "" {
+				return fmt.Errorf("%s:%d: empty section name", path, lineNum)
+			}
+			lastKey = ""
+			if repo = sections[id]; repo == nil {
+				repo = &Repo{ID: id, File: path, options: make(map[string]string)}
+				sections[id] = repo
+				order = append(order, repo)
+			} else {
+				log.Debugf("%s:%d: duplicate section %s merged", path, lineNum, id)
+			}
+			continue
+		}
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("%s:%d: expected key = value", path, lineNum)
+		}
+		if repo == nil {
+			return fmt.Errorf("%s:%d: option outside of any section", path, lineNum)
+		}
+		lastKey = strings.TrimSpace(key)
+		repo.options[lastKey] = cfg.substitute(stripComment(strings.TrimSpace(val)))
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error parsing repository file %s: %w", path, err)
+	}
+
+	for _, repo := range order {
+		if repo.ID == "main" {
+			continue
+		}
+		if prev := cfg.byID[repo.ID]; prev != nil {
+			log.Warnf("repository %s is defined in both %s and %s, using the former", repo.ID, prev.File, path)
+			continue
+		}
+		cfg.byID[repo.ID] = repo
+		cfg.repos = append(cfg.repos, repo)
+	}
+	return nil
+}
+
+// stripComment removes trailing comment introduced by whitespace
+// followed by '#' or ';'.
+func stripComment(val string) string {
+	for i := 1; i < len(val); i++ {
+		if (val[i] == '#' || val[i] == ';') && (val[i-1] == ' ' || val[i-1] == '\t') {
+			return strings.TrimSpace(val[:i])
+		}
+	}
+	return val
+}
+
+var varPattern = regexp.MustCompile(`\$(\w+)|\$\{(\w+)\}`)
+
+func (cfg *Config) substitute(val string) string {
+	return varPattern.ReplaceAllStringFunc(val, func(m string) string {
+		name := strings.Trim(m, "${}")
+		if v, ok := cfg.vars[name]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+// Repo returns repository with given ID, or nil if there is none.
+func (cfg *Config) Repo(id string) *Repo {
+	return cfg.byID[id]
+}
+
+// Repos returns all repositories in the order they were defined.
+func (cfg *Config) Repos() []*Repo {
+	return cfg.repos
+}
+
+// IsEnabled reports whether repository with given ID is defined and
+// enabled.
+func (cfg *Config) IsEnabled(id string) bool {
+	repo := cfg.Repo(id)
+	return repo != nil && repo.Enabled()
+}
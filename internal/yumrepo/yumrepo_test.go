@@ -0,0 +1,195 @@
+package yumrepo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEnabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		repoFile string
+		repoID   string
+		expected bool
+	}{
+		{
+			name:     "enabled_numeric_1",
+			repoID:   "repo-test",
+			expected: true,
+		},
+		{
+			name:     "enabled_boolean_true",
+			repoID:   "repo-test",
+			expected: true,
+		},
+		{
+			name:     "enabled_invalid_value",
+			repoID:   "repo-test",
+			expected: false,
+		},
+		{
+			name:     "enabled_mixed_case_true",
+			repoID:   "repo-test",
+			expected: true,
+		},
+		{
+			name:     "comment_in_line",
+			repoID:   "repo-test",
+			expected: true,
+		},
+		{
+			name:     "repo_not_found",
+			repoID:   "non-existent-repo",
+			expected: false,
+		},
+		{
+			// Repositories are enabled by default in dnf.
+			name:     "no_enabled_flag",
+			repoID:   "repo-test",
+			expected: true,
+		},
+		{
+			name:     "empty_file",
+			repoID:   "repo-test",
+			expected: false,
+		},
+		{
+			name:     "enabled_metadata_first",
+			repoID:   "repo-test",
+			expected: false,
+		},
+		{
+			name:     "duplicate_section",
+			repoID:   "repo-test",
+			expected: true,
+		},
+		{
+			name:     "rhel10_baseos",
+			repoFile: "rhel10",
+			repoID:   "rhel-10-for-x86_64-baseos-rpms",
+			expected: true,
+		},
+		{
+			name:     "rhel10_appstream",
+			repoFile: "rhel10",
+			repoID:   "rhel-10-for-x86_64-appstream-rpms",
+			expected: true,
+		},
+		{
+			name:     "rhel10_extensions",
+			repoFile: "rhel10",
+			repoID:   "rhel-10-for-x86_64-extensions-rpms",
+			expected: true,
+		},
+		{
+			name:     "rhel10_supplementary",
+			repoFile: "rhel10",
+			repoID:   "rhel-10-for-x86_64-supplementary-rpms",
+			expected: true,
+		},
+		{
+			name:     "rhel10_disabled",
+			repoFile: "rhel10",
+			repoID:   "rhel-10-for-x86_64-highavailability-rpms",
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoFile := tt.repoFile
+			if repoFile == "" {
+				repoFile = tt.name
+			}
+			cfg, err := LoadFiles([]string{"testdata/" + repoFile + ".repo"}, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result := cfg.IsEnabled(tt.repoID); result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestDuplicateSection(t *testing.T) {
+	cfg, err := LoadFiles([]string{"testdata/duplicate_section.repo"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var ids []string
+	for _, repo := range cfg.Repos() {
+		ids = append(ids, repo.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"repo-test", "other"}) {
+		t.Errorf("got repositories %v", ids)
+	}
+	if name := cfg.Repo("repo-test").Name(); name != "First definition" {
+		t.Errorf("got name %q", name)
+	}
+}
+
+func TestContinuationAndVariables(t *testing.T) {
+	vars := map[string]string{"releasever": "10", "basearch": "x86_64"}
+	cfg, err := LoadFiles([]string{"testdata/continuation.repo"}, vars)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	repo := cfg.Repo("repo-test")
+	if repo == nil {
+		t.Fatalf("repository not found")
+	}
+	if name := repo.Name(); name != "Test repository for 10 on x86_64" {
+		t.Errorf("got name %q", name)
+	}
+	expected := []string{
+		"https://mirror1.example.com/rhel10/x86_64/os",
+		"https://mirror2.example.com/rhel10/x86_64/os",
+		"https://mirror3.example.com/$unknownvar/os",
+	}
+	if urls := repo.BaseURLs(); !reflect.DeepEqual(urls, expected) {
+		t.Errorf("got base URLs %v, want %v", urls, expected)
+	}
+	if gpgcheck, _ := repo.Get("gpgcheck"); gpgcheck != "1" {
+		t.Errorf("got gpgcheck %q", gpgcheck)
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	vars := LoadVars("testdata/vars", map[string]string{"releasever": "10", "basearch": "aarch64"})
+	if !reflect.DeepEqual(vars, map[string]string{"releasever": "10.4", "basearch": "aarch64"}) {
+		t.Errorf("got variables %v", vars)
+	}
+
+	cfg, err := LoadDir("testdata/reposdir", vars)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var ids []string
+	for _, repo := range cfg.Repos() {
+		ids = append(ids, repo.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"shared", "only-b"}) {
+		t.Errorf("got repositories %v", ids)
+	}
+	shared := cfg.Repo("shared")
+	if shared.Name() != "From a.repo" || !shared.Enabled() || shared.File != "testdata/reposdir/a.repo" {
+		t.Errorf("first definition of duplicate repository not used: %+v", shared)
+	}
+	if urls := cfg.Repo("only-b").BaseURLs(); !reflect.DeepEqual(urls, []string{"https://example.com/aarch64"}) {
+		t.Errorf("got base URLs %v", urls)
+	}
+
+	cfg, err = LoadDir("testdata/does-not-exist", vars)
+	if err != nil || len(cfg.Repos()) != 0 {
+		t.Errorf("LoadDir() of missing directory = %v, %v", cfg.Repos(), err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	for _, path := range []string{"testdata/option_outside_section.repo", "testdata/does-not-exist.repo"} {
+		if _, err := LoadFiles([]string{path}, nil); err == nil {
+			t.Errorf("LoadFiles(%q) succeeded, expected error", path)
+		}
+	}
+}